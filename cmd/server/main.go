@@ -1,19 +1,161 @@
 package main
 
 import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/apiserver"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/events"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/webhooks"
 )
 
 func main() {
 	setupLogger()
 
-	streamRegistry := registry.NewRegistry()
+	setupWebhooks()
+
+	streamRegistry := registry.NewRegistry(registry.Config{
+		SlateDir: os.Getenv("SLATE_DIR"),
+		Push:     pushConfig(),
+		HLS:      hlsConfig(),
+	})
 	println("Starting...")
 
-	rtmp := rtmpserver.NewMediaServer(rtmpserver.MediaServerConfig{}, streamRegistry)
-	web := apiserver.NewWebServer(streamRegistry)
+	rtmpsPort, _ := strconv.Atoi(os.Getenv("RTMPS_PORT"))
+	srtLatency, _ := strconv.Atoi(os.Getenv("SRT_LATENCY_MS"))
+	rtspUDPPort, _ := strconv.Atoi(os.Getenv("RTSP_UDP_PORT"))
+	whipAudioPolicy, err := medias.ParseAudioPolicy(os.Getenv("WHIP_AUDIO_POLICY"))
+	if err != nil {
+		log.Fatalf("Invalid WHIP_AUDIO_POLICY: %v", err)
+	}
+	rtmp := rtmpserver.NewMediaServer(rtmpserver.MediaServerConfig{
+		ListenAddrs:     splitList(os.Getenv("RTMP_ADDRS")),
+		TLSPort:         rtmpsPort,
+		TLSListenAddrs:  splitList(os.Getenv("RTMPS_ADDRS")),
+		TLSCertFile:     os.Getenv("RTMPS_CERT_FILE"),
+		TLSKeyFile:      os.Getenv("RTMPS_KEY_FILE"),
+		SRTListenAddrs:  splitList(os.Getenv("SRT_ADDRS")),
+		SRTPassphrase:   os.Getenv("SRT_PASSPHRASE"),
+		SRTLatency:      time.Duration(srtLatency) * time.Millisecond,
+		WHIPUDPAddr:     os.Getenv("WHIP_UDP_ADDR"),
+		WHIPPublicIPs:   splitList(os.Getenv("WHIP_PUBLIC_IPS")),
+		WHIPAudioPolicy: whipAudioPolicy,
+		RTSPListenAddrs: splitList(os.Getenv("RTSP_ADDRS")),
+		RTSPUDPPort:     rtspUDPPort,
+		OnPublishURL:    os.Getenv("ON_PUBLISH_URL"),
+		OnPlayURL:       os.Getenv("ON_PLAY_URL"),
+	}, streamRegistry)
+	web := apiserver.NewWebServer(apiserver.WebServerConfig{
+		Addrs:            splitList(os.Getenv("HTTP_ADDRS")),
+		UsersFile:        os.Getenv("USERS_FILE"),
+		OIDC:             oidcConfig(),
+		TLSCertFile:      os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:       os.Getenv("TLS_KEY_FILE"),
+		HTTPRedirectAddr: os.Getenv("HTTP_REDIRECT_ADDR"),
+		ACMEDomains:      splitList(os.Getenv("ACME_DOMAINS")),
+		ACMEDirectoryURL: os.Getenv("ACME_DIRECTORY_URL"),
+		ACMEEmail:        os.Getenv("ACME_EMAIL"),
+		ACMECacheDir:     os.Getenv("ACME_CACHE_DIR"),
+		HLSSigningKey:    os.Getenv("HLS_SIGNING_KEY"),
+	}, streamRegistry, rtmp)
 	go rtmp.Start()
 	web.Start()
 }
+
+func oidcConfig() *apiserver.OIDCConfig {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil
+	}
+	roleMap, err := apiserver.ParseRoleMap(os.Getenv("OIDC_ROLE_MAP"))
+	if err != nil {
+		log.Fatalf("Invalid OIDC_ROLE_MAP: %v", err)
+	}
+	sessionHours, _ := strconv.Atoi(os.Getenv("OIDC_SESSION_HOURS"))
+	return &apiserver.OIDCConfig{
+		Issuer:       issuer,
+		JWKS:         os.Getenv("OIDC_JWKS"),
+		Audience:     os.Getenv("OIDC_AUDIENCE"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		AuthURL:      os.Getenv("OIDC_AUTH_URL"),
+		TokenURL:     os.Getenv("OIDC_TOKEN_URL"),
+		RoleClaim:    os.Getenv("OIDC_ROLE_CLAIM"),
+		RoleMap:      roleMap,
+		StreamsClaim: os.Getenv("OIDC_STREAMS_CLAIM"),
+		SessionTTL:   time.Duration(sessionHours) * time.Hour,
+	}
+}
+
+func setupWebhooks() {
+	urls := os.Getenv("WEBHOOK_URLS")
+	if urls == "" {
+		return
+	}
+	maxAttempts, _ := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS"))
+	dispatcher := webhooks.NewDispatcher(webhooks.Config{
+		URLs:           splitList(urls),
+		Secret:         os.Getenv("WEBHOOK_SECRET"),
+		MaxAttempts:    maxAttempts,
+		DeadLetterFile: os.Getenv("WEBHOOK_DEAD_LETTER_FILE"),
+	})
+	events.Subscribe(dispatcher.Handle)
+}
+
+func pushConfig() medias.PushConfig {
+	var config medias.PushConfig
+	if attempts := os.Getenv("PUSH_MAX_ATTEMPTS"); attempts != "" {
+		n, err := strconv.Atoi(attempts)
+		if err != nil {
+			log.Fatalf("Invalid PUSH_MAX_ATTEMPTS: %v", err)
+		}
+		config.MaxConnectAttempts = n
+	}
+	config.TLSFilesDir = os.Getenv("PUSH_TLS_DIR")
+	if proxy := os.Getenv("PUSH_PROXY"); proxy != "" {
+		proxyURL, err := api.ParseProxy(proxy)
+		if err != nil {
+			log.Fatalf("Invalid PUSH_PROXY: %v", err)
+		}
+		config.Proxy = proxyURL
+	}
+	return config
+}
+
+func hlsConfig() medias.HLSConfig {
+	var config medias.HLSConfig
+	if seconds := os.Getenv("HLS_SEGMENT_SECONDS"); seconds != "" {
+		n, err := strconv.Atoi(seconds)
+		if err != nil || n <= 0 {
+			log.Fatalf("Invalid HLS_SEGMENT_SECONDS: %q", seconds)
+		}
+		config.SegmentDuration = time.Duration(n) * time.Second
+	}
+	if size := os.Getenv("HLS_PLAYLIST_SIZE"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n <= 0 {
+			log.Fatalf("Invalid HLS_PLAYLIST_SIZE: %q", size)
+		}
+		config.PlaylistSize = n
+	}
+	return config
+}
+
+// splitList parses a comma separated environment value.
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
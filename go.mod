@@ -2,9 +2,40 @@ module github.com/kbats183/simple-rtmp-restreamer
 
 go 1.21.1
 
-replace github.com/yapingcat/gomedia v0.0.0-20240823161909-e61bbaf17c9a => github.com/kbats183/gomedia v0.0.0-20250817114334-50ae796beb83
+replace github.com/yapingcat/gomedia v0.0.0-20240823161909-e61bbaf17c9a => ./third_party/gomedia
 
 require (
 	github.com/go-chi/chi/v5 v5.1.0
 	github.com/yapingcat/gomedia v0.0.0-20240823161909-e61bbaf17c9a
 )
+
+require (
+	github.com/pion/interceptor v0.1.25
+	github.com/pion/rtcp v1.2.12
+	github.com/pion/rtp v1.8.5
+	github.com/pion/webrtc/v3 v3.2.40
+	golang.org/x/crypto v0.21.0
+	golang.org/x/net v0.22.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/google/uuid v1.3.1 // indirect
+	github.com/pion/datachannel v1.5.5 // indirect
+	github.com/pion/dtls/v2 v2.2.7 // indirect
+	github.com/pion/ice/v2 v2.3.24 // indirect
+	github.com/pion/logging v0.2.2 // indirect
+	github.com/pion/mdns v0.0.12 // indirect
+	github.com/pion/randutil v0.1.0 // indirect
+	github.com/pion/sctp v1.8.16 // indirect
+	github.com/pion/sdp/v3 v3.0.9 // indirect
+	github.com/pion/srtp/v2 v2.0.18 // indirect
+	github.com/pion/stun v0.6.1 // indirect
+	github.com/pion/transport/v2 v2.2.4 // indirect
+	github.com/pion/turn/v2 v2.1.3 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/testify v1.9.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -1,9 +1,29 @@
 package api
 
-import "net/url"
+import (
+	"net/url"
+	"strings"
+)
 
 type PushTargetUrl url.URL
 
 func (p *PushTargetUrl) String() string {
 	return (*url.URL)(p).String()
 }
+
+// Redacted returns the URL with the stream key, query and password hidden.
+func (p *PushTargetUrl) Redacted() string {
+	u := *(*url.URL)(p)
+	if u.User != nil {
+		u.User = url.User(u.User.Username())
+	}
+	// The key is the last non-empty segment of the path, even if it's the only one
+	if path := strings.TrimRight(u.Path, "/"); path != "" {
+		u.Path = path[:strings.LastIndex(path, "/")+1] + "****" + u.Path[len(path):]
+		u.RawPath = u.Path
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = "****"
+	}
+	return u.String()
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/srt"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/utils"
+)
+
+// PushTargetOptions are optional per-target connection settings.
+type PushTargetOptions struct {
+	TLS *PushTargetTLS `json:"tls,omitempty"`
+
+	Network       string `json:"network,omitempty"`        // "tcp" (dual-stack, default), "tcp4" or "tcp6"
+	SourceAddress string `json:"source_address,omitempty"` // local IP to dial from
+	Proxy         string `json:"proxy,omitempty"`          // socks5:// or http:// proxy URL, overrides the global proxy
+
+	// RTMP connection parameters, derived from the URL when empty
+	Handshake string `json:"handshake,omitempty"`  // "complex" (default) or "simple"
+	App       string `json:"app,omitempty"`        // may contain slashes
+	Playpath  string `json:"playpath,omitempty"`   // stream name, may contain query parameters
+	TcURL     string `json:"tc_url,omitempty"`     // defaults to scheme://host/app
+	FlashVer  string `json:"flash_ver,omitempty"`  // defaults to "FMSc/1.0"
+	ChunkSize uint32 `json:"chunk_size,omitempty"` // outgoing chunk size
+
+	// RTSP sources only
+	RTSPTransport string `json:"rtsp_transport,omitempty"` // "tcp" (interleaved, default) or "udp"
+
+	// SRT targets only, the stream ID may also be given by the streamid query parameter of the URL
+	SRTLatency    uint   `json:"srt_latency,omitempty"`    // ms, defaults to 120
+	SRTPassphrase string `json:"srt_passphrase,omitempty"` // encrypts the stream
+	SRTKeyLength  int    `json:"srt_key_length,omitempty"` // AES key length in bytes, 16 (default), 24 or 32
+	SRTStreamID   string `json:"srt_streamid,omitempty"`
+}
+
+const (
+	MinChunkSize = 128
+	MaxChunkSize = 65536
+
+	MaxSRTLatency = 60000
+)
+
+var errTLSFile = errors.New("not a readable PEM file in the TLS files directory")
+
+const maxTLSFileSize = 1 << 20
+
+// PushTargetTLS configures certificate verification of rtmps targets.
+// The file names are relative to the TLS files directory of the server.
+type PushTargetTLS struct {
+	CAFile             string `json:"ca_file,omitempty"`     // PEM bundle used instead of the system roots
+	Fingerprint        string `json:"fingerprint,omitempty"` // SHA-256 of the leaf certificate, replaces chain verification
+	ServerName         string `json:"server_name,omitempty"` // SNI and verified host name override
+	ClientCertFile     string `json:"client_cert_file,omitempty"`
+	ClientKeyFile      string `json:"client_key_file,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// Validate checks the options, the TLS files are read from tlsFilesDir. The file options are rejected
+// while it's empty, so API users can't read arbitrary local files.
+func (o *PushTargetOptions) Validate(tlsFilesDir string) error {
+	switch o.Network {
+	case "", "tcp", "tcp4", "tcp6":
+	default:
+		return fmt.Errorf("unknown network %q", o.Network)
+	}
+	if o.SourceAddress != "" && net.ParseIP(o.SourceAddress) == nil {
+		return fmt.Errorf("source_address %q is not an IP address", o.SourceAddress)
+	}
+	if o.Proxy != "" {
+		if _, err := ParseProxy(o.Proxy); err != nil {
+			return fmt.Errorf("proxy: %w", err)
+		}
+	}
+	switch o.RTSPTransport {
+	case "", "tcp":
+	case "udp":
+		if o.Proxy != "" {
+			return errors.New("rtsp_transport udp can't be used with a proxy")
+		}
+	default:
+		return fmt.Errorf("unknown rtsp_transport %q", o.RTSPTransport)
+	}
+	switch o.Handshake {
+	case "", "simple", "complex":
+	default:
+		return fmt.Errorf("unknown handshake %q", o.Handshake)
+	}
+	if strings.HasPrefix(o.App, "/") || strings.HasSuffix(o.App, "/") {
+		return fmt.Errorf("app %q must not start or end with a slash", o.App)
+	}
+	if o.TcURL != "" {
+		tcURL, err := url.Parse(o.TcURL)
+		if err != nil {
+			return fmt.Errorf("tc_url: %w", err)
+		}
+		if tcURL.Scheme != "rtmp" && tcURL.Scheme != "rtmps" || tcURL.Host == "" {
+			return fmt.Errorf("tc_url %q is not an rtmp URL", o.TcURL)
+		}
+	}
+	for name, value := range map[string]string{"app": o.App, "playpath": o.Playpath, "tc_url": o.TcURL, "flash_ver": o.FlashVer} {
+		if len(value) > 0xffff || strings.ContainsFunc(value, unicode.IsControl) {
+			return fmt.Errorf("invalid %s", name)
+		}
+	}
+	if o.ChunkSize != 0 && (o.ChunkSize < MinChunkSize || o.ChunkSize > MaxChunkSize) {
+		return fmt.Errorf("chunk_size must be between %d and %d", MinChunkSize, MaxChunkSize)
+	}
+	if o.SRTLatency > MaxSRTLatency {
+		return fmt.Errorf("srt_latency must be at most %d ms", MaxSRTLatency)
+	}
+	if o.SRTPassphrase != "" {
+		if err := srt.ValidatePassphrase(o.SRTPassphrase); err != nil {
+			return fmt.Errorf("srt_passphrase: %w", err)
+		}
+	}
+	if err := srt.ValidateKeyLength(o.SRTKeyLength); err != nil {
+		return fmt.Errorf("srt_key_length: %w", err)
+	}
+	if len(o.SRTStreamID) > srt.MaxStreamIDLen {
+		return fmt.Errorf("srt_streamid must be at most %d bytes", srt.MaxStreamIDLen)
+	}
+	if o.TLS != nil {
+		if err := o.TLS.Validate(tlsFilesDir); err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+	}
+	return nil
+}
+
+// ParseProxy parses a socks5:// (resolves the target locally), socks5h:// (the proxy resolves it) or http:// proxy URL.
+func ParseProxy(raw string) (*url.URL, error) {
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h", "http":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+	if proxyURL.Hostname() == "" {
+		return nil, fmt.Errorf("proxy %q has no host", proxyURL.Redacted())
+	}
+	if proxyURL.Path != "" && proxyURL.Path != "/" || proxyURL.RawQuery != "" {
+		return nil, fmt.Errorf("proxy %q must not have a path or query", proxyURL.Redacted())
+	}
+	if port := proxyURL.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 0xffff {
+			return nil, fmt.Errorf("proxy %q has an invalid port", proxyURL.Redacted())
+		}
+	}
+	return proxyURL, nil
+}
+
+func (t *PushTargetTLS) Validate(tlsFilesDir string) error {
+	if t.Fingerprint != "" {
+		if _, err := t.FingerprintBytes(); err != nil {
+			return err
+		}
+		if t.CAFile != "" {
+			return errors.New("fingerprint and ca_file can't be used together")
+		}
+	}
+	if t.CAFile != "" {
+		if _, err := t.CertPool(tlsFilesDir); err != nil {
+			return fmt.Errorf("ca_file: %w", err)
+		}
+	}
+	if (t.ClientCertFile == "") != (t.ClientKeyFile == "") {
+		return errors.New("client_cert_file and client_key_file must be set together")
+	}
+	if t.ClientCertFile != "" {
+		if _, err := t.ClientCertificate(tlsFilesDir); err != nil {
+			return fmt.Errorf("client_cert_file: %w", err)
+		}
+	}
+	return nil
+}
+
+// readTLSFile reads the file from the directory, refusing names which resolve outside of it.
+// Errors are generic so they don't tell whether a file exists.
+func readTLSFile(dir, name string) ([]byte, error) {
+	file, err := utils.OpenInDir(dir, name)
+	if err != nil {
+		return nil, errTLSFile
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxTLSFileSize))
+	if err != nil {
+		return nil, errTLSFile
+	}
+	return data, nil
+}
+
+// FingerprintBytes decodes the fingerprint, which may contain colons.
+func (t *PushTargetTLS) FingerprintBytes() ([]byte, error) {
+	fingerprint, err := hex.DecodeString(strings.ReplaceAll(t.Fingerprint, ":", ""))
+	if err != nil || len(fingerprint) != 32 {
+		return nil, errors.New("fingerprint must be a hex SHA-256 digest")
+	}
+	return fingerprint, nil
+}
+
+func (t *PushTargetTLS) CertPool(tlsFilesDir string) (*x509.CertPool, error) {
+	data, err := readTLSFile(tlsFilesDir, t.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errTLSFile
+	}
+	return pool, nil
+}
+
+func (t *PushTargetTLS) ClientCertificate(tlsFilesDir string) (tls.Certificate, error) {
+	certPEM, err := readTLSFile(tlsFilesDir, t.ClientCertFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := readTLSFile(tlsFilesDir, t.ClientKeyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, errTLSFile
+	}
+	return cert, nil
+}
+
+// Redacted returns the options with secrets hidden.
+func (o PushTargetOptions) Redacted() PushTargetOptions {
+	if proxyURL, err := url.Parse(o.Proxy); err == nil && proxyURL.User != nil {
+		if _, hasPassword := proxyURL.User.Password(); hasPassword {
+			proxyURL.User = url.UserPassword(proxyURL.User.Username(), "****")
+			o.Proxy = proxyURL.String()
+		}
+	}
+	// The playpath and the tcUrl query usually carry the stream key
+	if o.Playpath != "" {
+		o.Playpath = "****"
+	}
+	if tcURL, err := url.Parse(o.TcURL); err == nil && tcURL.RawQuery != "" {
+		tcURL.RawQuery = "****"
+		o.TcURL = tcURL.String()
+	}
+	if o.SRTPassphrase != "" {
+		o.SRTPassphrase = "****"
+	}
+	// Like the playpath, the stream ID usually carries the stream key
+	if o.SRTStreamID != "" {
+		o.SRTStreamID = "****"
+	}
+	return o
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestCertificate(t *testing.T, dir string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"ca.pem":      pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		"client.pem":  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		"client.key":  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		"garbage.pem": []byte("not a certificate"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPushTargetTLSValidate(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "tls")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeTestCertificate(t, dir)
+	outside := filepath.Join(root, "outside.pem")
+	if err := os.WriteFile(outside, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link.pem")); err != nil {
+		t.Fatal(err)
+	}
+	const fingerprint = "00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff"
+
+	tests := []struct {
+		name string
+		dir  string
+		tls  PushTargetTLS
+		ok   bool
+	}{
+		{"ca file", dir, PushTargetTLS{CAFile: "ca.pem"}, true},
+		{"client certificate", dir, PushTargetTLS{ClientCertFile: "client.pem", ClientKeyFile: "client.key"}, true},
+		{"fingerprint", dir, PushTargetTLS{Fingerprint: fingerprint}, true},
+		{"no tls dir", "", PushTargetTLS{CAFile: "ca.pem"}, false},
+		{"absolute path", dir, PushTargetTLS{CAFile: filepath.Join(dir, "ca.pem")}, false},
+		{"parent directory", dir, PushTargetTLS{CAFile: "../outside.pem"}, false},
+		{"symlink outside", dir, PushTargetTLS{CAFile: "link.pem"}, false},
+		{"missing file", dir, PushTargetTLS{CAFile: "missing.pem"}, false},
+		{"not a certificate", dir, PushTargetTLS{CAFile: "garbage.pem"}, false},
+		{"cert without key", dir, PushTargetTLS{ClientCertFile: "client.pem"}, false},
+		{"key mismatch", dir, PushTargetTLS{ClientCertFile: "client.pem", ClientKeyFile: "ca.pem"}, false},
+		{"fingerprint with ca file", dir, PushTargetTLS{Fingerprint: fingerprint, CAFile: "ca.pem"}, false},
+		{"short fingerprint", dir, PushTargetTLS{Fingerprint: "0011"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.tls.Validate(tt.dir)
+			if tt.ok && err != nil {
+				t.Fatalf("Validate: %v", err)
+			} else if !tt.ok && err == nil {
+				t.Fatal("Validate accepted invalid options")
+			}
+		})
+	}
+
+	// A missing file and an unreadable one must not be told apart
+	missing := (&PushTargetTLS{CAFile: "missing.pem"}).Validate(dir)
+	outsideErr := (&PushTargetTLS{CAFile: "../outside.pem"}).Validate(dir)
+	garbage := (&PushTargetTLS{CAFile: "garbage.pem"}).Validate(dir)
+	if missing.Error() != garbage.Error() || missing.Error() != outsideErr.Error() {
+		t.Fatalf("errors differ: %q, %q, %q", missing, outsideErr, garbage)
+	}
+}
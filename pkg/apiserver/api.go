@@ -1,5 +1,57 @@
 package apiserver
 
+import (
+	"fmt"
+	"time"
+)
+
 type DeleteTargetInfo struct {
-	Target string `json:"target"`
+	Target string `json:"target"` // the URL, or the redacted URL or the name of the target without the keys:read scope
+}
+
+// AmbiguousTarget is returned when the redacted URL of a deleted target matches several targets.
+type AmbiguousTarget struct {
+	Target string
+}
+
+func (e AmbiguousTarget) Error() string {
+	return fmt.Sprintf("AmbiguousTarget: %s matches several targets, delete it by name", e.Target)
+}
+
+type PrincipalInfo struct {
+	Name    string   `json:"name"`
+	Role    Role     `json:"role,omitempty"`
+	Scopes  []Scope  `json:"scopes"`
+	Streams []string `json:"streams,omitempty"`
+}
+
+type CreateTokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	Streams   []string   `json:"streams,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	ExpiresIn int64      `json:"expires_in,omitempty"` // seconds
+}
+
+type TokenInfo struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Streams    []string   `json:"streams,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CreatedToken is returned once on creation; the token itself can't be retrieved later.
+type CreatedToken struct {
+	TokenInfo
+	Token string `json:"token"`
+}
+
+// SignedURL is an HLS playlist or a DASH manifest URL which can be played without credentials until it expires.
+type SignedURL struct {
+	URL       string    `json:"url"`
+	HLSURL    string    `json:"hls_url,omitempty"` // HLS fMP4 playlist of the same CMAF segments as the DASH manifest
+	ExpiresAt time.Time `json:"expires_at"`
 }
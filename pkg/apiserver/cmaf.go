@@ -0,0 +1,165 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+)
+
+// cmafRouter serves the DASH manifest, the HLS fMP4 playlists and their shared CMAF segments,
+// with the same signed URLs as HLS.
+type cmafRouter struct {
+	*hlsRouter
+}
+
+func newCMAFRouter(hls *hlsRouter) *cmafRouter {
+	return &cmafRouter{hlsRouter: hls}
+}
+
+func (router *cmafRouter) Routes(r chi.Router, auth *authenticator) {
+	r.Route("/cmaf/{id}", func(r chi.Router) {
+		r.Use(router.signedOr(auth.Middleware))
+		r.Use(RequireScope(ScopeStatusRead))
+		r.Get("/manifest.mpd", router.getMPD())
+		r.Get("/master.m3u8", router.getHLSMaster())
+		r.Get("/{track}.m3u8", router.getHLSPlaylist())
+		r.Get("/init-{track}.mp4", router.getInit())
+		r.Get("/{track}-{start}.m4s", router.getSegment())
+	})
+}
+
+func (router *cmafRouter) APIRoutes(r chi.Router) {
+	r.With(ContentTypeJson, RequireScope(ScopeStatusRead)).Get("/api/streams/{id}/cmaf", router.getSignedURL())
+}
+
+func (router *cmafRouter) getSignedURL() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(router.signingKey) == 0 {
+			JSONError(w, "HLS signing key is not configured", http.StatusNotImplemented)
+			return
+		}
+		id := chi.URLParam(r, "id")
+		query, expiresAt := router.signedQuery(id)
+		if err := json.NewEncoder(w).Encode(SignedURL{
+			URL:       "/cmaf/" + url.PathEscape(id) + "/manifest.mpd?" + query,
+			HLSURL:    "/cmaf/" + url.PathEscape(id) + "/master.m3u8?" + query,
+			ExpiresAt: expiresAt,
+		}); err != nil {
+			handleErrors(w, err)
+			return
+		}
+	}
+}
+
+// consumer returns the CMAF packager of the live stream, or nil.
+func (router *cmafRouter) consumer(r *http.Request) *medias.CMAFConsumer {
+	stream, err := router.registry.GetInternalStream(chi.URLParam(r, "id"))
+	if err != nil || stream == nil {
+		return nil
+	}
+	return stream.CMAFConsumer()
+}
+
+func (router *cmafRouter) getMPD() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consumer := router.consumer(r)
+		if consumer == nil {
+			http.NotFound(w, r)
+			return
+		}
+		// The manifests keep the signature of their URL in the URLs of the segments
+		mpd, ok := consumer.MPD(r.URL.RawQuery, time.Now())
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/dash+xml")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = io.WriteString(w, mpd)
+	}
+}
+
+func (router *cmafRouter) getHLSMaster() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consumer := router.consumer(r)
+		if consumer == nil {
+			http.NotFound(w, r)
+			return
+		}
+		playlist, ok := consumer.HLSMaster(r.URL.RawQuery)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = io.WriteString(w, playlist)
+	}
+}
+
+func (router *cmafRouter) getHLSPlaylist() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consumer := router.consumer(r)
+		if consumer == nil {
+			http.NotFound(w, r)
+			return
+		}
+		playlist, ok := consumer.HLSPlaylist(chi.URLParam(r, "track"), r.URL.RawQuery)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = io.WriteString(w, playlist)
+	}
+}
+
+func (router *cmafRouter) getInit() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consumer := router.consumer(r)
+		if consumer == nil {
+			http.NotFound(w, r)
+			return
+		}
+		track := chi.URLParam(r, "track")
+		data, ok := consumer.Init(track)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", track+"/mp4")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = w.Write(data)
+	}
+}
+
+func (router *cmafRouter) getSegment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start, err := strconv.ParseUint(chi.URLParam(r, "start"), 10, 32)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		consumer := router.consumer(r)
+		if consumer == nil {
+			http.NotFound(w, r)
+			return
+		}
+		track := chi.URLParam(r, "track")
+		data, ok := consumer.Segment(track, uint32(start))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", track+"/mp4")
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write(data)
+	}
+}
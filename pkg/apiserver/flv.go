@@ -0,0 +1,102 @@
+package apiserver
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver"
+	"golang.org/x/net/websocket"
+)
+
+type flvRouter struct {
+	r        chi.Router
+	sessions SessionManager
+}
+
+func newFLVRouter(router chi.Router, sessions SessionManager) *flvRouter {
+	return &flvRouter{
+		r:        router,
+		sessions: sessions,
+	}
+}
+
+func (router *flvRouter) Routes() {
+	// The stream is named id to let RequireScope check the access of the principal to it
+	router.r.With(RequireScope(ScopeStatusRead)).Get("/live/{id}.flv", router.getFLV())
+}
+
+// getFLV plays the stream as chunked HTTP-FLV, or as WebSocket-FLV if the request is an upgrade.
+func (router *flvRouter) getFLV() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		protocol := rtmpserver.ProtocolHTTPFLV
+		upgrade := strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+		if upgrade {
+			protocol = rtmpserver.ProtocolWSFLV
+		}
+		id := chi.URLParam(r, "id")
+		serve, err := router.sessions.AcceptFLVPlayer(protocol, r.RemoteAddr, r.TLS != nil, id, r.URL.Query())
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			if errors.As(err, &rtmpserver.PlayUnauthorized{}) {
+				JSONError(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			handleErrors(w, err)
+			return
+		}
+
+		if upgrade {
+			server := websocket.Server{
+				Handshake: sameOriginHandshake,
+				Handler: func(ws *websocket.Conn) {
+					ws.PayloadType = websocket.BinaryFrame
+					ctx, cancel := context.WithCancel(r.Context())
+					defer cancel()
+					go func() {
+						// Players send nothing, reading ends when the connection closes
+						_, _ = ws.Read(make([]byte, 512))
+						cancel()
+					}()
+					_ = serve(ctx, func(data []byte) error {
+						_, err := ws.Write(data)
+						return err
+					})
+				},
+			}
+			server.ServeHTTP(w, r)
+			return
+		}
+
+		controller := http.NewResponseController(w)
+		w.Header().Set("Content-Type", "video/x-flv")
+		w.Header().Set("Cache-Control", "no-cache")
+		_ = serve(r.Context(), func(data []byte) error {
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+			return controller.Flush()
+		})
+	}
+}
+
+// sameOriginHandshake rejects cross-site WebSocket requests, which would carry the session
+// cookie of the user. Clients other than browsers send no Origin.
+func sameOriginHandshake(config *websocket.Config, r *http.Request) error {
+	origin, err := websocket.Origin(config, r)
+	if err != nil {
+		return err
+	}
+	config.Origin = origin
+	if origin == nil {
+		return nil
+	}
+	if origin.Host != r.Host {
+		log.Printf("WebSocket from origin %s to %s rejected", r.Header.Get("Origin"), r.Host)
+		return errors.New("cross-origin WebSocket")
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package apiserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+)
+
+// signedURLTTL is the lifetime of the signed playlist URLs.
+const signedURLTTL = 12 * time.Hour
+
+type hlsRouter struct {
+	registry   registry.Registry
+	signingKey []byte // signed URLs are disabled if empty
+}
+
+func newHLSRouter(registry registry.Registry, signingKey string) *hlsRouter {
+	return &hlsRouter{
+		registry:   registry,
+		signingKey: []byte(signingKey),
+	}
+}
+
+// Routes serves the playlists and the segments, which are accessed either with a signed URL
+// or like the API.
+func (router *hlsRouter) Routes(r chi.Router, auth *authenticator) {
+	r.Route("/hls/{id}", func(r chi.Router) {
+		r.Use(router.signedOr(auth.Middleware))
+		r.Use(RequireScope(ScopeStatusRead))
+		r.Get("/index.m3u8", router.getPlaylist())
+		r.Get("/{segment}.ts", router.getSegment())
+	})
+}
+
+// APIRoutes signs playlist URLs for players which can't authenticate, like a browser tab of a producer.
+func (router *hlsRouter) APIRoutes(r chi.Router) {
+	r.With(ContentTypeJson, RequireScope(ScopeStatusRead)).Get("/api/streams/{id}/hls", router.getSignedURL())
+}
+
+// signedOr accepts requests with a valid signature for the stream, the others go through the authentication.
+func (router *hlsRouter) signedOr(authenticate func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authenticated := authenticate(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			if query.Has("signature") {
+				stream := chi.URLParam(r, "id")
+				if !router.verify(stream, query.Get("expires"), query.Get("signature"), time.Now()) {
+					JSONError(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+				principal := &Principal{Name: "signed-url", Scopes: []Scope{ScopeStatusRead}, Streams: []string{stream}}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+				return
+			}
+			authenticated.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (router *hlsRouter) sign(stream string, expires int64) string {
+	mac := hmac.New(sha256.New, router.signingKey)
+	mac.Write([]byte(stream + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (router *hlsRouter) verify(stream, expires, signature string, now time.Time) bool {
+	if len(router.signingKey) == 0 {
+		return false
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > expiresAt {
+		return false
+	}
+	return hmac.Equal([]byte(router.sign(stream, expiresAt)), []byte(signature))
+}
+
+// signedQuery returns the query which signs the URLs of the stream until it expires.
+func (router *hlsRouter) signedQuery(stream string) (string, time.Time) {
+	expiresAt := time.Now().Add(signedURLTTL)
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("signature", router.sign(stream, expiresAt.Unix()))
+	return query.Encode(), expiresAt
+}
+
+func (router *hlsRouter) getSignedURL() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(router.signingKey) == 0 {
+			JSONError(w, "HLS signing key is not configured", http.StatusNotImplemented)
+			return
+		}
+		id := chi.URLParam(r, "id")
+		query, expiresAt := router.signedQuery(id)
+		if err := json.NewEncoder(w).Encode(SignedURL{
+			URL:       "/hls/" + url.PathEscape(id) + "/index.m3u8?" + query,
+			ExpiresAt: expiresAt,
+		}); err != nil {
+			handleErrors(w, err)
+			return
+		}
+	}
+}
+
+func (router *hlsRouter) getPlaylist() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stream, err := router.registry.GetInternalStream(chi.URLParam(r, "id"))
+		if err != nil || stream == nil {
+			http.NotFound(w, r)
+			return
+		}
+		consumer := stream.HLSConsumer()
+		if consumer == nil {
+			http.NotFound(w, r)
+			return
+		}
+		// The segment URIs keep the signature of the playlist URL
+		playlist, ok := consumer.Playlist(r.URL.RawQuery)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = io.WriteString(w, playlist)
+	}
+}
+
+func (router *hlsRouter) getSegment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(chi.URLParam(r, "segment"), ".ts"), 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		stream, err := router.registry.GetInternalStream(chi.URLParam(r, "id"))
+		if err != nil || stream == nil || stream.HLSConsumer() == nil {
+			http.NotFound(w, r)
+			return
+		}
+		data, ok := stream.HLSConsumer().Segment(seq)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "video/mp2t")
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write(data)
+	}
+}
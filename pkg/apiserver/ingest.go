@@ -0,0 +1,60 @@
+package apiserver
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver"
+)
+
+type ingestRouter struct {
+	r        chi.Router
+	sessions SessionManager
+}
+
+func newIngestRouter(router chi.Router, sessions SessionManager) *ingestRouter {
+	return &ingestRouter{
+		r:        router,
+		sessions: sessions,
+	}
+}
+
+func (router *ingestRouter) Routes() {
+	// The stream is named id to let RequireScope check the access of the principal to it
+	router.r.With(ContentTypeJson, RequireScope(ScopePublish)).Post("/ingest/{id}", router.postFLV())
+}
+
+// postFLV publishes the chunked FLV body of the request until it ends, like an RTMP publisher.
+func (router *ingestRouter) postFLV() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		controller := http.NewResponseController(w)
+		closer := func() error {
+			// Unblocks the read of the body when the session is closed or taken over
+			return controller.SetReadDeadline(time.Now())
+		}
+		serve, err := router.sessions.AcceptFLVIngest(r.Body, r.RemoteAddr, r.TLS != nil, closer, chi.URLParam(r, "id"), r.URL.Query())
+		if err != nil {
+			handleIngestErrors(w, err)
+			return
+		}
+		if err := serve(); err != nil {
+			log.Printf("HTTP-FLV ingest of %s ended: %v", chi.URLParam(r, "id"), err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func handleIngestErrors(w http.ResponseWriter, err error) {
+	switch {
+	case errors.As(err, &rtmpserver.PublishUnauthorized{}), errors.As(err, &rtmpserver.PublishForbidden{}):
+		JSONError(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &registry.PublisherConflict{}):
+		JSONError(w, err.Error(), http.StatusConflict)
+	default:
+		handleErrors(w, err)
+	}
+}
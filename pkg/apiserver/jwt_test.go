@@ -0,0 +1,213 @@
+package apiserver
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testSigner struct {
+	kid string
+	alg string
+	key crypto.Signer
+}
+
+func (s testSigner) jwk() jwk {
+	switch key := s.key.Public().(type) {
+	case *rsa.PublicKey:
+		return jwk{
+			Kty: "RSA",
+			Kid: s.kid,
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return jwk{
+			Kty: "EC",
+			Kid: s.kid,
+			Crv: key.Curve.Params().Name,
+			X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+			Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+		}
+	}
+	panic("unsupported key")
+}
+
+func (s testSigner) sign(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": s.alg, "kid": s.kid})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	hash := crypto.SHA256
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	var signature []byte
+	var err error
+	switch key := s.key.(type) {
+	case *rsa.PrivateKey:
+		if strings.HasPrefix(s.alg, "PS") {
+			signature, err = rsa.SignPSS(rand.Reader, key, hash, digest, nil)
+		} else {
+			signature, err = rsa.SignPKCS1v15(rand.Reader, key, hash, digest)
+		}
+	case *ecdsa.PrivateKey:
+		var r, sv *big.Int
+		r, sv, err = ecdsa.Sign(rand.Reader, key, digest)
+		size := (key.Curve.Params().BitSize + 7) / 8
+		signature = append(r.FillBytes(make([]byte, size)), sv.FillBytes(make([]byte, size))...)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeJWKS(t *testing.T, signers ...testSigner) string {
+	t.Helper()
+	keys := make([]jwk, 0, len(signers))
+	for _, s := range signers {
+		keys = append(keys, s.jwk())
+	}
+	data, _ := json.Marshal(map[string]interface{}{"keys": keys})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func testSigners(t *testing.T) (rsaSigner, psSigner, ecSigner testSigner) {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testSigner{kid: "rsa", alg: "RS256", key: rsaKey},
+		testSigner{kid: "rsa", alg: "PS256", key: rsaKey},
+		testSigner{kid: "ec", alg: "ES256", key: ecKey}
+}
+
+func TestVerifyJWT(t *testing.T) {
+	rsaSigner, psSigner, ecSigner := testSigners(t)
+	keys := newJWKSCache(writeJWKS(t, rsaSigner, ecSigner))
+	claims := map[string]interface{}{"sub": "alice"}
+
+	unknown := rsaSigner
+	unknown.kid = "other"
+	forged := ecSigner
+	forged.key, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	wrongAlg := ecSigner
+	wrongAlg.alg = "RS256"
+	valid := rsaSigner.sign(t, claims)
+	parts := strings.Split(valid, ".")
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"rs256", valid, true},
+		{"ps256", psSigner.sign(t, claims), true},
+		{"es256", ecSigner.sign(t, claims), true},
+		{"unknown key", unknown.sign(t, claims), false},
+		{"forged signature", forged.sign(t, claims), false},
+		{"key type mismatch", wrongAlg.sign(t, claims), false},
+		{"tampered payload", parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"root"}`)) + "." + parts[2], false},
+		{"alg none", base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa"}`)) + "." + parts[1] + ".", false},
+		{"not a JWS", "abc.def", false},
+		{"bad encoding", "!!!." + parts[1] + "." + parts[2], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifyJWT(tt.token, keys)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("verifyJWT: %v", err)
+				}
+				if got.string("sub") != "alice" {
+					t.Fatalf("sub = %q", got.string("sub"))
+				}
+			} else if err == nil {
+				t.Fatal("verifyJWT accepted an invalid token")
+			}
+		})
+	}
+}
+
+func TestOIDCAuthenticate(t *testing.T) {
+	signer, _, _ := testSigners(t)
+	jwks := writeJWKS(t, signer)
+	now := time.Now().Unix()
+	claims := func(modify func(map[string]interface{})) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":   "https://issuer.example",
+			"aud":   []string{"restreamer", "other"},
+			"sub":   "alice",
+			"exp":   now + 300,
+			"roles": []string{"ops"},
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+	config := OIDCConfig{
+		Issuer:  "https://issuer.example",
+		JWKS:    jwks,
+		RoleMap: map[string]Role{"ops": RoleOperator},
+	}
+
+	tests := []struct {
+		name     string
+		audience string
+		clientID string
+		claims   map[string]interface{}
+		ok       bool
+	}{
+		{"valid", "restreamer", "", claims(nil), true},
+		{"audience from client id", "", "restreamer", claims(nil), true},
+		{"no audience configured", "", "", claims(nil), false},
+		{"audience mismatch", "restreamer", "", claims(func(c map[string]interface{}) { c["aud"] = "other" }), false},
+		{"issuer mismatch", "restreamer", "", claims(func(c map[string]interface{}) { c["iss"] = "https://evil.example" }), false},
+		{"expired", "restreamer", "", claims(func(c map[string]interface{}) { c["exp"] = now - 3600 }), false},
+		{"no expiry", "restreamer", "", claims(func(c map[string]interface{}) { delete(c, "exp") }), false},
+		{"not valid yet", "restreamer", "", claims(func(c map[string]interface{}) { c["nbf"] = now + 3600 }), false},
+		{"no mapped role", "restreamer", "", claims(func(c map[string]interface{}) { c["roles"] = "guest" }), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config
+			c.Audience = tt.audience
+			c.ClientID = tt.clientID
+			principal, err := newOIDCProvider(c).Authenticate(signer.sign(t, tt.claims))
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("Authenticate: %v", err)
+				}
+				if principal.Name != "alice" || principal.Role != RoleOperator {
+					t.Fatalf("principal = %+v", principal)
+				}
+			} else if err == nil {
+				t.Fatal("Authenticate accepted an invalid token")
+			}
+		})
+	}
+}
@@ -1,9 +1,16 @@
 package apiserver
 
 import (
-	"crypto/subtle"
+	"context"
+	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func ContentTypeJson(next http.Handler) http.Handler {
@@ -14,34 +21,130 @@ func ContentTypeJson(next http.Handler) http.Handler {
 	return http.HandlerFunc(fn)
 }
 
-func BasicAuth(next http.Handler) http.Handler {
+type principalKey struct{}
+
+func principalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+type authenticator struct {
+	users    *UserStore
+	registry registry.Registry
+	oidc     *oidcProvider // nil if OIDC is not configured
+}
+
+func newAuthenticator(users *UserStore, registry registry.Registry, oidc *oidcProvider) *authenticator {
+	return &authenticator{users: users, registry: registry, oidc: oidc}
+}
+
+// Middleware authenticates the request and stores the Principal in its context.
+func (a *authenticator) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		username := os.Getenv("BASIC_AUTH_USER")
-		password := os.Getenv("BASIC_AUTH_PASS")
-		
-		// Skip auth if credentials not configured
-		if username == "" || password == "" {
-			next.ServeHTTP(w, r)
+		// Skip auth if no users configured
+		if a.users.IsEmpty() && a.oidc == nil {
+			anonymous := &Principal{Name: "anonymous", Role: RoleAdmin, Scopes: AllScopes}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, anonymous)))
 			return
 		}
 
-		user, pass, ok := r.BasicAuth()
+		principal, ok := a.authenticate(r)
 		if !ok {
-			w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			if a.oidc != nil && a.oidc.loginEnabled() && r.Method == http.MethodGet &&
+				strings.Contains(r.Header.Get("Accept"), "text/html") {
+				http.Redirect(w, r, "/auth/login", http.StatusFound)
+				return
+			}
+			unauthorized(w)
 			return
 		}
 
-		// Use subtle.ConstantTimeCompare to prevent timing attacks
-		usernameMatch := subtle.ConstantTimeCompare([]byte(user), []byte(username)) == 1
-		passwordMatch := subtle.ConstantTimeCompare([]byte(pass), []byte(password)) == 1
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	})
+}
 
-		if !usernameMatch || !passwordMatch {
-			w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
+func (a *authenticator) authenticate(r *http.Request) (*Principal, bool) {
+	if token, ok := bearerToken(r); ok {
+		if strings.HasPrefix(token, tokenPrefix) || a.oidc == nil {
+			apiToken, err := a.registry.UseToken(hashToken(token), time.Now())
+			if err != nil {
+				return nil, false
+			}
+			return principalFromToken(apiToken), true
 		}
+		return a.authenticateJWT(token)
+	}
 
-		next.ServeHTTP(w, r)
-	})
+	if a.oidc != nil {
+		if cookie, err := r.Cookie(sessionCookie); err == nil && cookie.Value != "" {
+			return a.oidc.Session(cookie.Value)
+		}
+	}
+
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return nil, false
+	}
+	return a.users.Authenticate(user, pass)
+}
+
+func (a *authenticator) authenticateJWT(token string) (*Principal, bool) {
+	principal, err := a.oidc.Authenticate(token)
+	if err != nil {
+		log.Printf("JWT rejected: %v", err)
+		return nil, false
+	}
+	return principal, true
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// RequireScope rejects requests whose principal lacks the required scope.
+// If the route has an {id} parameter, the principal must also be scoped to that stream.
+func RequireScope(required Scope) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := principalFromContext(r.Context())
+			if principal == nil {
+				unauthorized(w)
+				return
+			}
+			if !principal.Can(required) {
+				JSONError(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			if id := chi.URLParam(r, "id"); id != "" && id != "-" && !principal.CanAccessStream(id) {
+				JSONError(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// legacyBasicAuthUser returns the admin user configured by BASIC_AUTH_USER and BASIC_AUTH_PASS.
+func legacyBasicAuthUser() *User {
+	username := os.Getenv("BASIC_AUTH_USER")
+	password := os.Getenv("BASIC_AUTH_PASS")
+	if username == "" || password == "" {
+		return nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("Failed to hash BASIC_AUTH_PASS: %v", err)
+		return nil
+	}
+	return &User{Name: username, PasswordHash: string(hash), Role: RoleAdmin}
 }
@@ -0,0 +1,92 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+)
+
+func TestRequireScope(t *testing.T) {
+	handler, reg := newTestAPI(t)
+	for _, name := range []string{"s1", "s2"} {
+		if err := reg.Update(&registry.ExternalStream{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	target := `{"name":"t","url":"rtmp://host/live/KEY"}`
+	tests := []struct {
+		user   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"", http.MethodGet, "/api/streams/", "", http.StatusUnauthorized},
+		{"nobody", http.MethodGet, "/api/streams/", "", http.StatusUnauthorized},
+
+		{"viewer", http.MethodGet, "/api/streams/", "", http.StatusOK},
+		{"viewer", http.MethodGet, "/api/streams/s1", "", http.StatusOK},
+		{"viewer", http.MethodGet, "/api/streams/-/status", "", http.StatusOK},
+		{"viewer", http.MethodPost, "/api/streams/s1/targets", target, http.StatusForbidden},
+		{"viewer", http.MethodPost, "/api/streams/", `{"name":"s3"}`, http.StatusForbidden},
+		{"viewer", http.MethodDelete, "/api/streams/s2", "", http.StatusForbidden},
+
+		{"operator", http.MethodPost, "/api/streams/s1/targets", target, http.StatusOK},
+		{"operator", http.MethodDelete, "/api/streams/s1/targets", `{"target":"t"}`, http.StatusOK},
+		{"operator", http.MethodPost, "/api/streams/", `{"name":"s3"}`, http.StatusForbidden},
+		{"operator", http.MethodDelete, "/api/streams/s2", "", http.StatusForbidden},
+
+		{"admin", http.MethodPost, "/api/streams/", `{"name":"s3"}`, http.StatusOK},
+		{"admin", http.MethodDelete, "/api/streams/s3", "", http.StatusOK},
+
+		// Restricted to s1
+		{"restricted", http.MethodGet, "/api/streams/s1", "", http.StatusOK},
+		{"restricted", http.MethodGet, "/api/streams/s2", "", http.StatusForbidden},
+		{"restricted", http.MethodGet, "/api/streams/s2/status", "", http.StatusForbidden},
+		{"restricted", http.MethodPost, "/api/streams/s2/targets", target, http.StatusForbidden},
+		{"restricted", http.MethodDelete, "/api/streams/s2", "", http.StatusForbidden},
+		{"restricted", http.MethodPost, "/api/streams/", `{"name":"s3"}`, http.StatusForbidden},
+		{"restricted", http.MethodPost, "/api/streams/s1/targets", target, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := serve(handler, tt.user, tt.method, tt.path, tt.body).Code; got != tt.want {
+			t.Errorf("%s %s as %q: status %d, want %d", tt.method, tt.path, tt.user, got, tt.want)
+		}
+	}
+}
+
+func TestStreamVisibility(t *testing.T) {
+	handler, reg := newTestAPI(t)
+	for _, name := range []string{"s1", "s2"} {
+		if err := reg.Update(&registry.ExternalStream{Name: name, Targets: []registry.PushTarget{
+			{Name: "rtmp://host/live/KEY", URL: "rtmp://host/live/KEY"},
+		}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list := func(user, path string) []registry.ExternalStream {
+		w := serve(handler, user, http.MethodGet, path, "")
+		var streams []registry.ExternalStream
+		if err := json.NewDecoder(w.Body).Decode(&streams); err != nil {
+			t.Fatalf("GET %s as %s: %v", path, user, err)
+		}
+		return streams
+	}
+
+	for _, path := range []string{"/api/streams/", "/api/streams/-/status"} {
+		if streams := list("restricted", path); len(streams) != 1 || streams[0].Name != "s1" {
+			t.Errorf("GET %s as restricted lists %+v, want only s1", path, streams)
+		}
+		if streams := list("admin", path); len(streams) != 2 || streams[0].Targets[0].URL != "rtmp://host/live/KEY" {
+			t.Errorf("GET %s as admin lists %+v, want both streams with their keys", path, streams)
+		}
+		// Without keys:read neither the URL nor the name which defaults to it shows the key
+		for _, stream := range list("operator", path) {
+			if target := stream.Targets[0]; target.URL != "rtmp://host/live/****" || target.Name != target.URL {
+				t.Errorf("GET %s as operator shows the target %+v", path, target)
+			}
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package apiserver
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	sessionCookie = "restreamer_session"
+	stateCookie   = "restreamer_oidc_state"
+	jwtLeeway     = time.Minute
+	loginTimeout  = 10 * time.Minute
+)
+
+type OIDCConfig struct {
+	Issuer   string
+	JWKS     string // file path or URL; discovered from the issuer if empty
+	Audience string // defaults to ClientID, one of them is required
+
+	// Login redirect flow for the web UI, enabled when ClientID and RedirectURL are set
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	AuthURL      string // discovered from the issuer if empty
+	TokenURL     string // discovered from the issuer if empty
+
+	RoleClaim    string          // defaults to "roles"
+	RoleMap      map[string]Role // claim value -> role
+	StreamsClaim string          // optional claim with the list of allowed streams
+
+	SessionTTL time.Duration // lifetime of web UI sessions, independent of the id_token; defaults to 12 hours
+}
+
+// ParseRoleMap parses "claim-value=role,..." pairs.
+func ParseRoleMap(s string) (map[string]Role, error) {
+	result := make(map[string]Role)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		value, roleName, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid role mapping %q", pair)
+		}
+		role, err := ParseRole(roleName)
+		if err != nil {
+			return nil, err
+		}
+		result[value] = role
+	}
+	return result, nil
+}
+
+type oidcProvider struct {
+	config OIDCConfig
+	keys   *jwksCache
+
+	discovered bool
+	mu         sync.Mutex
+
+	logins     map[string]oidcLogin   // state -> login redirected to the provider
+	sessions   map[string]oidcSession // hash of the session cookie -> session
+	sessionsMu sync.Mutex
+}
+
+// oidcLogin is what the callback of a login checks, it must come back within loginTimeout.
+type oidcLogin struct {
+	nonce    string // expected in the id_token
+	verifier string // PKCE code verifier
+	expires  time.Time
+}
+
+// oidcSession is a web UI session. Only the hash of its cookie is kept, like for API tokens.
+type oidcSession struct {
+	principal *Principal
+	expires   time.Time
+}
+
+func newOIDCProvider(config OIDCConfig) *oidcProvider {
+	if config.Audience == "" {
+		config.Audience = config.ClientID
+	}
+	if config.RoleClaim == "" {
+		config.RoleClaim = "roles"
+	}
+	if config.SessionTTL == 0 {
+		config.SessionTTL = 12 * time.Hour
+	}
+	p := &oidcProvider{config: config, logins: make(map[string]oidcLogin), sessions: make(map[string]oidcSession)}
+	if config.JWKS != "" {
+		p.keys = newJWKSCache(config.JWKS)
+	}
+	return p
+}
+
+func (p *oidcProvider) loginEnabled() bool {
+	return p.config.ClientID != "" && p.config.RedirectURL != ""
+}
+
+// discover fills the endpoints which were not configured from the issuer's discovery document.
+func (p *oidcProvider) discover() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovered || (p.keys != nil && (!p.loginEnabled() || p.config.AuthURL != "" && p.config.TokenURL != "")) {
+		return nil
+	}
+
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OIDC discovery: %s", resp.Status)
+	}
+	var doc struct {
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return err
+	}
+	if p.config.AuthURL == "" {
+		p.config.AuthURL = doc.AuthorizationEndpoint
+	}
+	if p.config.TokenURL == "" {
+		p.config.TokenURL = doc.TokenEndpoint
+	}
+	if p.keys == nil {
+		p.keys = newJWKSCache(doc.JWKSURI)
+	}
+	p.discovered = true
+	return nil
+}
+
+// Authenticate validates the JWT and maps its claims to a principal.
+func (p *oidcProvider) Authenticate(token string) (*Principal, error) {
+	principal, _, err := p.authenticate(token)
+	return principal, err
+}
+
+func (p *oidcProvider) authenticate(token string) (*Principal, jwtClaims, error) {
+	if err := p.discover(); err != nil {
+		return nil, nil, err
+	}
+	claims, err := verifyJWT(token, p.keys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if claims.string("iss") != p.config.Issuer {
+		return nil, nil, errors.New("JWT issuer mismatch")
+	}
+	// Without an expected audience any token of the issuer would be accepted, so fail closed
+	if p.config.Audience == "" || !containsString(claims.strings("aud"), p.config.Audience) {
+		return nil, nil, errors.New("JWT audience mismatch")
+	}
+	exp, ok := claims.time("exp")
+	if !ok || now.After(exp.Add(jwtLeeway)) {
+		return nil, nil, errors.New("JWT expired")
+	}
+	if nbf, ok := claims.time("nbf"); ok && now.Add(jwtLeeway).Before(nbf) {
+		return nil, nil, errors.New("JWT not valid yet")
+	}
+
+	var role Role
+	for _, value := range claims.strings(p.config.RoleClaim) {
+		if mapped, ok := p.config.RoleMap[value]; ok && mapped.rank() > role.rank() {
+			role = mapped
+		}
+	}
+	if role == "" {
+		return nil, nil, errors.New("JWT has no mapped role")
+	}
+
+	name := claims.string("preferred_username")
+	if name == "" {
+		name = claims.string("email")
+	}
+	if name == "" {
+		name = claims.string("sub")
+	}
+	principal := &Principal{Name: name, Role: role, Scopes: role.Scopes()}
+	if p.config.StreamsClaim != "" {
+		principal.Streams = claims.strings(p.config.StreamsClaim)
+	}
+	return principal, claims, nil
+}
+
+func (p *oidcProvider) Routes(r chi.Router) {
+	r.Get("/auth/login", p.login())
+	r.Get("/auth/callback", p.callback())
+	r.Get("/auth/logout", p.logout())
+}
+
+// Session returns the principal of the web UI session with the cookie value.
+func (p *oidcProvider) Session(id string) (*Principal, bool) {
+	p.sessionsMu.Lock()
+	defer p.sessionsMu.Unlock()
+	session, ok := p.sessions[hashToken(id)]
+	if !ok || time.Now().After(session.expires) {
+		return nil, false
+	}
+	return session.principal, true
+}
+
+// pruneLocked drops the expired logins and sessions, called with sessionsMu held.
+func (p *oidcProvider) pruneLocked(now time.Time) {
+	for state, login := range p.logins {
+		if now.After(login.expires) {
+			delete(p.logins, state)
+		}
+	}
+	for hash, session := range p.sessions {
+		if now.After(session.expires) {
+			delete(p.sessions, hash)
+		}
+	}
+}
+
+func (p *oidcProvider) login() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := p.discover(); err != nil {
+			log.Printf("OIDC discovery failed: %v", err)
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+			return
+		}
+		var state, nonce, verifier string
+		var err error
+		for _, value := range []*string{&state, &nonce, &verifier} {
+			if *value, err = randomString(); err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+		}
+		now := time.Now()
+		p.sessionsMu.Lock()
+		p.pruneLocked(now)
+		p.logins[state] = oidcLogin{nonce: nonce, verifier: verifier, expires: now.Add(loginTimeout)}
+		p.sessionsMu.Unlock()
+		http.SetCookie(w, &http.Cookie{
+			Name:     stateCookie,
+			Value:    state,
+			Path:     "/auth/",
+			MaxAge:   int(loginTimeout.Seconds()),
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		challenge := sha256.Sum256([]byte(verifier))
+		query := url.Values{}
+		query.Set("response_type", "code")
+		query.Set("client_id", p.config.ClientID)
+		query.Set("redirect_uri", p.config.RedirectURL)
+		query.Set("scope", "openid profile email")
+		query.Set("state", state)
+		query.Set("nonce", nonce)
+		query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+		query.Set("code_challenge_method", "S256")
+		http.Redirect(w, r, p.config.AuthURL+"?"+query.Encode(), http.StatusFound)
+	}
+}
+
+func (p *oidcProvider) callback() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state, err := r.Cookie(stateCookie)
+		if err != nil || state.Value == "" || state.Value != r.URL.Query().Get("state") {
+			http.Error(w, "Invalid OIDC state", http.StatusBadRequest)
+			return
+		}
+		// A login is completed once
+		p.sessionsMu.Lock()
+		login, ok := p.logins[state.Value]
+		delete(p.logins, state.Value)
+		p.sessionsMu.Unlock()
+		if !ok || time.Now().After(login.expires) {
+			http.Error(w, "Invalid OIDC state", http.StatusBadRequest)
+			return
+		}
+		code := r.URL.Query().Get("code")
+		if code == "" {
+			http.Error(w, "Missing authorization code", http.StatusBadRequest)
+			return
+		}
+
+		idToken, err := p.exchange(code, login.verifier)
+		if err != nil {
+			log.Printf("OIDC code exchange failed: %v", err)
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+			return
+		}
+		principal, claims, err := p.authenticate(idToken)
+		if err == nil && claims.string("nonce") != login.nonce {
+			err = errors.New("id_token nonce mismatch")
+		}
+		if err != nil {
+			log.Printf("OIDC login rejected: %v", err)
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		log.Printf("OIDC login of %s as %s", principal.Name, principal.Role)
+
+		// The session is ours, so it doesn't end with the id_token
+		session, err := randomString()
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		now := time.Now()
+		p.sessionsMu.Lock()
+		p.pruneLocked(now)
+		p.sessions[hashToken(session)] = oidcSession{principal: principal, expires: now.Add(p.config.SessionTTL)}
+		p.sessionsMu.Unlock()
+
+		http.SetCookie(w, &http.Cookie{Name: stateCookie, Path: "/auth/", MaxAge: -1})
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookie,
+			Value:    session,
+			Path:     "/",
+			MaxAge:   int(p.config.SessionTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, "/", http.StatusFound)
+	}
+}
+
+func (p *oidcProvider) logout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie(sessionCookie); err == nil {
+			p.sessionsMu.Lock()
+			delete(p.sessions, hashToken(cookie.Value))
+			p.sessionsMu.Unlock()
+		}
+		http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+		http.Redirect(w, r, "/", http.StatusFound)
+	}
+}
+
+func (p *oidcProvider) exchange(code, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("code_verifier", verifier)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.PostForm(p.config.TokenURL, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint: %s", resp.Status)
+	}
+	var tokens struct {
+		IdToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return "", err
+	}
+	if tokens.IdToken == "" {
+		return "", errors.New("token endpoint returned no id_token")
+	}
+	return tokens.IdToken, nil
+}
+
+// randomString returns 64 hex digits, long enough for a PKCE code verifier.
+func randomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package apiserver
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// testIdP is the token endpoint of an identity provider which checks the PKCE verifier of the code.
+type testIdP struct {
+	t         *testing.T
+	signer    testSigner
+	challenge string // of the last login
+	nonce     string // put into the id_token
+	idToken   string // last issued
+}
+
+func (idp *testIdP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if r.PostFormValue("code") != "code" || base64.RawURLEncoding.EncodeToString(verifier[:]) != idp.challenge {
+		http.Error(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+	idp.idToken = idp.signer.sign(idp.t, map[string]interface{}{
+		"iss":   "https://issuer.example",
+		"aud":   "restreamer",
+		"sub":   "alice",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"roles": []string{"ops"},
+		"nonce": idp.nonce,
+	})
+	_ = json.NewEncoder(w).Encode(map[string]string{"id_token": idp.idToken})
+}
+
+func TestOIDCLogin(t *testing.T) {
+	signer, _, _ := testSigners(t)
+	idp := &testIdP{t: t, signer: signer}
+	server := httptest.NewServer(idp)
+	defer server.Close()
+	p := newOIDCProvider(OIDCConfig{
+		Issuer:      "https://issuer.example",
+		JWKS:        writeJWKS(t, signer),
+		ClientID:    "restreamer",
+		RedirectURL: "https://restreamer.example/auth/callback",
+		AuthURL:     "https://issuer.example/authorize",
+		TokenURL:    server.URL,
+		RoleMap:     map[string]Role{"ops": RoleOperator},
+	})
+	router := http.NewServeMux()
+	router.Handle("/auth/login", p.login())
+	router.Handle("/auth/callback", p.callback())
+	router.Handle("/auth/logout", p.logout())
+
+	// login redirects to the provider and returns the state cookie and the authorization request
+	login := func() (*http.Cookie, url.Values) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+		location, err := url.Parse(w.Header().Get("Location"))
+		if w.Code != http.StatusFound || err != nil {
+			t.Fatalf("login: status %d, location %s", w.Code, w.Header().Get("Location"))
+		}
+		query := location.Query()
+		if query.Get("nonce") == "" || query.Get("code_challenge_method") != "S256" || len(query.Get("code_challenge")) != 43 {
+			t.Fatalf("authorization request without nonce or PKCE: %v", query)
+		}
+		idp.challenge, idp.nonce = query.Get("code_challenge"), query.Get("nonce")
+		return w.Result().Cookies()[0], query
+	}
+	callback := func(state *http.Cookie, query url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/auth/callback?code=code&state="+query.Get("state"), nil)
+		r.AddCookie(state)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	state, query := login()
+	w := callback(state, query)
+	if w.Code != http.StatusFound {
+		t.Fatalf("callback: status %d %s", w.Code, w.Body)
+	}
+	var session *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == sessionCookie {
+			session = cookie
+		}
+	}
+	if session == nil || session.Value == idp.idToken {
+		t.Fatalf("session cookie %+v, want a value of our own", session)
+	}
+	if principal, ok := p.Session(session.Value); !ok || principal.Name != "alice" || principal.Role != RoleOperator {
+		t.Fatalf("session principal %+v", principal)
+	}
+	if _, ok := p.Session(idp.idToken); ok {
+		t.Fatal("the id_token is accepted as a session")
+	}
+
+	// The state can't be used twice
+	if w := callback(state, query); w.Code != http.StatusBadRequest {
+		t.Fatalf("replayed callback: status %d", w.Code)
+	}
+
+	// An id_token issued for another login
+	state, query = login()
+	idp.nonce = "other"
+	if w := callback(state, query); w.Code != http.StatusForbidden {
+		t.Fatalf("callback with a nonce mismatch: status %d", w.Code)
+	}
+
+	// A code intercepted by someone without the verifier
+	state, query = login()
+	idp.challenge = "intercepted"
+	if w := callback(state, query); w.Code != http.StatusBadGateway {
+		t.Fatalf("callback with a PKCE mismatch: status %d", w.Code)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/auth/logout", nil)
+	r.AddCookie(session)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	if _, ok := p.Session(session.Value); ok {
+		t.Fatal("the session is valid after the logout")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver"
 	"log"
 	"net/http"
 	"net/url"
@@ -24,19 +25,35 @@ func newStreamsRouter(router chi.Router, registry registry.Registry) *streamRout
 }
 
 func (router *streamRouter) Routes() {
+	router.r.With(ContentTypeJson).Get("/api/me", router.getMe())
 	router.r.Route("/api/streams", func(r chi.Router) {
 		r.Use(ContentTypeJson)
-		r.Get("/", router.getStreams())
-		r.Get("/{id}", router.getStreamById())
-		r.Get("/-/status", router.getStreamsInfo())
-		r.Post("/", router.createStream())
-		r.Delete("/{id}", router.deleteBankByID())
-		r.Get("/{id}/status", router.getStreamStatusById())
-		r.Post("/{id}/targets", router.addStreamTargetByStreamId())
-		r.Delete("/{id}/targets", router.deleteStreamTargetByStreamId())
+		r.With(RequireScope(ScopeStatusRead)).Get("/", router.getStreams())
+		r.With(RequireScope(ScopeStatusRead)).Get("/{id}", router.getStreamById())
+		r.With(RequireScope(ScopeStatusRead)).Get("/-/status", router.getStreamsInfo())
+		r.With(RequireScope(ScopeStreamsWrite)).Post("/", router.createStream())
+		r.With(RequireScope(ScopeStreamsWrite)).Delete("/{id}", router.deleteBankByID())
+		r.With(RequireScope(ScopeStatusRead)).Get("/{id}/status", router.getStreamStatusById())
+		r.With(RequireScope(ScopeTargetsWrite)).Post("/{id}/targets", router.addStreamTargetByStreamId())
+		r.With(RequireScope(ScopeTargetsWrite)).Delete("/{id}/targets", router.deleteStreamTargetByStreamId())
 	})
 }
 
+func (router *streamRouter) getMe() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := principalFromContext(r.Context())
+		if err := json.NewEncoder(w).Encode(PrincipalInfo{
+			Name:    principal.Name,
+			Role:    principal.Role,
+			Scopes:  principal.Scopes,
+			Streams: principal.Streams,
+		}); err != nil {
+			handleErrors(w, err)
+			return
+		}
+	}
+}
+
 func (router *streamRouter) getStreams() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		streams, err := router.registry.GetStreams()
@@ -44,7 +61,14 @@ func (router *streamRouter) getStreams() http.HandlerFunc {
 			handleErrors(w, err)
 			return
 		}
-		if err := json.NewEncoder(w).Encode(streams); err != nil {
+		principal := principalFromContext(r.Context())
+		visible := make([]*registry.ExternalStream, 0, len(streams))
+		for _, stream := range streams {
+			if principal.CanAccessStream(stream.Name) {
+				visible = append(visible, redactStream(principal, stream))
+			}
+		}
+		if err := json.NewEncoder(w).Encode(visible); err != nil {
 			handleErrors(w, err)
 			return
 		}
@@ -60,7 +84,7 @@ func (router *streamRouter) getStreamById() http.HandlerFunc {
 			handleErrors(w, err)
 			return
 		}
-		if err := json.NewEncoder(w).Encode(stream); err != nil {
+		if err := json.NewEncoder(w).Encode(redactStream(principalFromContext(r.Context()), stream)); err != nil {
 			handleErrors(w, err)
 			return
 		}
@@ -75,6 +99,10 @@ func (router *streamRouter) createStream() http.HandlerFunc {
 			handleErrors(w, err)
 			return
 		}
+		if !principalFromContext(r.Context()).CanAccessStream(stream.Name) {
+			JSONError(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
 		err := router.registry.Update(&stream)
 		if err != nil {
 			handleErrors(w, err)
@@ -127,7 +155,7 @@ func (router *streamRouter) addStreamTargetByStreamId() http.HandlerFunc {
 			targetName = targetInfo.URL // Use URL as name if no name provided
 		}
 
-		err = router.registry.AddStreamTarget(chi.URLParam(r, "id"), (*api.PushTargetUrl)(target), targetName)
+		err = router.registry.AddStreamTarget(chi.URLParam(r, "id"), (*api.PushTargetUrl)(target), targetName, targetInfo.PushTargetOptions)
 		if err != nil {
 			handleErrors(w, err)
 			return
@@ -143,7 +171,18 @@ func (router *streamRouter) deleteStreamTargetByStreamId() http.HandlerFunc {
 			return
 		}
 
-		err := router.registry.DeleteStreamTarget(chi.URLParam(r, "id"), targetInfo.Target)
+		id := chi.URLParam(r, "id")
+		target := targetInfo.Target
+		if !principalFromContext(r.Context()).Can(ScopeKeysRead) {
+			// Non-admins only see redacted URLs and names, so resolve them back to the stored ones
+			var err error
+			if target, err = router.resolveRedactedTarget(id, target); err != nil {
+				handleErrors(w, err)
+				return
+			}
+		}
+
+		err := router.registry.DeleteStreamTarget(id, target)
 		if err != nil {
 			handleErrors(w, err)
 			return
@@ -158,13 +197,87 @@ func (router *streamRouter) getStreamsInfo() http.HandlerFunc {
 			handleErrors(w, err)
 			return
 		}
-		if err := json.NewEncoder(w).Encode(streams); err != nil {
+		principal := principalFromContext(r.Context())
+		visible := make([]*registry.ExternalStreamInfo, 0, len(streams))
+		for _, stream := range streams {
+			if principal.CanAccessStream(stream.Name) {
+				info := *stream
+				info.ExternalStream = *redactStream(principal, &stream.ExternalStream)
+				if !principal.Can(ScopeKeysRead) {
+					info.TargetsStatus = make([]registry.TargetStatus, len(stream.TargetsStatus))
+					for i, status := range stream.TargetsStatus {
+						info.TargetsStatus[i] = status
+						info.TargetsStatus[i].URL = redactTargetURL(status.URL)
+						if status.Name == status.URL {
+							info.TargetsStatus[i].Name = info.TargetsStatus[i].URL
+						}
+					}
+				}
+				visible = append(visible, &info)
+			}
+		}
+		if err := json.NewEncoder(w).Encode(visible); err != nil {
 			handleErrors(w, err)
 			return
 		}
 	}
 }
 
+// resolveRedactedTarget returns the URL of the target with the redacted URL or the name.
+// Targets which differ only by their keys have the same redacted URL, then the name must tell them apart.
+func (router *streamRouter) resolveRedactedTarget(streamName string, target string) (string, error) {
+	stream, err := router.registry.GetStream(streamName)
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for _, t := range stream.Targets {
+		if redactTargetURL(t.URL) == target || t.Name == target {
+			matches = append(matches, t.URL)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return target, nil
+	case 1:
+		return matches[0], nil
+	}
+	return "", AmbiguousTarget{Target: target}
+}
+
+// redactStream hides stream keys of the targets and the source from principals without the keys:read scope.
+func redactStream(principal *Principal, stream *registry.ExternalStream) *registry.ExternalStream {
+	if principal.Can(ScopeKeysRead) {
+		return stream
+	}
+	redacted := *stream
+	redacted.Targets = make([]registry.PushTarget, len(stream.Targets))
+	for i, target := range stream.Targets {
+		redacted.Targets[i] = target
+		redacted.Targets[i].URL = redactTargetURL(target.URL)
+		redacted.Targets[i].PushTargetOptions = target.PushTargetOptions.Redacted()
+		if target.Name == target.URL {
+			redacted.Targets[i].Name = redacted.Targets[i].URL
+		}
+	}
+	if stream.Source != "" {
+		redacted.Source = redactTargetURL(stream.Source)
+	}
+	if stream.SourceOptions != nil {
+		options := stream.SourceOptions.Redacted()
+		redacted.SourceOptions = &options
+	}
+	return &redacted
+}
+
+func redactTargetURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "****"
+	}
+	return (*api.PushTargetUrl)(parsed).Redacted()
+}
+
 // ErrorResponse represents json error structure
 type ErrorResponse struct {
 	Error string `json:"error"`
@@ -191,8 +304,12 @@ func handleErrors(w http.ResponseWriter, err error) {
 		return
 	}
 	switch err.(type) {
-	case registry.StreamNotFound:
+	case registry.StreamNotFound, registry.TokenNotFound, rtmpserver.SessionNotFound:
 		JSONError(w, err.Error(), http.StatusNotFound)
+	case registry.InvalidStreamConfig:
+		JSONError(w, err.Error(), http.StatusBadRequest)
+	case AmbiguousTarget:
+		JSONError(w, err.Error(), http.StatusConflict)
 	default:
 		log.Printf(logFormat, err)
 		JSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
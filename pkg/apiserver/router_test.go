@@ -0,0 +1,108 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestAPI returns the stream and token routes behind the authentication with a user of each role and
+// the admin "restricted" which can only access the stream s1. The passwords are the names of the users.
+func newTestAPI(t *testing.T) (http.Handler, registry.Registry) {
+	t.Helper()
+	old := registry.REGESTRY_STORAGE_FILE
+	registry.REGESTRY_STORAGE_FILE = filepath.Join(t.TempDir(), "data.json")
+	t.Cleanup(func() { registry.REGESTRY_STORAGE_FILE = old })
+	reg := registry.NewRegistry(registry.Config{})
+	t.Cleanup(func() {
+		streams, _ := reg.GetStreams()
+		for _, stream := range streams {
+			_ = reg.DeleteStream(stream.Name)
+		}
+	})
+
+	users, _ := NewUserStore("")
+	for _, user := range []*User{
+		{Name: "viewer", Role: RoleViewer},
+		{Name: "operator", Role: RoleOperator},
+		{Name: "admin", Role: RoleAdmin},
+		{Name: "restricted", Role: RoleAdmin, Streams: []string{"s1"}},
+	} {
+		hash, err := bcrypt.GenerateFromPassword([]byte(user.Name), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user.PasswordHash = string(hash)
+		users.AddUser(user)
+	}
+
+	router := chi.NewRouter()
+	router.Group(func(r chi.Router) {
+		r.Use(newAuthenticator(users, reg, nil).Middleware)
+		newStreamsRouter(r, reg).Routes()
+		newTokensRouter(r, reg).Routes()
+	})
+	return router, reg
+}
+
+// serve sends the request as the user or with the bearer token if it has the token prefix,
+// anonymously if the credential is empty.
+func serve(handler http.Handler, credential, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if strings.HasPrefix(credential, tokenPrefix) {
+		r.Header.Set("Authorization", "Bearer "+credential)
+	} else if credential != "" {
+		r.SetBasicAuth(credential, credential)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestDeleteRedactedTarget(t *testing.T) {
+	handler, reg := newTestAPI(t)
+	if err := reg.Update(&registry.ExternalStream{Name: "s1", Targets: []registry.PushTarget{
+		{Name: "first", URL: "rtmp://host/live/AAA"},
+		{Name: "second", URL: "rtmp://host/live/BBB"},
+		{Name: "rtmp://host/other/CCC", URL: "rtmp://host/other/CCC"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	remaining := func() []string {
+		stream, _ := reg.GetStream("s1")
+		var urls []string
+		for _, target := range stream.Targets {
+			urls = append(urls, target.URL)
+		}
+		return urls
+	}
+	deleteTarget := func(target string) int {
+		body, _ := json.Marshal(DeleteTargetInfo{Target: target})
+		return serve(handler, "operator", http.MethodDelete, "/api/streams/s1/targets", string(body)).Code
+	}
+
+	// The first two targets redact to the same URL
+	if code := deleteTarget("rtmp://host/live/****"); code != http.StatusConflict {
+		t.Fatalf("delete of an ambiguous redacted URL: status %d, want %d", code, http.StatusConflict)
+	}
+	if got := remaining(); len(got) != 3 {
+		t.Fatalf("targets after the ambiguous delete: %v", got)
+	}
+
+	if code := deleteTarget("second"); code != http.StatusOK {
+		t.Fatalf("delete by name: status %d", code)
+	}
+	if code := deleteTarget("rtmp://host/other/****"); code != http.StatusOK {
+		t.Fatalf("delete by the redacted URL: status %d", code)
+	}
+	if got := remaining(); len(got) != 1 || got[0] != "rtmp://host/live/AAA" {
+		t.Fatalf("targets left: %v, want the first one", got)
+	}
+}
@@ -1,44 +1,187 @@
 package apiserver
 
 import (
+	"crypto/tls"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/tlsutil"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
 	"log"
+	"net"
 	"net/http"
 	"path/filepath"
 	"strings"
 )
 
 type webServer struct {
+	config   WebServerConfig
 	registry registry.Registry
 	router   *chi.Mux
 }
 
-func NewWebServer(registry registry.Registry) *webServer {
+type WebServerConfig struct {
+	Addrs     []string // defaults to :6070
+	UsersFile string
+	OIDC      *OIDCConfig
+
+	TLSCertFile string
+	TLSKeyFile  string
+	// HTTPRedirectAddr serves redirects to HTTPS (and ACME HTTP-01 challenges) when set
+	HTTPRedirectAddr string
+
+	ACMEDomains      []string
+	ACMEDirectoryURL string // defaults to Let's Encrypt
+	ACMEEmail        string
+	ACMECacheDir     string
+
+	HLSSigningKey string // enables signed HLS and CMAF URLs
+}
+
+func (c WebServerConfig) tlsEnabled() bool {
+	return c.TLSCertFile != "" || len(c.ACMEDomains) > 0
+}
+
+func NewWebServer(config WebServerConfig, registry registry.Registry, sessions SessionManager) *webServer {
+	users, err := NewUserStore(config.UsersFile)
+	if err != nil {
+		log.Fatalf("Failed to load users file: %v", err)
+	}
+	if user := legacyBasicAuthUser(); user != nil {
+		users.AddUser(user)
+	}
+
+	var oidc *oidcProvider
+	if config.OIDC != nil {
+		if config.OIDC.Audience == "" && config.OIDC.ClientID == "" {
+			log.Fatalf("OIDC requires an audience or a client id")
+		}
+		oidc = newOIDCProvider(*config.OIDC)
+	}
+
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID)
 	router.Use(loggerMiddleware())
 	router.Use(middleware.Recoverer)
-	router.Use(BasicAuth)
+	if oidc != nil && oidc.loginEnabled() {
+		oidc.Routes(router)
+	}
 
-	streamRouter := newStreamsRouter(router, registry)
-	streamRouter.Routes()
-	router.Mount("/debug", middleware.Profiler())
+	auth := newAuthenticator(users, registry, oidc)
+	hlsRouter := newHLSRouter(registry, config.HLSSigningKey)
+	cmafRouter := newCMAFRouter(hlsRouter)
+	router.Group(func(r chi.Router) {
+		hlsRouter.Routes(r, auth)
+		cmafRouter.Routes(r, auth)
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(auth.Middleware)
 
-	// Serve static files from web directory
-	workDir, _ := filepath.Abs(".")
-	filesDir := http.Dir(filepath.Join(workDir, "web"))
-	FileServer(router, "/", filesDir)
+		streamRouter := newStreamsRouter(r, registry)
+		streamRouter.Routes()
+		tokenRouter := newTokensRouter(r, registry)
+		tokenRouter.Routes()
+		sessionRouter := newSessionsRouter(r, sessions)
+		sessionRouter.Routes()
+		ingestRouter := newIngestRouter(r, sessions)
+		ingestRouter.Routes()
+		whipRouter := newWhipRouter(r, sessions)
+		whipRouter.Routes()
+		hlsRouter.APIRoutes(r)
+		cmafRouter.APIRoutes(r)
+		flvRouter := newFLVRouter(r, sessions)
+		flvRouter.Routes()
+		r.With(RequireScope(ScopeDebug)).Mount("/debug", middleware.Profiler())
+
+		// Serve static files from web directory
+		workDir, _ := filepath.Abs(".")
+		filesDir := http.Dir(filepath.Join(workDir, "web"))
+		FileServer(r, "/", filesDir)
+	})
 
 	return &webServer{
+		config:   config,
 		registry: registry,
 		router:   router,
 	}
 }
 
 func (a *webServer) Start() {
-	log.Fatal(http.ListenAndServe(":6070", a.router)) //viper.GetString("server.port")
+	addrs := a.config.Addrs
+	if len(addrs) == 0 {
+		addrs = []string{":6070"}
+	}
+	if !a.config.tlsEnabled() {
+		for _, addr := range addrs[1:] {
+			go func(addr string) {
+				log.Fatal(http.ListenAndServe(addr, a.router))
+			}(addr)
+		}
+		log.Fatal(http.ListenAndServe(addrs[0], a.router)) //viper.GetString("server.port")
+	}
+
+	var tlsConfig *tls.Config
+	redirect := httpsRedirectHandler(addrs[0])
+	if len(a.config.ACMEDomains) > 0 {
+		manager := a.acmeManager()
+		tlsConfig = manager.TLSConfig()
+		redirect = manager.HTTPHandler(redirect)
+	} else {
+		reloader, err := tlsutil.NewCertReloader(a.config.TLSCertFile, a.config.TLSKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load TLS certificate: %v", err)
+		}
+		tlsConfig = reloader.TLSConfig()
+	}
+
+	if a.config.HTTPRedirectAddr != "" {
+		go func() {
+			log.Fatal(http.ListenAndServe(a.config.HTTPRedirectAddr, redirect))
+		}()
+	}
+
+	for _, addr := range addrs[1:] {
+		go func(addr string) {
+			server := &http.Server{Addr: addr, Handler: a.router, TLSConfig: tlsConfig}
+			log.Fatal(server.ListenAndServeTLS("", ""))
+		}(addr)
+	}
+	server := &http.Server{Addr: addrs[0], Handler: a.router, TLSConfig: tlsConfig}
+	log.Fatal(server.ListenAndServeTLS("", ""))
+}
+
+func (a *webServer) acmeManager() *autocert.Manager {
+	cacheDir := a.config.ACMECacheDir
+	if cacheDir == "" {
+		cacheDir = "acme-cache"
+	}
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		HostPolicy: autocert.HostWhitelist(a.config.ACMEDomains...),
+		Email:      a.config.ACMEEmail,
+	}
+	if a.config.ACMEDirectoryURL != "" {
+		manager.Client = &acme.Client{DirectoryURL: a.config.ACMEDirectoryURL}
+	}
+	return manager
+}
+
+// httpsRedirectHandler redirects to the same host and path on the HTTPS address.
+func httpsRedirectHandler(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
 }
 
 // FileServer conveniently sets up a http.FileServer handler to serve
@@ -0,0 +1,74 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver"
+)
+
+// SessionManager lists and disconnects live media sessions, and accepts publishers and players over HTTP.
+type SessionManager interface {
+	Sessions() []rtmpserver.SessionInfo
+	CloseSession(id string) error
+	AcceptFLVIngest(body io.Reader, remoteAddr string, secure bool, closer func() error, streamName string, query url.Values) (func() error, error)
+	AcceptWHIP(offer, remoteAddr, streamName string, query url.Values) (answer, sessionId string, err error)
+	AcceptFLVPlayer(protocol, remoteAddr string, secure bool, streamName string, query url.Values) (func(ctx context.Context, write func(data []byte) error) error, error)
+}
+
+type sessionRouter struct {
+	r        chi.Router
+	sessions SessionManager
+}
+
+func newSessionsRouter(router chi.Router, sessions SessionManager) *sessionRouter {
+	return &sessionRouter{
+		r:        router,
+		sessions: sessions,
+	}
+}
+
+func (router *sessionRouter) Routes() {
+	router.r.Route("/api/sessions", func(r chi.Router) {
+		r.Use(ContentTypeJson)
+		r.With(RequireScope(ScopeStatusRead)).Get("/", router.getSessions())
+		r.With(RequireScope(ScopeStreamsWrite)).Delete("/{sessionId}", router.deleteSession())
+	})
+}
+
+func (router *sessionRouter) getSessions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := principalFromContext(r.Context())
+		sessions := make([]rtmpserver.SessionInfo, 0)
+		for _, sess := range router.sessions.Sessions() {
+			if principal.CanAccessStream(sess.Stream) {
+				sessions = append(sessions, sess)
+			}
+		}
+		if err := json.NewEncoder(w).Encode(sessions); err != nil {
+			handleErrors(w, err)
+			return
+		}
+	}
+}
+
+func (router *sessionRouter) deleteSession() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "sessionId")
+		principal := principalFromContext(r.Context())
+		for _, sess := range router.sessions.Sessions() {
+			if sess.Id == id && !principal.CanAccessStream(sess.Stream) {
+				handleErrors(w, rtmpserver.SessionNotFound{})
+				return
+			}
+		}
+		if err := router.sessions.CloseSession(id); err != nil {
+			handleErrors(w, err)
+			return
+		}
+	}
+}
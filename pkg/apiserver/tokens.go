@@ -0,0 +1,170 @@
+package apiserver
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/utils"
+)
+
+const tokenPrefix = "srr_"
+
+type tokenRouter struct {
+	r        chi.Router
+	registry registry.Registry
+}
+
+func newTokensRouter(router chi.Router, registry registry.Registry) *tokenRouter {
+	return &tokenRouter{
+		r:        router,
+		registry: registry,
+	}
+}
+
+func (router *tokenRouter) Routes() {
+	router.r.Route("/api/tokens", func(r chi.Router) {
+		r.Use(ContentTypeJson)
+		r.Use(RequireScope(ScopeTokensWrite))
+		r.Get("/", router.getTokens())
+		r.Post("/", router.createToken())
+		r.Delete("/{tokenId}", router.deleteToken())
+	})
+}
+
+func (router *tokenRouter) getTokens() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokens, err := router.registry.GetTokens()
+		if err != nil {
+			handleErrors(w, err)
+			return
+		}
+		infos := make([]TokenInfo, len(tokens))
+		for i, token := range tokens {
+			infos[i] = newTokenInfo(token)
+		}
+		if err := json.NewEncoder(w).Encode(infos); err != nil {
+			handleErrors(w, err)
+			return
+		}
+	}
+}
+
+func (router *tokenRouter) createToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request CreateTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			JSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if request.Name == "" || len(request.Scopes) == 0 {
+			JSONError(w, "name and scopes are required", http.StatusBadRequest)
+			return
+		}
+
+		// A token can't be more powerful than the principal that creates it
+		principal := principalFromContext(r.Context())
+		for _, s := range request.Scopes {
+			scope, err := ParseScope(s)
+			if err != nil {
+				JSONError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if !principal.Can(scope) {
+				JSONError(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+		}
+		streams := request.Streams
+		if len(principal.Streams) > 0 {
+			if len(streams) == 0 {
+				streams = principal.Streams
+			}
+			for _, stream := range streams {
+				if !principal.CanAccessStream(stream) {
+					JSONError(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+			}
+		}
+
+		secret, err := generateToken()
+		if err != nil {
+			handleErrors(w, err)
+			return
+		}
+		token := &registry.APIToken{
+			Id:        utils.GenId(),
+			Name:      request.Name,
+			Hash:      hashToken(secret),
+			Scopes:    request.Scopes,
+			Streams:   streams,
+			CreatedAt: time.Now(),
+			ExpiresAt: request.ExpiresAt,
+		}
+		if token.ExpiresAt == nil && request.ExpiresIn > 0 {
+			expiresAt := token.CreatedAt.Add(time.Duration(request.ExpiresIn) * time.Second)
+			token.ExpiresAt = &expiresAt
+		}
+		if err := router.registry.AddToken(token); err != nil {
+			handleErrors(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(CreatedToken{TokenInfo: newTokenInfo(token), Token: secret}); err != nil {
+			handleErrors(w, err)
+			return
+		}
+	}
+}
+
+func (router *tokenRouter) deleteToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := router.registry.DeleteToken(chi.URLParam(r, "tokenId"))
+		if err != nil {
+			handleErrors(w, err)
+			return
+		}
+	}
+}
+
+func newTokenInfo(token *registry.APIToken) TokenInfo {
+	return TokenInfo{
+		Id:         token.Id,
+		Name:       token.Name,
+		Scopes:     token.Scopes,
+		Streams:    token.Streams,
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+	}
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return tokenPrefix + hex.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func principalFromToken(token *registry.APIToken) *Principal {
+	scopes := make([]Scope, 0, len(token.Scopes))
+	for _, s := range token.Scopes {
+		if scope, err := ParseScope(s); err == nil {
+			scopes = append(scopes, scope)
+		}
+	}
+	return &Principal{Name: "token:" + token.Name, Scopes: scopes, Streams: token.Streams}
+}
@@ -0,0 +1,124 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+)
+
+// createToken creates a token through the API as the user and returns it.
+func createToken(t *testing.T, handler http.Handler, user string, request CreateTokenRequest) CreatedToken {
+	t.Helper()
+	body, _ := json.Marshal(request)
+	w := serve(handler, user, http.MethodPost, "/api/tokens/", string(body))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("creating the token %s as %s: status %d %s", request.Name, user, w.Code, w.Body)
+	}
+	var created CreatedToken
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	return created
+}
+
+func TestTokenScopes(t *testing.T) {
+	handler, reg := newTestAPI(t)
+	for _, name := range []string{"s1", "s2"} {
+		if err := reg.Update(&registry.ExternalStream{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	created := createToken(t, handler, "admin", CreateTokenRequest{Name: "ci", Scopes: []string{"status:read"}, Streams: []string{"s1"}})
+	if !strings.HasPrefix(created.Token, tokenPrefix) {
+		t.Fatalf("token %q without the prefix", created.Token)
+	}
+
+	// Only the hash is stored and listed tokens don't show it either
+	tokens, _ := reg.GetTokens()
+	if len(tokens) != 1 || tokens[0].Hash != hashToken(created.Token) || strings.Contains(tokens[0].Hash, created.Token) {
+		t.Fatalf("stored tokens %+v", tokens)
+	}
+	if body := serve(handler, "admin", http.MethodGet, "/api/tokens/", "").Body.String(); strings.Contains(body, created.Token) {
+		t.Fatalf("the token list shows the token: %s", body)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{http.MethodGet, "/api/streams/s1", "", http.StatusOK},
+		{http.MethodGet, "/api/streams/s2", "", http.StatusForbidden},
+		{http.MethodPost, "/api/streams/s1/targets", `{"url":"rtmp://host/live/KEY"}`, http.StatusForbidden},
+		{http.MethodGet, "/api/tokens/", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if got := serve(handler, created.Token, tt.method, tt.path, tt.body).Code; got != tt.want {
+			t.Errorf("%s %s with the token: status %d, want %d", tt.method, tt.path, got, tt.want)
+		}
+	}
+	if got := serve(handler, tokenPrefix+"unknown", http.MethodGet, "/api/streams/s1", "").Code; got != http.StatusUnauthorized {
+		t.Errorf("unknown token: status %d, want %d", got, http.StatusUnauthorized)
+	}
+}
+
+func TestTokenCreationLimits(t *testing.T) {
+	handler, _ := newTestAPI(t)
+	tests := []struct {
+		user    string
+		request CreateTokenRequest
+		want    int
+	}{
+		{"operator", CreateTokenRequest{Name: "t", Scopes: []string{"status:read"}}, http.StatusForbidden},
+		{"restricted", CreateTokenRequest{Name: "t", Scopes: []string{"status:read"}, Streams: []string{"s2"}}, http.StatusForbidden},
+		{"admin", CreateTokenRequest{Name: "t", Scopes: []string{"unknown"}}, http.StatusBadRequest},
+		{"admin", CreateTokenRequest{Name: "t"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		body, _ := json.Marshal(tt.request)
+		if got := serve(handler, tt.user, http.MethodPost, "/api/tokens/", string(body)).Code; got != tt.want {
+			t.Errorf("creating %+v as %s: status %d, want %d", tt.request, tt.user, got, tt.want)
+		}
+	}
+
+	// A token of a restricted principal is restricted to its streams
+	created := createToken(t, handler, "restricted", CreateTokenRequest{Name: "t", Scopes: []string{"status:read"}})
+	if len(created.Streams) != 1 || created.Streams[0] != "s1" {
+		t.Fatalf("the token of a principal restricted to s1 has the streams %v", created.Streams)
+	}
+}
+
+func TestTokenExpiryAndRevocation(t *testing.T) {
+	handler, reg := newTestAPI(t)
+	if err := reg.Update(&registry.ExternalStream{Name: "s1"}); err != nil {
+		t.Fatal(err)
+	}
+	expiring := createToken(t, handler, "admin", CreateTokenRequest{Name: "expiring", Scopes: []string{"status:read"}, ExpiresIn: 3600})
+	if expiring.ExpiresAt == nil || time.Until(*expiring.ExpiresAt) < 59*time.Minute {
+		t.Fatalf("expires at %v, want in an hour", expiring.ExpiresAt)
+	}
+	if got := serve(handler, expiring.Token, http.MethodGet, "/api/streams/s1", "").Code; got != http.StatusOK {
+		t.Fatalf("valid token: status %d", got)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	expired := createToken(t, handler, "admin", CreateTokenRequest{Name: "expired", Scopes: []string{"status:read"}, ExpiresAt: &past})
+	if got := serve(handler, expired.Token, http.MethodGet, "/api/streams/s1", "").Code; got != http.StatusUnauthorized {
+		t.Errorf("expired token: status %d, want %d", got, http.StatusUnauthorized)
+	}
+
+	if got := serve(handler, "admin", http.MethodDelete, "/api/tokens/"+expiring.Id, "").Code; got != http.StatusOK {
+		t.Fatalf("revoking the token: status %d", got)
+	}
+	if got := serve(handler, expiring.Token, http.MethodGet, "/api/streams/s1", "").Code; got != http.StatusUnauthorized {
+		t.Errorf("revoked token: status %d, want %d", got, http.StatusUnauthorized)
+	}
+	if got := serve(handler, "admin", http.MethodDelete, "/api/tokens/"+expiring.Id, "").Code; got != http.StatusNotFound {
+		t.Errorf("revoking the token again: status %d, want %d", got, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,217 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Role string
+
+type Scope string
+
+const (
+	ScopeStatusRead   Scope = "status:read"
+	ScopeTargetsWrite Scope = "targets:write"
+	ScopeStreamsWrite Scope = "streams:write"
+	ScopeKeysRead     Scope = "keys:read"
+	ScopeTokensWrite  Scope = "tokens:write"
+	ScopeDebug        Scope = "debug"
+	ScopePublish      Scope = "publish"
+)
+
+var AllScopes = []Scope{ScopeStatusRead, ScopeTargetsWrite, ScopeStreamsWrite, ScopeKeysRead, ScopeTokensWrite, ScopeDebug, ScopePublish}
+
+func ParseScope(s string) (Scope, error) {
+	for _, scope := range AllScopes {
+		if string(scope) == s {
+			return scope, nil
+		}
+	}
+	return "", fmt.Errorf("unknown scope %q", s)
+}
+
+const (
+	RoleViewer   Role = "viewer"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleOperator:
+		return 2
+	case RoleAdmin:
+		return 3
+	}
+	return 0
+}
+
+// Scopes returns the permissions granted by the role.
+func (r Role) Scopes() []Scope {
+	switch r {
+	case RoleViewer:
+		return []Scope{ScopeStatusRead}
+	case RoleOperator:
+		return []Scope{ScopeStatusRead, ScopeTargetsWrite}
+	case RoleAdmin:
+		return AllScopes
+	}
+	return nil
+}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if role.rank() == 0 {
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+	return role, nil
+}
+
+// User is a single entry of the users file.
+type User struct {
+	Name         string   `json:"name"`
+	PasswordHash string   `json:"password_hash"`
+	Role         Role     `json:"role"`
+	Streams      []string `json:"streams,omitempty"` // empty means all streams
+}
+
+// Principal is the authenticated caller of an API request.
+type Principal struct {
+	Name    string
+	Role    Role // empty for API tokens
+	Scopes  []Scope
+	Streams []string
+}
+
+func (p *Principal) Can(scope Scope) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Principal) CanAccessStream(name string) bool {
+	if len(p.Streams) == 0 {
+		return true
+	}
+	for _, s := range p.Streams {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// UserStore keeps users loaded from a JSON file with bcrypt password hashes.
+// The file is re-read when its modification time changes.
+type UserStore struct {
+	path    string
+	users   map[string]*User
+	static  map[string]*User
+	modTime time.Time
+	mu      sync.Mutex
+}
+
+func NewUserStore(path string) (*UserStore, error) {
+	store := &UserStore{path: path, users: make(map[string]*User), static: make(map[string]*User)}
+	if path == "" {
+		return store, nil
+	}
+	if err := store.reload(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// AddUser registers a user which is not backed by the users file.
+func (s *UserStore) AddUser(user *User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.static[user.Name] = user
+}
+
+func (s *UserStore) IsEmpty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.users) == 0 && len(s.static) == 0 && s.path == ""
+}
+
+func (s *UserStore) Authenticate(name, password string) (*Principal, bool) {
+	s.reloadIfChanged()
+
+	s.mu.Lock()
+	user, ok := s.users[name]
+	if !ok {
+		user, ok = s.static[name]
+	}
+	s.mu.Unlock()
+	if !ok {
+		// Compare against a dummy hash anyway to keep timing uniform.
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, false
+	}
+	return &Principal{Name: user.Name, Role: user.Role, Scopes: user.Role.Scopes(), Streams: user.Streams}, true
+}
+
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy"), bcrypt.DefaultCost)
+
+func (s *UserStore) reloadIfChanged() {
+	if s.path == "" {
+		return
+	}
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	changed := !info.ModTime().Equal(s.modTime)
+	s.mu.Unlock()
+	if changed {
+		if err := s.reload(); err != nil {
+			log.Printf("Failed to reload users file %s: %v", s.path, err)
+		}
+	}
+}
+
+func (s *UserStore) reload() error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	var users []*User
+	if err := json.NewDecoder(file).Decode(&users); err != nil {
+		return err
+	}
+	loaded := make(map[string]*User, len(users))
+	for _, user := range users {
+		if _, err := ParseRole(string(user.Role)); err != nil {
+			return fmt.Errorf("user %s: %w", user.Name, err)
+		}
+		loaded[user.Name] = user
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = loaded
+	s.modTime = info.ModTime()
+	return nil
+}
@@ -0,0 +1,83 @@
+package apiserver
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver"
+)
+
+const maxOfferSize = 64 * 1024
+
+type whipRouter struct {
+	r        chi.Router
+	sessions SessionManager
+}
+
+func newWhipRouter(router chi.Router, sessions SessionManager) *whipRouter {
+	return &whipRouter{
+		r:        router,
+		sessions: sessions,
+	}
+}
+
+func (router *whipRouter) Routes() {
+	// The stream is named id to let RequireScope check the access of the principal to it
+	router.r.Route("/whip/{id}", func(r chi.Router) {
+		r.Use(RequireScope(ScopePublish))
+		r.Post("/", router.postOffer())
+		r.Delete("/{sessionId}", router.deleteResource())
+	})
+}
+
+// postOffer answers the SDP offer of a WHIP publisher, the session is the created WHIP resource.
+func (router *whipRouter) postOffer() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/sdp" {
+			w.Header().Set("Content-Type", "application/json")
+			JSONError(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+			return
+		}
+		offer, err := io.ReadAll(io.LimitReader(r.Body, maxOfferSize))
+		if err != nil {
+			handleErrors(w, err)
+			return
+		}
+
+		id := chi.URLParam(r, "id")
+		answer, sessionId, err := router.sessions.AcceptWHIP(string(offer), r.RemoteAddr, id, r.URL.Query())
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			if errors.As(err, &rtmpserver.WHIPOfferInvalid{}) {
+				JSONError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			handleIngestErrors(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/sdp")
+		w.Header().Set("Location", "/whip/"+id+"/"+sessionId)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, answer)
+	}
+}
+
+// deleteResource ends the WHIP session of the stream.
+func (router *whipRouter) deleteResource() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		sessionId := chi.URLParam(r, "sessionId")
+		for _, sess := range router.sessions.Sessions() {
+			if sess.Id == sessionId && sess.Protocol == rtmpserver.ProtocolWHIP && sess.Stream == chi.URLParam(r, "id") {
+				if err := router.sessions.CloseSession(sessionId); err != nil {
+					handleErrors(w, err)
+				}
+				return
+			}
+		}
+		handleErrors(w, rtmpserver.SessionNotFound{})
+	}
+}
@@ -0,0 +1,64 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/utils"
+)
+
+type Type string
+
+const (
+	PublishStarted  Type = "stream.publish_started"
+	PublishStopped  Type = "stream.publish_stopped"
+	PublishRejected Type = "stream.publish_rejected" // another publisher is live and the policy is reject
+	PublishTakeover Type = "stream.publish_takeover" // the session was disconnected by a new publisher
+	PublishPromoted Type = "stream.publish_promoted" // a standby publisher became active
+	PublishDemoted  Type = "stream.publish_demoted"  // a failover stream switched away from the publisher
+	TargetConnected Type = "target.connected"
+	TargetFailed    Type = "target.failed"
+	TargetGaveUp    Type = "target.gave_up"
+	ViewerJoined    Type = "viewer.joined"
+)
+
+type Event struct {
+	Id         string                 `json:"id"`
+	Type       Type                   `json:"type"`
+	Time       time.Time              `json:"time"`
+	Stream     string                 `json:"stream,omitempty"`
+	Target     string                 `json:"target,omitempty"` // redacted target URL
+	Session    string                 `json:"session,omitempty"`
+	RemoteAddr string                 `json:"remote_addr,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+}
+
+// Handler must not block, it is called synchronously by Publish.
+type Handler func(event Event)
+
+var (
+	handlers []Handler
+	mu       sync.RWMutex
+)
+
+func Subscribe(handler Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers = append(handlers, handler)
+}
+
+// Publish fills the event id and time and passes it to all subscribers.
+func Publish(event Event) {
+	if event.Id == "" {
+		event.Id = utils.GenId()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
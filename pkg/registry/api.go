@@ -1,7 +1,9 @@
 package registry
 
 import (
+	"fmt"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
 	"net/url"
 	"time"
 )
@@ -12,21 +14,56 @@ type Registry interface {
 	GetInternalStream(keyName string) (*Stream, error)
 	Update(key *ExternalStream) error
 	DeleteStream(keyName string) error
-	AddStreamTarget(keyName string, target *api.PushTargetUrl, targetName string) error
+	AddStreamTarget(keyName string, target *api.PushTargetUrl, targetName string, options api.PushTargetOptions) error
 	DeleteStreamTarget(keyName string, target string) error
 	GetStatus(keyName string) (*StreamStatus, error)
 	GetStreamsStatus() ([]*ExternalStreamInfo, error) // should it public?
 	UpdateStatus(keyName string, lastFrameTime time.Time, bitrate uint) error
+	GetTokens() ([]*APIToken, error)
+	AddToken(token *APIToken) error
+	DeleteToken(id string) error
+	UseToken(hash string, now time.Time) (*APIToken, error) // records the last-used time
 }
 
 type PushTarget struct {
 	Name string `json:"name"`
 	URL  string `json:"url"`
+	api.PushTargetOptions
 }
 
 type ExternalStream struct {
-	Name    string       `json:"name"`
-	Targets []PushTarget `json:"targets"`
+	Name       string       `json:"name"`
+	Targets    []PushTarget `json:"targets"`
+	RequireTLS bool         `json:"require_tls,omitempty"` // accept publishers only over RTMPS or encrypted SRT
+
+	PublisherPolicy   PublisherPolicy `json:"publisher_policy,omitempty"`
+	FailoverTimeoutMs int             `json:"failover_timeout_ms,omitempty"` // defaults to 3000
+	FallbackFile      string          `json:"fallback_file,omitempty"`       // FLV slate looped while the publisher is away, relative to SlateDir
+
+	Source        string                 `json:"source,omitempty"` // rtmp, rtmps or rtsp URL to pull the stream from, or udp://host:port to listen for MPEG-TS
+	SourceOptions *api.PushTargetOptions `json:"source_options,omitempty"`
+
+	HLS  bool `json:"hls,omitempty"`  // serve the live stream as HLS from the API server
+	CMAF bool `json:"cmaf,omitempty"` // serve the live stream as DASH and HLS fMP4 from the API server
+}
+
+func (stream *ExternalStream) validateSettings(config *Config) error {
+	if err := stream.PublisherPolicy.validate(); err != nil {
+		return err
+	}
+	// Publishers deliver frames in batches of up to a second
+	if stream.FailoverTimeoutMs != 0 && stream.FailoverTimeoutMs < minFailoverTimeoutMs {
+		return InvalidStreamConfig{Err: fmt.Errorf("failover_timeout_ms must be at least %d", minFailoverTimeoutMs)}
+	}
+	if stream.FallbackFile != "" {
+		if _, err := loadSlate(config.SlateDir, stream.FallbackFile); err != nil {
+			return InvalidStreamConfig{Err: fmt.Errorf("fallback_file: %w", err)}
+		}
+	}
+	if stream.Source != "" {
+		return validateSource(stream.Source, stream.SourceOptions, config)
+	}
+	return nil
 }
 
 type StreamStatus struct {
@@ -35,17 +72,27 @@ type StreamStatus struct {
 	LastFrameTime int64 `json:"last_frame_time"`
 }
 
+type TargetStatus struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	medias.PushConsumerStatus
+}
+
 type ExternalStreamInfo struct {
 	ExternalStream
-	Status *StreamStatus `json:"status"`
+	Status        *StreamStatus            `json:"status"`
+	TargetsStatus []TargetStatus           `json:"targets_status"`
+	SourceStatus  *medias.PullSourceStatus `json:"source_status,omitempty"`
 }
 
-func (stream *ExternalStream) toRegistryObject() (*Stream, error) {
-	s, err := newStream(stream)
+func (stream *ExternalStream) toRegistryObject(config *Config) (*Stream, error) {
+	s, err := newStream(stream, config)
 	return s, err
 }
 
 func (stream *Stream) toExternalStream() *ExternalStream {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
 	targets := make([]PushTarget, len(stream.Targets))
 	for i, target := range stream.Targets {
 		targetURL := ((*url.URL)(target)).String()
@@ -58,11 +105,23 @@ func (stream *Stream) toExternalStream() *ExternalStream {
 		}
 
 		targets[i] = PushTarget{
-			Name: targetName,
-			URL:  targetURL,
+			Name:              targetName,
+			URL:               targetURL,
+			PushTargetOptions: stream.TargetOptions[targetURL],
 		}
 	}
-	return &ExternalStream{Name: stream.Name, Targets: targets}
+	return &ExternalStream{
+		Name:              stream.Name,
+		Targets:           targets,
+		RequireTLS:        stream.RequireTLS,
+		PublisherPolicy:   stream.PublisherPolicy,
+		FailoverTimeoutMs: stream.FailoverTimeoutMs,
+		FallbackFile:      stream.FallbackFile,
+		Source:            stream.Source,
+		SourceOptions:     stream.SourceOptions,
+		HLS:               stream.HLS,
+		CMAF:              stream.CMAF,
+	}
 }
 
 func (status *streamStatus) toStreamStatus() *StreamStatus {
@@ -78,5 +137,10 @@ func (status *streamStatus) toStreamStatus() *StreamStatus {
 
 func (stream *Stream) toExternalStreamInfo() *ExternalStreamInfo {
 	es := stream.toExternalStream()
-	return &ExternalStreamInfo{ExternalStream: *es, Status: stream.status.toStreamStatus()}
+	return &ExternalStreamInfo{
+		ExternalStream: *es,
+		Status:         stream.status.toStreamStatus(),
+		TargetsStatus:  stream.targetsStatus(),
+		SourceStatus:   stream.sourceStatus(),
+	}
 }
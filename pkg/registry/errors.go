@@ -11,3 +11,16 @@ type StreamNotFound struct{}
 func (e StreamNotFound) Error() string {
 	return fmt.Sprintf("%s", StreamNotExist)
 }
+
+// InvalidStreamConfig is returned when stream or target settings fail validation.
+type InvalidStreamConfig struct {
+	Err error
+}
+
+func (e InvalidStreamConfig) Error() string {
+	return fmt.Sprintf("InvalidStreamConfig: %v", e.Err)
+}
+
+func (e InvalidStreamConfig) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,141 @@
+package registry
+
+import (
+	"log"
+	"slices"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+)
+
+const (
+	defaultFailoverTimeout = 3 * time.Second
+	minFailoverTimeoutMs   = 2000
+)
+
+// failoverState tracks the health of the primary and the backup publisher.
+type failoverState struct {
+	sources map[Publisher]*failoverSource
+}
+
+type failoverSource struct {
+	lastBatch    time.Time
+	healthySince time.Time
+	hasVideo     bool
+}
+
+func (s *Stream) failoverTimeout() time.Duration {
+	if s.FailoverTimeoutMs > 0 {
+		return time.Duration(s.FailoverTimeoutMs) * time.Millisecond
+	}
+	return defaultFailoverTimeout
+}
+
+// acquireFailoverPublisher accepts one primary and one backup publisher.
+// The first of them becomes active, the other one stands by.
+func (s *Stream) acquireFailoverPublisher(p Publisher, sessionTargets []PushTarget) (PublisherState, error) {
+	s.mu.Lock()
+	for _, other := range append([]Publisher{s.publisher}, standbyPublishers(s.standby)...) {
+		if other != nil && other.IsBackup() == p.IsBackup() {
+			s.mu.Unlock()
+			return "", PublisherConflict{Stream: s.Name}
+		}
+	}
+	now := time.Now()
+	if s.publisher != nil {
+		s.standby = append(s.standby, standbyPublisher{publisher: p})
+		s.failover.source(p, now)
+		s.mu.Unlock()
+		if len(sessionTargets) > 0 {
+			log.Printf("Ignoring session targets of standby publisher %s of %s", p.Id(), s.Name)
+		}
+		log.Printf("Publisher %s of %s is standing by (backup: %v)", p.Id(), s.Name, p.IsBackup())
+		return PublisherStandingBy, nil
+	}
+	s.failover = failoverState{}
+	s.publisher = p
+	s.failover.source(p, now)
+	s.mu.Unlock()
+
+	if err := s.SetSessionTargets(sessionTargets); err != nil {
+		return "", err
+	}
+	return PublisherActive, nil
+}
+
+// activate makes the publisher active and demotes the previous one, it's called with s.mu held.
+func (s *Stream) activate(p Publisher) {
+	s.standby = slices.DeleteFunc(s.standby, func(sp standbyPublisher) bool {
+		return sp.publisher == p
+	})
+	if s.publisher != nil {
+		s.standby = append(s.standby, standbyPublisher{publisher: s.publisher})
+	}
+	s.publisher = p
+}
+
+// onFailoverBatch switches to the standby publisher when the active one stalls, or back to
+// the primary when it has been healthy for the failover timeout. The switch happens at a keyframe
+// of the new publisher, see timeline. It's called with s.mu held and returns the frames to forward
+// and the demoted publisher.
+func (s *Stream) onFailoverBatch(p Publisher, batch *medias.MediaFrameBatch, now time.Time) (*medias.MediaFrameBatch, Publisher) {
+	f := &s.failover
+	timeout := s.failoverTimeout()
+	src := f.source(p, now)
+	if now.Sub(src.lastBatch) > timeout {
+		src.healthySince = now
+	}
+	src.lastBatch = now
+	for _, frame := range batch.Frames {
+		if frame.IsVideo() {
+			src.hasVideo = true
+			break
+		}
+	}
+
+	var demoted Publisher
+	if p != s.publisher {
+		active := s.publisher
+		stalled := active == nil || now.Sub(f.source(active, now).lastBatch) > timeout
+		recovered := !p.IsBackup() && now.Sub(src.healthySince) >= timeout && keyframeIndex(batch.Frames, src.hasVideo) >= 0
+		if !stalled && !recovered {
+			return nil, nil
+		}
+		if stalled {
+			log.Printf("Publisher %s of %s stalled, switching to %s", publisherId(active), s.Name, p.Id())
+		} else {
+			log.Printf("Primary publisher %s of %s recovered, switching back", p.Id(), s.Name)
+		}
+		demoted = active
+		s.activate(p)
+	}
+
+	return s.retimeBatch(p, batch), demoted
+}
+
+func (f *failoverState) source(p Publisher, now time.Time) *failoverSource {
+	if f.sources == nil {
+		f.sources = make(map[Publisher]*failoverSource)
+	}
+	src, ok := f.sources[p]
+	if !ok {
+		src = &failoverSource{lastBatch: now, healthySince: now}
+		f.sources[p] = src
+	}
+	return src
+}
+
+func standbyPublishers(standby []standbyPublisher) []Publisher {
+	publishers := make([]Publisher, len(standby))
+	for i, sp := range standby {
+		publishers[i] = sp.publisher
+	}
+	return publishers
+}
+
+func publisherId(p Publisher) string {
+	if p == nil {
+		return "none"
+	}
+	return p.Id()
+}
@@ -0,0 +1,40 @@
+package registry
+
+import (
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+)
+
+// maxGOPFrames bounds the cache of a publisher which doesn't send keyframes.
+const maxGOPFrames = 3000
+
+// gopCache keeps the frames since the last video keyframe, so that new players start with a picture.
+type gopCache struct {
+	frames []medias.MediaFrame
+	keyed  bool
+}
+
+func (c *gopCache) add(batch *medias.MediaFrameBatch) {
+	for _, frame := range batch.Frames {
+		if frame.IsIFrame {
+			c.frames = c.frames[:0]
+			c.keyed = true
+		}
+		if c.keyed {
+			c.frames = append(c.frames, frame)
+		}
+	}
+	if len(c.frames) > maxGOPFrames {
+		*c = gopCache{}
+	}
+}
+
+// batch returns a copy of the cached frames, it is nil until a keyframe is received.
+func (c *gopCache) batch() *medias.MediaFrameBatch {
+	if len(c.frames) == 0 {
+		return nil
+	}
+	batch := &medias.MediaFrameBatch{Frames: c.frames, StartTime: time.Now()}
+	return batch.Clone()
+}
@@ -3,23 +3,44 @@ package registry
 import (
 	"encoding/json"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
 	"log"
-	"net/url"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 )
 
-const (
-	REGESTRY_STORAGE_FILE = "simple-rtmp-restreamer.data.json"
-)
+// REGESTRY_STORAGE_FILE is where the registry is persisted, relative to the working directory by default.
+var REGESTRY_STORAGE_FILE = "simple-rtmp-restreamer.data.json"
+
+// Config holds the server settings the streams pass to their consumers.
+type Config struct {
+	// Directory the fallback files are read from, the files are named relative to it
+	SlateDir string
+	Push     medias.PushConfig
+	HLS      medias.HLSConfig
+}
 
 type registryImpl struct {
-	keys map[string]*Stream
-	mux  sync.Mutex
+	config      Config
+	keys        map[string]*Stream
+	tokens      map[string]*APIToken
+	mux         sync.Mutex
+	saveMu      sync.Mutex // serializes writes of the storage file
+	storageFile string
+}
+
+// persistentData is the layout of the registry storage file.
+// Older files contain only the streams array.
+type persistentData struct {
+	Streams []*ExternalStream `json:"streams"`
+	Tokens  []*APIToken       `json:"tokens"`
 }
 
 func (r *registryImpl) GetStreams() ([]*ExternalStream, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
 	streams := make([]*ExternalStream, 0, len(r.keys))
 	for _, key := range r.keys {
 		streams = append(streams, key.toExternalStream())
@@ -28,9 +49,11 @@ func (r *registryImpl) GetStreams() ([]*ExternalStream, error) {
 }
 
 func (r *registryImpl) GetStream(keyName string) (*ExternalStream, error) {
-	stream, err := r.GetInternalStream(keyName)
-	if err != nil {
-		return nil, err
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	stream, ok := r.keys[keyName]
+	if !ok {
+		return nil, StreamNotFound{}
 	}
 	return stream.toExternalStream(), nil
 }
@@ -45,20 +68,38 @@ func (r *registryImpl) GetInternalStream(keyName string) (*Stream, error) {
 }
 
 func (r *registryImpl) Update(key *ExternalStream) error {
-	err := r.updateFromExternal(key)
+	stream, err := r.updateFromExternal(key)
+	// Validation loads the fallback file even if the update is rejected or replaces the old one
+	r.pruneSlates()
 	if err != nil {
 		return err
 	}
+	// Restarting the source waits for the old one to stop, so it's done outside of the registry lock
+	stream.updateSource(key.Source, key.SourceOptions, r)
 	r.savePersistent()
 	return nil
 }
 
 func (r *registryImpl) DeleteStream(keyName string) error {
 	r.deleteStream(keyName)
+	r.pruneSlates()
 	r.savePersistent()
 	return nil
 }
 
+// pruneSlates evicts the cached fallback files no stream refers to.
+func (r *registryImpl) pruneSlates() {
+	used := make(map[string]bool)
+	for _, stream := range r.getStreamsList() {
+		stream.mu.Lock()
+		if stream.FallbackFile != "" {
+			used[stream.FallbackFile] = true
+		}
+		stream.mu.Unlock()
+	}
+	evictSlates(used)
+}
+
 func (r *registryImpl) GetStatus(keyName string) (*StreamStatus, error) {
 	r.mux.Lock()
 	defer r.mux.Unlock()
@@ -72,8 +113,11 @@ func (r *registryImpl) GetStatus(keyName string) (*StreamStatus, error) {
 	return nil, StreamNotFound{}
 }
 
-func (r *registryImpl) AddStreamTarget(keyName string, target *api.PushTargetUrl, targetName string) error {
-	err := r.addStreamTarget(keyName, target, targetName)
+func (r *registryImpl) AddStreamTarget(keyName string, target *api.PushTargetUrl, targetName string, options api.PushTargetOptions) error {
+	if err := options.Validate(r.config.Push.TLSFilesDir); err != nil {
+		return InvalidStreamConfig{Err: err}
+	}
+	err := r.addStreamTarget(keyName, target, targetName, options)
 	r.savePersistent()
 	return err
 }
@@ -99,6 +143,8 @@ func (r *registryImpl) UpdateStatus(keyName string, lastFrameTime time.Time, bit
 }
 
 func (r *registryImpl) GetStreamsStatus() ([]*ExternalStreamInfo, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
 	streams := make([]*ExternalStreamInfo, 0, len(r.keys))
 	for _, key := range r.keys {
 		streams = append(streams, key.toExternalStreamInfo())
@@ -117,45 +163,75 @@ func (r *registryImpl) getStreamsList() []*Stream {
 }
 
 func (r *registryImpl) loadPersistent() {
-	file, err := os.Open(REGESTRY_STORAGE_FILE)
+	file, err := os.Open(r.storageFile)
 	if err != nil {
 		log.Printf("Failed to load restreamser registry from file: %v", err)
 		return
 	}
 	defer file.Close()
 
-	var streams []*ExternalStream
-	err = json.NewDecoder(file).Decode(&streams)
+	var raw json.RawMessage
+	err = json.NewDecoder(file).Decode(&raw)
+	if err != nil {
+		log.Printf("Failed to load restreamser registry from file: %v", err)
+		return
+	}
+	var data persistentData
+	if len(raw) > 0 && raw[0] == '[' {
+		err = json.Unmarshal(raw, &data.Streams)
+	} else {
+		err = json.Unmarshal(raw, &data)
+	}
 	if err != nil {
 		log.Printf("Failed to load restreamser registry from file: %v", err)
 		return
 	}
-	for _, stream := range streams {
+	for _, token := range data.Tokens {
+		r.tokens[token.Id] = token
+	}
+	for _, stream := range data.Streams {
 		s := stream
-		regObj, err := newStream(s)
+		regObj, err := newStream(s, &r.config)
 		if err != nil {
-			log.Printf("Failed to create restreamser registry from file: %v", err)
-			return
+			log.Printf("Failed to create stream %s of restreamser registry from file: %v", stream.Name, err)
+			continue
 		}
 		r.keys[stream.Name] = regObj
+		regObj.updateSource(stream.Source, stream.SourceOptions, r)
 	}
 }
 
+// savePersistent writes the registry to a temporary file and renames it over the storage file,
+// so a crash or a concurrent save never leaves a truncated file behind.
 func (r *registryImpl) savePersistent() {
-	file, err := os.Create(REGESTRY_STORAGE_FILE)
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+
+	streams, err := r.GetStreams()
+	if err != nil {
+		log.Printf("Failed to save restreamser registry file: %v", err)
+		return
+	}
+	tokens, err := r.GetTokens()
 	if err != nil {
 		log.Printf("Failed to save restreamser registry file: %v", err)
 		return
 	}
-	defer file.Close()
 
-	streams, err := r.GetStreams()
+	file, err := os.CreateTemp(filepath.Dir(r.storageFile), filepath.Base(r.storageFile)+".*.tmp")
 	if err != nil {
 		log.Printf("Failed to save restreamser registry file: %v", err)
 		return
 	}
-	err = json.NewEncoder(file).Encode(streams)
+	err = json.NewEncoder(file).Encode(persistentData{Streams: streams, Tokens: tokens})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), r.storageFile)
+	}
 	if err != nil {
+		_ = os.Remove(file.Name())
 		log.Printf("Failed to save restreamser registry file: %v", err)
 		return
 	}
@@ -167,32 +243,29 @@ func (r *registryImpl) update(key *Stream) {
 	r.keys[key.Name] = key
 }
 
-func (r *registryImpl) updateFromExternal(key *ExternalStream) error {
+// updateFromExternal applies everything but the source, which the caller updates without the lock.
+func (r *registryImpl) updateFromExternal(key *ExternalStream) (*Stream, error) {
 	r.mux.Lock()
 	defer r.mux.Unlock()
 	if stream, ok := r.keys[key.Name]; ok {
-		targets := make([]*api.PushTargetUrl, len(key.Targets))
-		targetNames := make(map[string]string)
-
-		for i, t := range key.Targets {
-			parse, err := url.Parse(t.URL)
-			if err != nil {
-				return err
-			}
-			targets[i] = (*api.PushTargetUrl)(parse)
-			targetNames[t.URL] = t.Name
-		}
-
-		stream.Targets = targets
-		stream.TargetNames = targetNames
-	} else {
-		stream, err := newStream(key)
+		targets, targetNames, targetOptions, err := parseTargets(key.Targets, &r.config)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if err := key.validateSettings(&r.config); err != nil {
+			return nil, err
 		}
-		r.keys[key.Name] = stream
+
+		stream.setTargets(targets, targetNames, targetOptions)
+		stream.updateSettings(key)
+		return stream, nil
 	}
-	return nil
+	stream, err := newStream(key, &r.config)
+	if err != nil {
+		return nil, err
+	}
+	r.keys[key.Name] = stream
+	return stream, nil
 }
 
 func (r *registryImpl) deleteStream(keyName string) {
@@ -206,31 +279,12 @@ func (r *registryImpl) deleteStream(keyName string) {
 	delete(r.keys, keyName)
 }
 
-func (r *registryImpl) addStreamTarget(keyName string, target *api.PushTargetUrl, targetName string) error {
+func (r *registryImpl) addStreamTarget(keyName string, target *api.PushTargetUrl, targetName string, options api.PushTargetOptions) error {
 	r.mux.Lock()
 	defer r.mux.Unlock()
 
 	if key, ok := r.keys[keyName]; ok {
-		targetURL := target.String()
-
-		// Check if target already exists
-		for _, t := range key.Targets {
-			if t.String() == targetURL {
-				return nil
-			}
-		}
-
-		// Add target
-		key.Targets = append(key.Targets, target)
-
-		// Initialize TargetNames map if nil
-		if key.TargetNames == nil {
-			key.TargetNames = make(map[string]string)
-		}
-
-		// Store the target name
-		key.TargetNames[targetURL] = targetName
-
+		key.addTarget(target, targetName, options)
 		return nil
 	}
 	return StreamNotFound{}
@@ -241,27 +295,18 @@ func (r *registryImpl) deleteStreamTarget(keyName string, target string) error {
 	defer r.mux.Unlock()
 
 	if key, ok := r.keys[keyName]; ok {
-		newTargets := make([]*api.PushTargetUrl, 0, len(key.Targets))
-		for _, t := range key.Targets {
-			if t.String() != target {
-				newTargets = append(newTargets, t)
-			}
-		}
-		key.Targets = newTargets
-
-		// Also remove from TargetNames map
-		if key.TargetNames != nil {
-			delete(key.TargetNames, target)
-		}
-
+		key.removeTarget(target)
 		return nil
 	}
 	return StreamNotFound{}
 }
 
-func NewRegistry() Registry {
+func NewRegistry(config Config) Registry {
 	r := registryImpl{
-		keys: make(map[string]*Stream),
+		config:      config,
+		keys:        make(map[string]*Stream),
+		tokens:      make(map[string]*APIToken),
+		storageFile: REGESTRY_STORAGE_FILE,
 	}
 	r.loadPersistent()
 	return &r
@@ -0,0 +1,190 @@
+package registry
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+)
+
+// PublisherPolicy decides what happens when a stream that is already live gets another publisher.
+type PublisherPolicy string
+
+const (
+	PublisherReject   PublisherPolicy = "reject"   // refuse the newcomer (default)
+	PublisherTakeover PublisherPolicy = "takeover" // disconnect the active publisher
+	PublisherStandby  PublisherPolicy = "standby"  // keep the newcomer as a backup until the active one leaves
+	PublisherFailover PublisherPolicy = "failover" // forward a primary and switch to a backup while it stalls
+)
+
+func (p PublisherPolicy) validate() error {
+	switch p {
+	case "", PublisherReject, PublisherTakeover, PublisherStandby, PublisherFailover:
+		return nil
+	}
+	return InvalidStreamConfig{Err: fmt.Errorf("unknown publisher policy %q", p)}
+}
+
+type PublisherState string
+
+const (
+	PublisherActive     PublisherState = "active"
+	PublisherStandingBy PublisherState = "standby"
+)
+
+// Publisher is an ingest session feeding the stream.
+type Publisher interface {
+	Id() string
+	// IsBackup reports whether the publisher is the backup source of a failover stream
+	IsBackup() bool
+	// SetState is called when the publisher is promoted or demoted
+	SetState(state PublisherState)
+	// Disconnect is called when another publisher takes the stream over
+	Disconnect()
+}
+
+type PublisherConflict struct {
+	Stream string
+}
+
+func (e PublisherConflict) Error() string {
+	return fmt.Sprintf("stream %s is already being published", e.Stream)
+}
+
+type standbyPublisher struct {
+	publisher Publisher
+	targets   []PushTarget
+}
+
+// AcquirePublisher applies the publisher policy of the stream to a new publisher.
+// The session targets apply while the publisher is active.
+func (s *Stream) AcquirePublisher(p Publisher, sessionTargets []PushTarget) (PublisherState, error) {
+	if _, _, _, err := parseTargets(sessionTargets, s.config); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	previous := s.publisher
+	if s.PublisherPolicy == PublisherFailover {
+		s.mu.Unlock()
+		return s.acquireFailoverPublisher(p, sessionTargets)
+	}
+	if previous != nil {
+		switch s.PublisherPolicy {
+		case PublisherStandby:
+			s.standby = append(s.standby, standbyPublisher{publisher: p, targets: sessionTargets})
+			s.mu.Unlock()
+			log.Printf("Publisher %s of %s is standing by", p.Id(), s.Name)
+			return PublisherStandingBy, nil
+		case PublisherTakeover:
+			log.Printf("Publisher %s takes %s over from %s", p.Id(), s.Name, previous.Id())
+		default:
+			s.mu.Unlock()
+			return "", PublisherConflict{Stream: s.Name}
+		}
+	}
+	s.publisher = p
+	s.mu.Unlock()
+
+	if previous != nil {
+		// The new encoder has its own timestamps, so the targets reconnect
+		s.OnProducerClose()
+		previous.Disconnect()
+	}
+	if err := s.SetSessionTargets(sessionTargets); err != nil {
+		return "", err
+	}
+	return PublisherActive, nil
+}
+
+// ReleasePublisher removes the publisher and lets a standby publisher replace it if it was active.
+// It reports whether the publisher was active.
+func (s *Stream) ReleasePublisher(p Publisher) bool {
+	s.mu.Lock()
+	if s.publisher != p {
+		s.standby = slices.DeleteFunc(s.standby, func(sp standbyPublisher) bool {
+			return sp.publisher == p
+		})
+		delete(s.failover.sources, p)
+		s.timeline.forget(p)
+		s.mu.Unlock()
+		return false
+	}
+	s.timeline.forget(p)
+	if s.PublisherPolicy == PublisherFailover && len(s.standby) > 0 {
+		// Switch without restarting the targets
+		next := s.standby[0].publisher
+		s.publisher = nil
+		s.activate(next)
+		delete(s.failover.sources, p)
+		s.mu.Unlock()
+		log.Printf("Publisher %s of %s left, switching to %s", p.Id(), s.Name, next.Id())
+		next.SetState(PublisherActive)
+		return true
+	}
+	s.publisher = nil
+	// The slate keeps the targets connected until a publisher comes back
+	slate := s.FallbackFile != "" && s.timeline.started && s.startSlate()
+	s.mu.Unlock()
+
+	if !slate {
+		s.OnProducerClose()
+	}
+
+	s.mu.Lock()
+	if len(s.standby) == 0 || s.publisher != nil {
+		s.mu.Unlock()
+		return true
+	}
+	next := s.standby[0]
+	s.standby = s.standby[1:]
+	s.publisher = next.publisher
+	s.mu.Unlock()
+
+	if err := s.SetSessionTargets(next.targets); err != nil {
+		log.Printf("Failed to apply session targets of %s: %v", next.publisher.Id(), err)
+	}
+	log.Printf("Standby publisher %s of %s promoted", next.publisher.Id(), s.Name)
+	next.publisher.SetState(PublisherActive)
+	return true
+}
+
+// OnPublisherBatch forwards the frames of the active publisher and drops others.
+// It reports whether any frames were forwarded.
+func (s *Stream) OnPublisherBatch(p Publisher, batch *medias.MediaFrameBatch) bool {
+	s.mu.Lock()
+	var demoted Publisher
+	if s.PublisherPolicy == PublisherFailover {
+		batch, demoted = s.onFailoverBatch(p, batch, time.Now())
+	} else if s.publisher == p {
+		batch = s.retimeBatch(p, batch)
+	} else {
+		batch = nil
+	}
+	if batch != nil {
+		s.lastLiveBatch = time.Now()
+		if s.slate != nil {
+			log.Printf("Stream %s switches from the fallback slate back to live", s.Name)
+			s.stopSlate()
+		}
+	}
+	s.mu.Unlock()
+
+	if demoted != nil {
+		demoted.SetState(PublisherStandingBy)
+		p.SetState(PublisherActive)
+	}
+	if batch == nil {
+		return false
+	}
+	s.OnFrameBatch(batch)
+	return true
+}
+
+func (s *Stream) HasPublisher() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.publisher != nil
+}
@@ -0,0 +1,241 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/utils"
+	"github.com/yapingcat/gomedia/go-codec"
+	"github.com/yapingcat/gomedia/go-flv"
+)
+
+// slate is a pre-recorded FLV file looped into the consumers while no publisher delivers frames.
+type slate struct {
+	path     string
+	frames   []medias.MediaFrame
+	firstDts int64
+	duration int64 // of one loop, ms
+}
+
+type cachedSlate struct {
+	modTime time.Time
+	size    int64
+	slate   *slate
+}
+
+// maxSlateSize limits the fallback file, since all of its frames are kept in memory.
+const maxSlateSize = 64 << 20
+
+var errSlateFile = errors.New("not a readable file in the slate directory")
+
+var (
+	slates   = make(map[string]cachedSlate)
+	slatesMu sync.Mutex
+)
+
+// loadSlate reads the FLV file from the directory once and reloads it when it changes.
+// Fallback files are rejected while the directory is empty, so API users can't read arbitrary local files.
+func loadSlate(dir, name string) (*slate, error) {
+	file, err := utils.OpenInDir(dir, name)
+	if err != nil {
+		return nil, errSlateFile
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, errSlateFile
+	}
+	if info.Size() > maxSlateSize {
+		return nil, fmt.Errorf("%s is larger than %d MiB", name, maxSlateSize>>20)
+	}
+	slatesMu.Lock()
+	defer slatesMu.Unlock()
+	if cached, ok := slates[name]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.slate, nil
+	}
+
+	sl := &slate{path: name}
+	reader := flv.CreateFlvReader()
+	reader.OnFrame = func(cid codec.CodecID, frame []byte, pts uint32, dts uint32) {
+		data := make([]byte, len(frame))
+		copy(data, frame)
+		f := medias.MediaFrame{Cid: cid, Frame: data, Pts: pts, Dts: dts}
+		f.IsIFrame = cid == codec.CODECID_VIDEO_H264 && codec.IsH264IDRFrame(data) ||
+			cid == codec.CODECID_VIDEO_H265 && codec.IsH265IDRFrame(data)
+		sl.frames = append(sl.frames, f)
+	}
+	// The file may grow after the size check
+	input := io.LimitReader(file, maxSlateSize)
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := input.Read(buf)
+		if n > 0 {
+			if err := reader.Input(buf[:n]); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if len(sl.frames) == 0 {
+		return nil, fmt.Errorf("%s: no frames", name)
+	}
+
+	sl.firstDts = int64(sl.frames[0].Dts)
+	var step, lastVideoDts int64 = defaultFrameStep, -1
+	for _, frame := range sl.frames {
+		dts := int64(frame.Dts)
+		if frame.IsVideo() {
+			if lastVideoDts >= 0 && dts > lastVideoDts {
+				step = dts - lastVideoDts
+			}
+			lastVideoDts = dts
+		}
+		sl.firstDts = min(sl.firstDts, dts)
+		sl.duration = max(sl.duration, dts)
+	}
+	sl.duration += step - sl.firstDts
+	slates[name] = cachedSlate{modTime: info.ModTime(), size: info.Size(), slate: sl}
+	return sl, nil
+}
+
+// evictSlates drops the cached files which aren't used by any stream.
+func evictSlates(used map[string]bool) {
+	slatesMu.Lock()
+	defer slatesMu.Unlock()
+	for name := range slates {
+		if !used[name] {
+			delete(slates, name)
+		}
+	}
+}
+
+// slateRun is a running slate loop of the stream.
+type slateRun struct {
+	stop chan struct{}
+}
+
+// startSlate loops the fallback file, it's called with s.mu held.
+func (s *Stream) startSlate() bool {
+	if s.slate != nil {
+		return true
+	}
+	sl, err := loadSlate(s.config.SlateDir, s.FallbackFile)
+	if err != nil {
+		log.Printf("Failed to load fallback file of %s: %v", s.Name, err)
+		return false
+	}
+	log.Printf("Stream %s switches to the fallback slate %s", s.Name, sl.path)
+	run := &slateRun{stop: make(chan struct{})}
+	s.slate = run
+	go s.runSlate(sl, run)
+	return true
+}
+
+// stopSlate is called with s.mu held.
+func (s *Stream) stopSlate() {
+	if s.slate != nil {
+		close(s.slate.stop)
+		s.timeline.forget(s.slate)
+		s.slate = nil
+	}
+}
+
+func (s *Stream) runSlate(sl *slate, run *slateRun) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	start := time.Now()
+	var base int64 // shift of the current loop
+	i := 0
+	var batch *medias.MediaFrameBatch
+	for {
+		select {
+		case <-ticker.C:
+		case <-run.stop:
+			return
+		case <-s.quit:
+			return
+		}
+
+		// Pace the frames in real time and batch them like a publisher
+		elapsed := time.Since(start).Milliseconds()
+		flush := false
+		for {
+			frame := sl.frames[i]
+			dts := int64(frame.Dts) - sl.firstDts + base
+			if dts > elapsed {
+				break
+			}
+			frame.Pts = uint32(int64(frame.Pts) - int64(frame.Dts) + dts)
+			frame.Dts = uint32(dts)
+			frame.Time = time.Now()
+			if batch == nil {
+				batch = &medias.MediaFrameBatch{StartTime: time.Now()}
+			}
+			batch.Frames = append(batch.Frames, frame)
+			if frame.IsIFrame || time.Since(batch.StartTime) >= time.Second {
+				flush = true
+			}
+			i++
+			if i == len(sl.frames) {
+				i = 0
+				base += sl.duration
+			}
+			if flush {
+				break
+			}
+		}
+		if !flush {
+			continue
+		}
+
+		s.mu.Lock()
+		if s.slate != run {
+			s.mu.Unlock()
+			return
+		}
+		// Every run is a new source, its timestamps start from zero
+		retimed := s.retimeBatch(run, batch)
+		s.mu.Unlock()
+		if retimed != nil {
+			s.OnFrameBatch(retimed)
+		}
+		batch = nil
+	}
+}
+
+// watchFallback starts the slate when the publisher stops delivering frames.
+func (s *Stream) watchFallback() {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.quit:
+			return
+		}
+		s.mu.Lock()
+		if s.FallbackFile == "" {
+			if s.slate != nil {
+				s.stopSlate()
+				if s.publisher == nil {
+					s.mu.Unlock()
+					s.OnProducerClose()
+					continue
+				}
+			}
+		} else if s.slate == nil && s.timeline.started && time.Since(s.lastLiveBatch) > s.failoverTimeout() {
+			s.startSlate()
+		}
+		s.mu.Unlock()
+	}
+}
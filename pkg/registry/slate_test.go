@@ -0,0 +1,90 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yapingcat/gomedia/go-flv"
+)
+
+// writeTestSlate writes an FLV file with one second of H.264 at 25 fps.
+func writeTestSlate(t *testing.T, path string) {
+	t.Helper()
+	sps, _ := base64.StdEncoding.DecodeString("Z0IAKeKQFAe2AtwEBAaQeJEV")
+	startCode := []byte{0, 0, 0, 1}
+	var idr []byte
+	for _, nalu := range [][]byte{sps, {0x68, 0xce, 0x3c, 0x80}, {0x65, 0x88, 0x84, 0x00, 0x33}} {
+		idr = append(append(idr, startCode...), nalu...)
+	}
+	nonIdr := []byte{0, 0, 0, 1, 0x41, 0x9a, 0x00, 0x10}
+
+	var buf bytes.Buffer
+	writer := flv.CreateFlvWriter(&buf)
+	if err := writer.WriteFlvHeader(); err != nil {
+		t.Fatal(err)
+	}
+	for i := uint32(0); i < 25; i++ {
+		frame := nonIdr
+		if i%5 == 0 {
+			frame = idr
+		}
+		// The muxer replaces the start codes in place
+		if err := writer.WriteH264(bytes.Clone(frame), i*40, i*40); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadSlate(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "slates")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeTestSlate(t, filepath.Join(dir, "away.flv"))
+	writeTestSlate(t, filepath.Join(root, "outside.flv"))
+	if err := os.Symlink(filepath.Join(root, "outside.flv"), filepath.Join(dir, "link.flv")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "huge.flv"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(filepath.Join(dir, "huge.flv"), maxSlateSize+1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadSlate("", "away.flv"); err == nil {
+		t.Fatal("a slate was loaded without a slate directory")
+	}
+
+	sl, err := loadSlate(dir, "away.flv")
+	if err != nil {
+		t.Fatalf("loadSlate: %v", err)
+	}
+	if len(sl.frames) != 25 || sl.duration != 1000 || !sl.frames[0].IsIFrame || sl.frames[1].IsIFrame {
+		t.Fatalf("got %d frames of %d ms, first keyframe %v", len(sl.frames), sl.duration, sl.frames[0].IsIFrame)
+	}
+	if cached, _ := loadSlate(dir, "away.flv"); cached != sl {
+		t.Fatal("the unchanged slate wasn't taken from the cache")
+	}
+
+	for _, name := range []string{"../outside.flv", filepath.Join(root, "outside.flv"), "link.flv", "missing.flv", "huge.flv", "."} {
+		if _, err := loadSlate(dir, name); err == nil {
+			t.Fatalf("%s was loaded", name)
+		}
+	}
+
+	evictSlates(map[string]bool{"other.flv": true})
+	slatesMu.Lock()
+	_, cached := slates["away.flv"]
+	slatesMu.Unlock()
+	if cached {
+		t.Fatal("an unused slate stayed in the cache")
+	}
+}
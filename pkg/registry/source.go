@@ -0,0 +1,158 @@
+package registry
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/events"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/utils"
+)
+
+// pullSource feeds the stream from its source URL, the stream treats it like any other publisher.
+type pullSource struct {
+	id       string
+	stream   *Stream
+	registry Registry
+	client   *medias.PullSource
+}
+
+func validateSource(source string, options *api.PushTargetOptions, config *Config) error {
+	u, err := url.Parse(source)
+	if err != nil || (u.Scheme != "rtmp" && u.Scheme != "rtmps" && u.Scheme != "rtsp" && u.Scheme != "udp") || u.Host == "" {
+		return InvalidStreamConfig{Err: errors.New("source must be an rtmp, rtmps, rtsp or udp URL")}
+	}
+	if u.Scheme == "udp" && u.Port() == "" {
+		return InvalidStreamConfig{Err: errors.New("udp source must have a port")}
+	}
+	if options != nil {
+		if err := options.Validate(config.Push.TLSFilesDir); err != nil {
+			return InvalidStreamConfig{Err: err}
+		}
+	}
+	return nil
+}
+
+// updateSource restarts the pull source if its URL or options changed.
+// It waits for the old source to stop, so it must not be called with the registry lock held.
+func (s *Stream) updateSource(source string, options *api.PushTargetOptions, registry Registry) {
+	s.mu.Lock()
+	if s.Source == source && reflect.DeepEqual(s.SourceOptions, options) && (s.source != nil) == (source != "") {
+		s.mu.Unlock()
+		return
+	}
+	old := s.source
+	s.Source = source
+	s.SourceOptions = options
+	s.source = nil
+	s.mu.Unlock()
+
+	if old != nil {
+		_ = old.client.Close()
+	}
+	if source == "" {
+		return
+	}
+	u, err := url.Parse(source)
+	if err != nil {
+		log.Printf("Invalid source of %s: %v", s.Name, err)
+		return
+	}
+	var sourceOptions api.PushTargetOptions
+	if options != nil {
+		sourceOptions = *options
+	}
+	p := &pullSource{id: "source-" + utils.GenId(), stream: s, registry: registry}
+	p.client = medias.NewPullSource((*api.PushTargetUrl)(u), sourceOptions, s.config.Push, s.Name, p)
+	s.mu.Lock()
+	s.source = p
+	s.mu.Unlock()
+	p.client.Start()
+}
+
+func (s *Stream) stopSource() {
+	s.mu.Lock()
+	old := s.source
+	s.source = nil
+	s.mu.Unlock()
+	if old != nil {
+		_ = old.client.Close()
+	}
+}
+
+func (s *Stream) sourceStatus() *medias.PullSourceStatus {
+	s.mu.Lock()
+	p := s.source
+	s.mu.Unlock()
+	if p == nil {
+		return nil
+	}
+	status := p.client.Status()
+	return &status
+}
+
+func (p *pullSource) OnSourceStart() error {
+	state, err := p.stream.AcquirePublisher(p, nil)
+	if err != nil {
+		return err
+	}
+	p.publishEvent(events.PublishStarted, state)
+	return nil
+}
+
+func (p *pullSource) OnSourceBatch(batch *medias.MediaFrameBatch) {
+	if p.stream.OnPublisherBatch(p, batch) {
+		// Frames of a remote stream arrive in bursts, so the wall clock time of the batch is too short
+		first, last := batch.Frames[0].Dts, batch.Frames[len(batch.Frames)-1].Dts
+		since := max(time.Duration(last-first)*time.Millisecond, time.Since(batch.StartTime))
+		_ = p.registry.UpdateStatus(p.stream.Name, batch.StartTime, medias.EvaluateBitrate(batch.Size(), since))
+	}
+}
+
+func (p *pullSource) OnSourceStop() {
+	wasActive := p.stream.ReleasePublisher(p)
+	p.publishEvent(events.PublishStopped, "")
+	if wasActive && !p.stream.HasPublisher() {
+		_ = p.registry.UpdateStatus(p.stream.Name, time.Unix(0, 0), 0)
+	}
+}
+
+func (p *pullSource) Id() string {
+	return p.id
+}
+
+func (p *pullSource) IsBackup() bool {
+	return false
+}
+
+func (p *pullSource) SetState(state PublisherState) {
+	log.Printf("Source of %s is %s", p.stream.Name, state)
+	if state == PublisherActive {
+		p.publishEvent(events.PublishPromoted, state)
+	} else {
+		p.publishEvent(events.PublishDemoted, state)
+	}
+}
+
+// Disconnect drops the connection, the source keeps reconnecting.
+func (p *pullSource) Disconnect() {
+	p.publishEvent(events.PublishTakeover, "")
+	p.client.Disconnect()
+}
+
+func (p *pullSource) publishEvent(eventType events.Type, state PublisherState) {
+	data := map[string]interface{}{"source": true}
+	if state != "" {
+		data["publish_state"] = state
+	}
+	events.Publish(events.Event{
+		Type:    eventType,
+		Stream:  p.stream.Name,
+		Session: p.id,
+		Data:    data,
+	})
+}
@@ -0,0 +1,74 @@
+package registry
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+)
+
+// hangingDialer never connects, like a dial to an address which drops the packets.
+type hangingDialer chan<- struct{}
+
+func (d hangingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// useTempStorage points the registry file into the test's temporary directory.
+func useTempStorage(t *testing.T) {
+	t.Helper()
+	old := REGESTRY_STORAGE_FILE
+	REGESTRY_STORAGE_FILE = filepath.Join(t.TempDir(), "data.json")
+	t.Cleanup(func() { REGESTRY_STORAGE_FILE = old })
+}
+
+func TestSourceRestartDoesNotBlockRegistry(t *testing.T) {
+	useTempStorage(t)
+	dialing := make(chan struct{}, 4)
+	r := NewRegistry(Config{Push: medias.PushConfig{
+		Dialer: func(api.PushTargetOptions) (medias.Dialer, error) {
+			return hangingDialer(dialing), nil
+		},
+	}})
+
+	waitDial := func() {
+		select {
+		case <-dialing:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the source doesn't dial")
+		}
+	}
+	// The old source is stuck dialing while the next call arrives
+	inTime := func(name string, call func() error) {
+		done := make(chan error, 1)
+		go func() { done <- call() }()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s blocks while the source dials", name)
+		}
+	}
+
+	inTime("create", func() error { return r.Update(&ExternalStream{Name: "s1", Source: "rtmp://192.0.2.1/live/a"}) })
+	waitDial()
+
+	inTime("update", func() error { return r.Update(&ExternalStream{Name: "s1", Source: "rtmp://192.0.2.1/live/b"}) })
+	waitDial()
+	inTime("list", func() error {
+		streams, err := r.GetStreams()
+		if err == nil && (len(streams) != 1 || streams[0].Source != "rtmp://192.0.2.1/live/b") {
+			t.Errorf("streams = %+v", streams)
+		}
+		return err
+	})
+	inTime("delete", func() error { return r.DeleteStream("s1") })
+}
@@ -1,10 +1,12 @@
 package registry
 
 import (
+	"fmt"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
 	"log"
 	"net/url"
+	"reflect"
 	"slices"
 	"sync"
 	"sync/atomic"
@@ -15,10 +17,40 @@ type Stream struct {
 	Name        string               `json:"name"`
 	Targets     []*api.PushTargetUrl `json:"targets"`
 	TargetNames map[string]string    `json:"target_names"` // URL -> Name mapping
-	status      *streamStatus
+	// URL -> connection options mapping
+	TargetOptions   map[string]api.PushTargetOptions `json:"target_options"`
+	RequireTLS      bool                             `json:"require_tls"`
+	PublisherPolicy PublisherPolicy                  `json:"publisher_policy"`
+	// Stall time after which the stream switches to the other publisher or the fallback slate
+	FailoverTimeoutMs int `json:"failover_timeout_ms"`
+	// FLV file looped into the consumers while no publisher delivers frames
+	FallbackFile string `json:"fallback_file"`
+	// rtmp, rtmps or rtsp URL the server pulls the stream from, or udp address it receives MPEG-TS on
+	Source        string                 `json:"source"`
+	SourceOptions *api.PushTargetOptions `json:"source_options"`
+	// Segment the stream into HLS while it is live
+	HLS bool `json:"hls"`
+	// Package the stream into CMAF for DASH and HLS fMP4 while it is live
+	CMAF   bool `json:"cmaf"`
+	status *streamStatus
+	config *Config
+
+	publisher     Publisher
+	standby       []standbyPublisher
+	failover      failoverState
+	timeline      timeline
+	slate         *slateRun
+	lastLiveBatch time.Time
+	source        *pullSource
+
+	sessionTargets       []*api.PushTargetUrl // extra targets of the current publish session
+	sessionTargetOptions map[string]api.PushTargetOptions
 
 	targetConsumers []medias.MediaPushConsumer
 	consumers       []medias.MediaConsumer
+	hls             *medias.HLSConsumer  // also one of the consumers
+	cmaf            *medias.CMAFConsumer // also one of the consumers
+	gop             gopCache
 
 	framesBatches chan *medias.MediaFrameBatch
 
@@ -28,29 +60,50 @@ type Stream struct {
 	die    sync.Once
 }
 
-func newStream(key *ExternalStream) (*Stream, error) {
-	targets := make([]*api.PushTargetUrl, len(key.Targets))
+// parseTargets validates the targets and splits them into URLs, URL -> Name and URL -> options mappings.
+func parseTargets(pushTargets []PushTarget, config *Config) ([]*api.PushTargetUrl, map[string]string, map[string]api.PushTargetOptions, error) {
+	targets := make([]*api.PushTargetUrl, len(pushTargets))
 	targetNames := make(map[string]string)
+	targetOptions := make(map[string]api.PushTargetOptions)
 
-	for i, t := range key.Targets {
+	for i, t := range pushTargets {
 		parse, err := url.Parse(t.URL)
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, err
+		}
+		if err := t.PushTargetOptions.Validate(config.Push.TLSFilesDir); err != nil {
+			return nil, nil, nil, InvalidStreamConfig{Err: fmt.Errorf("target %s: %w", t.Name, err)}
 		}
 		targets[i] = (*api.PushTargetUrl)(parse)
 		targetNames[t.URL] = t.Name
+		targetOptions[t.URL] = t.PushTargetOptions
+	}
+	return targets, targetNames, targetOptions, nil
+}
+
+func newStream(key *ExternalStream, config *Config) (*Stream, error) {
+	targets, targetNames, targetOptions, err := parseTargets(key.Targets, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := key.validateSettings(config); err != nil {
+		return nil, err
 	}
 
 	s := &Stream{
 		Name:            key.Name,
 		Targets:         targets,
 		TargetNames:     targetNames,
+		TargetOptions:   targetOptions,
+		config:          config,
 		consumers:       make([]medias.MediaConsumer, 0, 10),
 		targetConsumers: make([]medias.MediaPushConsumer, 0, 10),
 		framesBatches:   make(chan *medias.MediaFrameBatch, 3000),
 		quit:            make(chan struct{}),
 	}
+	s.updateSettings(key)
 	go s.dispatch()
+	go s.watchFallback()
 	return s, nil
 }
 
@@ -72,12 +125,115 @@ func (s *Stream) OnFrameBatch(frame *medias.MediaFrameBatch) {
 	}
 }
 
+// updateSettings copies the stream options other than targets and the source.
+func (s *Stream) updateSettings(key *ExternalStream) {
+	s.mu.Lock()
+	s.RequireTLS = key.RequireTLS
+	s.PublisherPolicy = key.PublisherPolicy
+	s.FailoverTimeoutMs = key.FailoverTimeoutMs
+	s.FallbackFile = key.FallbackFile
+	s.HLS = key.HLS
+	s.CMAF = key.CMAF
+	s.mu.Unlock()
+}
+
+// SetSessionTargets adds push targets which live until the current producer closes.
+func (s *Stream) SetSessionTargets(targets []PushTarget) error {
+	parsed, _, options, err := parseTargets(targets, s.config)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.sessionTargets = parsed
+	s.sessionTargetOptions = options
+	s.mu.Unlock()
+	return nil
+}
+
+// setTargets replaces the targets, their names and options, and reconnects the targets whose options changed.
+// Like the other settings they are written under the stream lock, the dispatch goroutine reads them.
+func (s *Stream) setTargets(targets []*api.PushTargetUrl, targetNames map[string]string, targetOptions map[string]api.PushTargetOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for targetURL, options := range targetOptions {
+		if old, existed := s.TargetOptions[targetURL]; existed && !reflect.DeepEqual(old, options) {
+			s.restartTarget(targetURL)
+		}
+	}
+	s.Targets = targets
+	s.TargetNames = targetNames
+	s.TargetOptions = targetOptions
+}
+
+// addTarget adds the target, or updates the options of an existing one.
+func (s *Stream) addTarget(target *api.PushTargetUrl, targetName string, options api.PushTargetOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	targetURL := target.String()
+	if !slices.ContainsFunc(s.Targets, func(t *api.PushTargetUrl) bool { return t.String() == targetURL }) {
+		s.Targets = append(s.Targets, target)
+		if s.TargetNames == nil {
+			s.TargetNames = make(map[string]string)
+		}
+		s.TargetNames[targetURL] = targetName
+	}
+	if s.TargetOptions == nil {
+		s.TargetOptions = make(map[string]api.PushTargetOptions)
+	}
+	if old, existed := s.TargetOptions[targetURL]; existed && !reflect.DeepEqual(old, options) {
+		s.restartTarget(targetURL)
+	}
+	s.TargetOptions[targetURL] = options
+}
+
+// removeTarget removes the target with its name and options.
+func (s *Stream) removeTarget(targetURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Targets = slices.DeleteFunc(s.Targets, func(t *api.PushTargetUrl) bool { return t.String() == targetURL })
+	delete(s.TargetNames, targetURL)
+	delete(s.TargetOptions, targetURL)
+}
+
+// restartTarget closes the push consumer of the target, so it's recreated with the actual options.
+// It is called with the stream lock held.
+func (s *Stream) restartTarget(targetURL string) {
+	for _, c := range s.targetConsumers {
+		if c.Target() == targetURL {
+			go func(c medias.MediaPushConsumer) {
+				_ = c.Close()
+			}(c)
+		}
+	}
+}
+
+func (s *Stream) targetsStatus() []TargetStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]TargetStatus, 0, len(s.targetConsumers))
+	for _, c := range s.targetConsumers {
+		name := c.Target()
+		if n, ok := s.TargetNames[name]; ok && n != "" {
+			name = n
+		}
+		statuses = append(statuses, TargetStatus{Name: name, URL: c.Target(), PushConsumerStatus: c.Status()})
+	}
+	return statuses
+}
+
 func (s *Stream) OnProducerClose() {
 	s.mu.Lock()
 	consumers := slices.Clone(s.consumers)
 	targetConsumers := slices.Clone(s.targetConsumers)
 	s.consumers = nil
 	s.targetConsumers = nil
+	s.hls = nil
+	s.cmaf = nil
+	s.gop = gopCache{}
+	s.sessionTargets = nil
+	s.sessionTargetOptions = nil
+	s.timeline = timeline{}
+	s.stopSlate()
 	s.mu.Unlock()
 	for _, c := range consumers {
 		_ = c.Close()
@@ -94,7 +250,7 @@ func (s *Stream) dispatch() {
 		}
 		s.OnProducerClose()
 	}()
-	
+
 	for {
 		timer := time.After(time.Second * 30)
 		select {
@@ -102,6 +258,7 @@ func (s *Stream) dispatch() {
 			s.updateConsumers()
 
 			s.mu.Lock()
+			s.gop.add(batch)
 			targetConsumers := slices.Clone(s.targetConsumers)
 			consumers := slices.Clone(s.consumers)
 			s.mu.Unlock()
@@ -143,8 +300,22 @@ func (s *Stream) dispatch() {
 }
 
 func (s *Stream) updateConsumers() {
+	var closedTargets []medias.MediaPushConsumer
+	var closed []medias.MediaConsumer
+
+	// Filtered under the lock, so that a player added meanwhile isn't lost
+	s.mu.Lock()
+	targets := append(slices.Clone(s.Targets), s.sessionTargets...)
+	targetOptions := make(map[string]api.PushTargetOptions, len(targets))
+	for u, options := range s.TargetOptions {
+		targetOptions[u] = options
+	}
+	for u, options := range s.sessionTargetOptions {
+		targetOptions[u] = options
+	}
+
 	registryTargets := make(map[string]struct{})
-	for _, target := range s.Targets {
+	for _, target := range targets {
 		registryTargets[target.String()] = struct{}{}
 	}
 	actualTargets := make(map[string]struct{})
@@ -152,11 +323,7 @@ func (s *Stream) updateConsumers() {
 	for _, consumer := range s.targetConsumers {
 		actualTargets[consumer.Target()] = struct{}{}
 		if _, ok := registryTargets[consumer.Target()]; !ok || consumer.IsClosed() {
-			go func(c medias.MediaPushConsumer) {
-				if err := c.Close(); err != nil {
-					log.Printf("Error closing push consumer for %s: %v", consumer.Target(), err)
-				}
-			}(consumer)
+			closedTargets = append(closedTargets, consumer)
 		} else {
 			newTargetConsumers = append(newTargetConsumers, consumer)
 		}
@@ -165,21 +332,45 @@ func (s *Stream) updateConsumers() {
 	newConsumers := make([]medias.MediaConsumer, 0, 10)
 	for _, consumer := range s.consumers {
 		if consumer.IsClosed() {
-			_ = consumer.Close()
+			closed = append(closed, consumer)
 		} else {
 			newConsumers = append(newConsumers, consumer)
 		}
 	}
 
-	s.mu.Lock()
 	s.targetConsumers = newTargetConsumers
 	s.consumers = newConsumers
+	if s.HLS && s.hls == nil {
+		s.hls = medias.NewHLSConsumer(s.Name, s.config.HLS)
+		s.consumers = append(s.consumers, s.hls)
+	} else if !s.HLS && s.hls != nil {
+		_ = s.hls.Close()
+		s.hls = nil
+	}
+	if s.CMAF && s.cmaf == nil {
+		s.cmaf = medias.NewCMAFConsumer(s.Name, s.config.HLS)
+		s.consumers = append(s.consumers, s.cmaf)
+	} else if !s.CMAF && s.cmaf != nil {
+		_ = s.cmaf.Close()
+		s.cmaf = nil
+	}
 	s.mu.Unlock()
 
-	for _, target := range s.Targets {
+	for _, consumer := range closedTargets {
+		go func(c medias.MediaPushConsumer) {
+			if err := c.Close(); err != nil {
+				log.Printf("Error closing push consumer for %s: %v", c.Target(), err)
+			}
+		}(consumer)
+	}
+	for _, consumer := range closed {
+		_ = consumer.Close()
+	}
+
+	for _, target := range targets {
 		if _, ok := actualTargets[target.String()]; !ok {
 			log.Printf("Creating PushConsumer for %s with target %s", s.Name, target.String())
-			c, err := medias.NewPushConsumer(target, s.Name)
+			c, err := medias.NewTargetConsumer(target, targetOptions[target.String()], s.Name, s.config.Push)
 			if err != nil {
 				log.Printf("Failed to create push consumer for stream %s: %v", s.Name, err)
 				continue
@@ -202,6 +393,17 @@ func (s *Stream) AddConsumer(consumer medias.MediaConsumer) {
 	s.mu.Unlock()
 }
 
+// AddPlayer adds a playback consumer, which first receives the frames since the last keyframe.
+func (s *Stream) AddPlayer(consumer medias.MediaConsumer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Under the lock, so that the cached frames come before the next batch
+	if batch := s.gop.batch(); batch != nil {
+		consumer.Play(batch)
+	}
+	s.consumers = append(s.consumers, consumer)
+}
+
 func (s *Stream) RemoveConsumer(id interface{}) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -212,8 +414,23 @@ func (s *Stream) RemoveConsumer(id interface{}) {
 	}
 }
 
+// HLSConsumer returns the HLS segmenter of the live stream, it is nil if the stream isn't live or HLS is off.
+func (s *Stream) HLSConsumer() *medias.HLSConsumer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hls
+}
+
+// CMAFConsumer returns the CMAF packager of the live stream, it is nil if the stream isn't live or CMAF is off.
+func (s *Stream) CMAFConsumer() *medias.CMAFConsumer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cmaf
+}
+
 func (s *Stream) Quit() {
 	s.die.Do(func() {
+		s.stopSource()
 		close(s.quit)
 	})
 }
@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+)
+
+// TestTargetsUpdateWhileLive changes the targets of a live stream while the dispatch goroutine and
+// the status API read them, run with -race.
+func TestTargetsUpdateWhileLive(t *testing.T) {
+	useTempStorage(t)
+	r := NewRegistry(Config{})
+	defer func() { _ = r.DeleteStream("s1") }()
+	if err := r.Update(&ExternalStream{Name: "s1", Targets: []PushTarget{{Name: "t0", URL: "rtmp://127.0.0.1:1/live/t0"}}}); err != nil {
+		t.Fatal(err)
+	}
+	stream, _ := r.GetInternalStream("s1")
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				stream.OnFrameBatch(&medias.MediaFrameBatch{})
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				if _, err := r.GetStreamsStatus(); err != nil {
+					t.Error(err)
+				}
+			}
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		target, _ := url.Parse(fmt.Sprintf("rtmp://127.0.0.1:1/live/a%d", i))
+		if err := r.AddStreamTarget("s1", (*api.PushTargetUrl)(target), "a", api.PushTargetOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Update(&ExternalStream{Name: "s1", Targets: []PushTarget{{Name: "t", URL: fmt.Sprintf("rtmp://127.0.0.1:1/live/t%d", i)}}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.DeleteStreamTarget("s1", target.String()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	streams, _ := r.GetStreams()
+	if len(streams) != 1 || len(streams[0].Targets) != 1 || streams[0].Targets[0].URL != "rtmp://127.0.0.1:1/live/t49" {
+		t.Fatalf("streams = %+v", streams)
+	}
+}
+
+// testPlayer counts the batches it receives.
+type testPlayer struct {
+	id      int
+	batches chan struct{}
+}
+
+func (p *testPlayer) Play(*medias.MediaFrameBatch) {
+	select {
+	case p.batches <- struct{}{}:
+	default:
+	}
+}
+
+func (p *testPlayer) Id() string     { return fmt.Sprint(p.id) }
+func (p *testPlayer) IsClosed() bool { return false }
+func (p *testPlayer) Close() error   { return nil }
+
+// closedPlayer tells when the dispatch goroutine closes it.
+type closedPlayer struct {
+	testPlayer
+	closing chan struct{}
+}
+
+func (p *closedPlayer) IsClosed() bool { return true }
+func (p *closedPlayer) Close() error {
+	close(p.closing)
+	time.Sleep(10 * time.Millisecond)
+	return nil
+}
+
+func TestAddPlayerWhileDispatching(t *testing.T) {
+	useTempStorage(t)
+	r := NewRegistry(Config{})
+	defer func() { _ = r.DeleteStream("s1") }()
+	if err := r.Update(&ExternalStream{Name: "s1"}); err != nil {
+		t.Fatal(err)
+	}
+	stream, _ := r.GetInternalStream("s1")
+
+	closed := &closedPlayer{closing: make(chan struct{})}
+	stream.AddConsumer(closed)
+	stream.OnFrameBatch(&medias.MediaFrameBatch{})
+	select {
+	case <-closed.closing:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the closed consumer isn't dropped")
+	}
+	// The player comes while the dispatch goroutine drops the closed consumer
+	player := &testPlayer{batches: make(chan struct{}, 1)}
+	stream.AddPlayer(player)
+	stream.OnFrameBatch(&medias.MediaFrameBatch{})
+	select {
+	case <-player.batches:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the player receives no frames")
+	}
+}
@@ -0,0 +1,95 @@
+package registry
+
+import "github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+
+const defaultFrameStep = 40 // ms, used before the frame rate is known
+
+// timeline retimes the frames of consecutive sources (publishers or the fallback slate)
+// into one continuous output, so the push targets never notice a switch.
+// A new source is forwarded from its first keyframe.
+type timeline struct {
+	source       interface{} // the source being forwarded
+	offset       int64       // added to the timestamps of the source
+	videoSources map[interface{}]bool
+	started      bool // some frames have been forwarded
+	lastDts      int64
+	lastVideoDts int64
+	minDts       int64 // frames of the new source preceding its first keyframe are dropped
+	step         int64 // interval between the last forwarded video frames
+}
+
+// retime returns the frames of the source to forward, switching to it at its first keyframe.
+func (t *timeline) retime(source interface{}, frames []medias.MediaFrame) []medias.MediaFrame {
+	if t.videoSources == nil {
+		t.videoSources = make(map[interface{}]bool)
+	}
+	for _, frame := range frames {
+		if frame.IsVideo() {
+			t.videoSources[source] = true
+			break
+		}
+	}
+
+	if source != t.source {
+		k := keyframeIndex(frames, t.videoSources[source])
+		if k < 0 {
+			return nil
+		}
+		frames = frames[k:]
+		t.source = source
+		if t.started {
+			t.offset = t.lastDts + t.step - int64(frames[0].Dts)
+			t.minDts = t.lastDts + t.step
+		} else {
+			t.offset = 0
+		}
+	}
+
+	forwarded := make([]medias.MediaFrame, 0, len(frames))
+	for _, frame := range frames {
+		dts := int64(frame.Dts) + t.offset
+		if dts < t.minDts {
+			continue
+		}
+		frame.Dts = uint32(dts)
+		frame.Pts = uint32(int64(frame.Pts) + t.offset)
+		if frame.IsVideo() {
+			if t.started && dts > t.lastVideoDts && dts-t.lastVideoDts < 1000 {
+				t.step = dts - t.lastVideoDts
+			}
+			t.lastVideoDts = dts
+		}
+		if dts > t.lastDts {
+			t.lastDts = dts
+		}
+		if t.step == 0 {
+			t.step = defaultFrameStep
+		}
+		t.started = true
+		forwarded = append(forwarded, frame)
+	}
+	return forwarded
+}
+
+// retimeBatch returns nil if there is nothing to forward, it's called with s.mu held.
+func (s *Stream) retimeBatch(source interface{}, batch *medias.MediaFrameBatch) *medias.MediaFrameBatch {
+	frames := s.timeline.retime(source, batch.Frames)
+	if len(frames) == 0 {
+		return nil
+	}
+	return &medias.MediaFrameBatch{Frames: frames, StartTime: batch.StartTime}
+}
+
+func (t *timeline) forget(source interface{}) {
+	delete(t.videoSources, source)
+}
+
+// keyframeIndex returns the index of the first keyframe, any frame of audio-only sources qualifies.
+func keyframeIndex(frames []medias.MediaFrame, hasVideo bool) int {
+	for i, frame := range frames {
+		if frame.IsIFrame || !hasVideo {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,72 @@
+package registry
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+	"github.com/yapingcat/gomedia/go-codec"
+)
+
+func video(dts uint32, key bool) medias.MediaFrame {
+	return medias.MediaFrame{Cid: codec.CODECID_VIDEO_H264, Pts: dts, Dts: dts, IsIFrame: key}
+}
+
+func audio(dts uint32) medias.MediaFrame {
+	return medias.MediaFrame{Cid: codec.CODECID_AUDIO_AAC, Pts: dts, Dts: dts}
+}
+
+func TestTimelineRetime(t *testing.T) {
+	a, b, c := "a", "b", "c"
+	steps := []struct {
+		name   string
+		source string
+		frames []medias.MediaFrame
+		want   []uint32 // dts of the forwarded frames
+	}{
+		{"first source starts at its keyframe", a,
+			[]medias.MediaFrame{video(1000, false), video(1040, true), video(1080, false), audio(1050)},
+			[]uint32{1040, 1080, 1050}},
+		{"same source keeps its timestamps", a,
+			[]medias.MediaFrame{video(1120, false)},
+			[]uint32{1120}},
+		{"switch waits for a keyframe", b,
+			[]medias.MediaFrame{video(5000, false)},
+			nil},
+		{"switch continues one frame step later", b,
+			[]medias.MediaFrame{audio(4990), video(5000, true), audio(4995), audio(5010), video(5033, false)},
+			[]uint32{1160, 1170, 1193}},
+		{"audio-only source switches at once", c,
+			[]medias.MediaFrame{audio(0), audio(23)},
+			[]uint32{1226, 1249}},
+		{"switch back uses the latest frame step", b,
+			[]medias.MediaFrame{audio(5030), video(5066, false), video(5100, true)},
+			[]uint32{1282}},
+	}
+
+	var tl timeline
+	for _, step := range steps {
+		got := tl.retime(step.source, step.frames)
+		var dts []uint32
+		for _, frame := range got {
+			dts = append(dts, frame.Dts)
+			if frame.Pts != frame.Dts {
+				t.Fatalf("%s: pts %d of frame %d wasn't shifted with dts", step.name, frame.Pts, frame.Dts)
+			}
+		}
+		if !slices.Equal(dts, step.want) {
+			t.Fatalf("%s: forwarded %v, want %v", step.name, dts, step.want)
+		}
+	}
+}
+
+func TestTimelineKeepsCompositionOffset(t *testing.T) {
+	var tl timeline
+	tl.retime("a", []medias.MediaFrame{video(0, true), video(40, false)})
+	frame := video(1000, true)
+	frame.Pts = 1080
+	got := tl.retime("b", []medias.MediaFrame{frame})
+	if len(got) != 1 || got[0].Dts != 80 || got[0].Pts != 160 {
+		t.Fatalf("got %+v, want dts 80 and pts 160", got)
+	}
+}
@@ -0,0 +1,88 @@
+package registry
+
+import (
+	"time"
+)
+
+// APIToken is a named bearer token for automation. Only the SHA-256 hash of the token is stored.
+type APIToken struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	Hash       string     `json:"hash"`
+	Scopes     []string   `json:"scopes"`
+	Streams    []string   `json:"streams,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+func (t *APIToken) IsExpired(now time.Time) bool {
+	return t.ExpiresAt != nil && now.After(*t.ExpiresAt)
+}
+
+type TokenNotFound struct{}
+
+func (e TokenNotFound) Error() string {
+	return "TokenNotExist"
+}
+
+// lastUsedPersistInterval limits how often token usage alone causes the registry file to be rewritten.
+const lastUsedPersistInterval = time.Minute
+
+func (r *registryImpl) GetTokens() ([]*APIToken, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	tokens := make([]*APIToken, 0, len(r.tokens))
+	for _, token := range r.tokens {
+		t := *token
+		tokens = append(tokens, &t)
+	}
+	return tokens, nil
+}
+
+func (r *registryImpl) AddToken(token *APIToken) error {
+	r.mux.Lock()
+	r.tokens[token.Id] = token
+	r.mux.Unlock()
+	r.savePersistent()
+	return nil
+}
+
+func (r *registryImpl) DeleteToken(id string) error {
+	r.mux.Lock()
+	if _, ok := r.tokens[id]; !ok {
+		r.mux.Unlock()
+		return TokenNotFound{}
+	}
+	delete(r.tokens, id)
+	r.mux.Unlock()
+	r.savePersistent()
+	return nil
+}
+
+func (r *registryImpl) UseToken(hash string, now time.Time) (*APIToken, error) {
+	r.mux.Lock()
+	var found *APIToken
+	for _, token := range r.tokens {
+		if token.Hash == hash {
+			found = token
+			break
+		}
+	}
+	if found == nil || found.IsExpired(now) {
+		r.mux.Unlock()
+		return nil, TokenNotFound{}
+	}
+	persist := found.LastUsedAt == nil || now.Sub(*found.LastUsedAt) >= lastUsedPersistInterval
+	if persist {
+		found.LastUsedAt = &now
+	}
+	t := *found
+	r.mux.Unlock()
+
+	if persist {
+		// Keep the file write off the authentication path
+		go r.savePersistent()
+	}
+	return &t, nil
+}
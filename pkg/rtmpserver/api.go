@@ -2,16 +2,47 @@ package rtmpserver
 
 import (
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+	"github.com/pion/webrtc/v3"
 	"sync"
+	"time"
 )
 
 type MediaServer struct {
-	config   MediaServerConfig
-	registry registry.Registry
-	sessions map[string]*MediaSession
-	mu       sync.Mutex
+	config    MediaServerConfig
+	registry  registry.Registry
+	sessions  map[string]session
+	callbacks *callbackClient
+	whip      *webrtc.API
+	rtspUDP   *rtspUDP
+	mu        sync.Mutex
 }
 
 type MediaServerConfig struct {
-	Port int
+	Port        int
+	ListenAddrs []string // host:port pairs, e.g. "[::]:1935"; defaults to all interfaces on Port
+
+	// Optional RTMPS listeners
+	TLSPort        int
+	TLSListenAddrs []string
+	TLSCertFile    string
+	TLSKeyFile     string
+
+	// Optional SRT listeners for MPEG-TS publishers
+	SRTListenAddrs []string
+	SRTPassphrase  string        // callers must encrypt with it if set
+	SRTLatency     time.Duration // defaults to 120ms
+
+	// WebRTC publishers negotiated over WHIP by the API server
+	WHIPUDPAddr     string   // single UDP port for the media of all publishers, random ports if empty
+	WHIPPublicIPs   []string // announced instead of the local addresses behind a NAT
+	WHIPAudioPolicy medias.AudioPolicy
+
+	// Optional RTSP listeners for players of rtsp://host:port/[app/]stream
+	RTSPListenAddrs []string // e.g. ":8554"; RTSP is disabled if empty
+	RTSPUDPPort     int      // RTP port of UDP players, RTCP uses the next one; players must use TCP if zero
+
+	OnPublishURL    string // HTTP endpoint authorizing publishers, like nginx-rtmp on_publish
+	OnPlayURL       string // HTTP endpoint authorizing players
+	CallbackTimeout time.Duration
 }
@@ -0,0 +1,99 @@
+package rtmpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+)
+
+// callbackRequest is posted as JSON to the on_publish / on_play URL.
+type callbackRequest struct {
+	Action     string              `json:"action"`
+	App        string              `json:"app"`
+	Stream     string              `json:"stream"`
+	Query      map[string][]string `json:"query"`
+	RemoteAddr string              `json:"remote_addr"`
+	SessionId  string              `json:"session_id"`
+}
+
+// callbackResponse is the optional JSON body of an accepting response.
+type callbackResponse struct {
+	Targets []registry.PushTarget `json:"targets"` // extra push targets for this publish session
+}
+
+type callbackClient struct {
+	onPublishURL string
+	onPlayURL    string
+	client       *http.Client
+}
+
+func newCallbackClient(config MediaServerConfig) *callbackClient {
+	return &callbackClient{
+		onPublishURL: config.OnPublishURL,
+		onPlayURL:    config.OnPlayURL,
+		client:       &http.Client{Timeout: config.CallbackTimeout},
+	}
+}
+
+// authorize calls the configured URL for the action. A session is accepted if the URL
+// is not configured or the endpoint answers with a 2xx status code.
+func (c *callbackClient) authorize(action string, remoteAddr, sessionId, app, streamName string, query url.Values) (*callbackResponse, error) {
+	callbackURL := c.onPublishURL
+	if action == "play" {
+		callbackURL = c.onPlayURL
+	}
+	if callbackURL == "" {
+		return &callbackResponse{}, nil
+	}
+
+	body, err := json.Marshal(callbackRequest{
+		Action:     action,
+		App:        app,
+		Stream:     streamName,
+		Query:      query,
+		RemoteAddr: remoteAddr,
+		SessionId:  sessionId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Post(callbackURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s callback rejected with %s", action, resp.Status)
+	}
+
+	var result callbackResponse
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) > 0 && strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("invalid %s callback response: %w", action, err)
+		}
+	}
+	return &result, nil
+}
+
+// splitStreamName separates the query parameters encoders append to the stream name.
+func splitStreamName(streamName string) (string, url.Values) {
+	name, rawQuery, found := strings.Cut(streamName, "?")
+	if !found {
+		return name, url.Values{}
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return name, url.Values{}
+	}
+	return name, query
+}
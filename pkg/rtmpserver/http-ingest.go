@@ -0,0 +1,50 @@
+package rtmpserver
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+)
+
+const ingestReadSize = 32 * 1024
+
+// AcceptFLVIngest authorizes an FLV body posted to the API server like an RTMP publisher.
+// The returned serve func demuxes the body into the stream until it ends or the session is closed,
+// closer must unblock a pending read of the body.
+func (s *MediaServer) AcceptFLVIngest(body io.Reader, remoteAddr string, secure bool, closer func() error, streamName string, query url.Values) (func() error, error) {
+	var bytesIn atomic.Uint64
+	counters := func() (uint64, uint64) {
+		return bytesIn.Load(), 0
+	}
+	sess := newIngestSession(ProtocolHTTPFLV, remoteAddr, secure, closer, counters)
+	if err := s.acquireIngest(sess, "", streamName, query); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		defer s.releaseIngest(sess)
+		demuxer := medias.NewFLVDemuxer(sess.onBatch)
+		buf := make([]byte, ingestReadSize)
+		for {
+			n, err := body.Read(buf)
+			bytesIn.Add(uint64(n))
+			if n > 0 {
+				if err := demuxer.Input(buf[:n]); err != nil {
+					log.Printf("HTTP-FLV stream %s from %s: demuxer error %v", streamName, remoteAddr, err)
+					return err
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				log.Printf("HTTP-FLV stream %s from %s ended", streamName, remoteAddr)
+				return nil
+			} else if err != nil {
+				log.Printf("HTTP-FLV stream %s from %s closed: %v", streamName, remoteAddr, err)
+				return err
+			}
+		}
+	}, nil
+}
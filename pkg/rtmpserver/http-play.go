@@ -0,0 +1,117 @@
+package rtmpserver
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/events"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/utils"
+)
+
+// PlayUnauthorized is returned when the play callback rejects a player.
+type PlayUnauthorized struct {
+	Err error
+}
+
+func (e PlayUnauthorized) Error() string {
+	return fmt.Sprintf("play rejected: %v", e.Err)
+}
+
+func (e PlayUnauthorized) Unwrap() error {
+	return e.Err
+}
+
+// flvPlayer is an HTTP-FLV or WebSocket-FLV player of a stream.
+type flvPlayer struct {
+	id         string
+	protocol   string
+	remoteAddr string
+	secure     bool
+	since      time.Time
+	name       string
+	consumer   *medias.FLVConsumer
+	bytesOut   atomic.Uint64
+}
+
+// AcceptFLVPlayer authorizes a player like an RTMP one. The returned serve func writes the stream
+// as FLV, starting from the last keyframe, until the stream ends, a write fails, the context is done
+// or the session is closed.
+func (s *MediaServer) AcceptFLVPlayer(protocol, remoteAddr string, secure bool, streamName string, query url.Values) (func(ctx context.Context, write func(data []byte) error) error, error) {
+	player := &flvPlayer{
+		id:         utils.GenId(),
+		protocol:   protocol,
+		remoteAddr: remoteAddr,
+		secure:     secure,
+		since:      time.Now(),
+		name:       streamName,
+		consumer:   medias.NewFLVConsumer(streamName),
+	}
+	if _, err := s.callbacks.authorize("play", remoteAddr, player.id, "", streamName, query); err != nil {
+		log.Printf("Play of %s rejected: %v", streamName, err)
+		return nil, PlayUnauthorized{Err: err}
+	}
+	stream, err := s.registry.GetInternalStream(streamName)
+	if err != nil {
+		return nil, err
+	} else if stream == nil {
+		return nil, registry.StreamNotFound{}
+	}
+
+	return func(ctx context.Context, write func(data []byte) error) error {
+		stop := context.AfterFunc(ctx, func() {
+			_ = player.consumer.Close()
+		})
+		defer stop()
+		s.mu.Lock()
+		s.sessions[player.id] = player
+		s.mu.Unlock()
+		defer func() {
+			_ = player.consumer.Close()
+			s.mu.Lock()
+			delete(s.sessions, player.id)
+			s.mu.Unlock()
+		}()
+
+		stream.AddPlayer(player.consumer)
+		player.publishEvent(events.ViewerJoined)
+		err := player.consumer.Serve(func(data []byte) error {
+			player.bytesOut.Add(uint64(len(data)))
+			return write(data)
+		})
+		log.Printf("%s player of %s from %s left: %v", protocol, streamName, remoteAddr, err)
+		return err
+	}, nil
+}
+
+func (p *flvPlayer) publishEvent(eventType events.Type) {
+	events.Publish(events.Event{
+		Type:       eventType,
+		Stream:     p.name,
+		Session:    p.id,
+		RemoteAddr: p.remoteAddr,
+		Data:       map[string]interface{}{"secure": p.secure, "protocol": p.protocol},
+	})
+}
+
+func (p *flvPlayer) Info() SessionInfo {
+	return SessionInfo{
+		Id:         p.id,
+		Protocol:   p.protocol,
+		RemoteAddr: p.remoteAddr,
+		Role:       RolePlayer,
+		Stream:     p.name,
+		Secure:     p.secure,
+		Since:      p.since,
+		BytesOut:   p.bytesOut.Load(),
+	}
+}
+
+func (p *flvPlayer) Close() error {
+	return p.consumer.Close()
+}
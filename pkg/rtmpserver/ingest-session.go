@@ -0,0 +1,202 @@
+package rtmpserver
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/events"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/utils"
+)
+
+// PublishUnauthorized is returned when the publish callback rejects an ingest session.
+type PublishUnauthorized struct {
+	Err error
+}
+
+func (e PublishUnauthorized) Error() string {
+	return fmt.Sprintf("publish rejected: %v", e.Err)
+}
+
+func (e PublishUnauthorized) Unwrap() error {
+	return e.Err
+}
+
+// PublishForbidden is returned when the stream requires a secure connection.
+type PublishForbidden struct {
+	Stream string
+}
+
+func (e PublishForbidden) Error() string {
+	return fmt.Sprintf("stream %s requires a secure connection", e.Stream)
+}
+
+// ingestSession publishes frames demuxed from a connection of another protocol than RTMP,
+// it is the publisher of its stream like a MediaProducer.
+type ingestSession struct {
+	id         string
+	protocol   string
+	remoteAddr string
+	secure     bool
+	since      time.Time
+	app        string
+	name       string
+	backup     bool
+	stream     *registry.Stream
+	registry   registry.Registry
+	closer     func() error
+	counters   func() (in, out uint64)
+
+	infoMu       sync.Mutex
+	publishState registry.PublisherState
+}
+
+func newIngestSession(protocol, remoteAddr string, secure bool, closer func() error, counters func() (in, out uint64)) *ingestSession {
+	return &ingestSession{
+		id:         utils.GenId(),
+		protocol:   protocol,
+		remoteAddr: remoteAddr,
+		secure:     secure,
+		since:      time.Now(),
+		closer:     closer,
+		counters:   counters,
+	}
+}
+
+// acquireIngest authorizes the session like an RTMP publisher and makes it the publisher of the stream.
+// The session is listed until releaseIngest.
+func (s *MediaServer) acquireIngest(sess *ingestSession, app, streamName string, query url.Values) error {
+	sess.app = app
+	sess.name = streamName
+	sess.registry = s.registry
+	callback, err := s.callbacks.authorize("publish", sess.remoteAddr, sess.id, app, streamName, query)
+	if err != nil {
+		log.Printf("Publish of %s rejected: %v", streamName, err)
+		return PublishUnauthorized{Err: err}
+	}
+
+	stream, err := s.registry.GetInternalStream(streamName)
+	if err != nil {
+		log.Printf("Failed to get %s stream info: %v", streamName, err)
+		return err
+	} else if stream == nil {
+		log.Printf("No such %s stream info", streamName)
+		return registry.StreamNotFound{}
+	} else if stream.RequireTLS && !sess.secure {
+		log.Printf("Stream %s requires a secure connection, rejecting %s publisher", streamName, sess.protocol)
+		return PublishForbidden{Stream: streamName}
+	}
+
+	sess.stream = stream
+	sess.backup, _ = strconv.ParseBool(query.Get("backup"))
+	state, err := stream.AcquirePublisher(sess, callback.Targets)
+	if errors.As(err, &registry.PublisherConflict{}) {
+		log.Printf("Publish of %s rejected: %v", streamName, err)
+		sess.publishEvent(events.PublishRejected)
+		return err
+	} else if err != nil {
+		log.Printf("Invalid targets from publish callback for %s: %v", streamName, err)
+		return err
+	}
+	sess.setPublishState(state)
+
+	s.mu.Lock()
+	s.sessions[sess.id] = sess
+	s.mu.Unlock()
+	log.Printf("New %s stream %s from %s", sess.protocol, streamName, sess.remoteAddr)
+	sess.publishEvent(events.PublishStarted)
+	return nil
+}
+
+// releaseIngest ends the publishing of an acquired session.
+func (s *MediaServer) releaseIngest(sess *ingestSession) {
+	s.mu.Lock()
+	delete(s.sessions, sess.id)
+	s.mu.Unlock()
+	_ = sess.closer()
+	wasActive := sess.stream.ReleasePublisher(sess)
+	sess.publishEvent(events.PublishStopped)
+	if wasActive && !sess.stream.HasPublisher() {
+		_ = sess.registry.UpdateStatus(sess.name, time.Unix(0, 0), 0)
+	}
+}
+
+func (sess *ingestSession) onBatch(batch *medias.MediaFrameBatch) {
+	// Frames of standby publishers are dropped by the stream
+	if sess.stream.OnPublisherBatch(sess, batch) {
+		_ = sess.registry.UpdateStatus(sess.name, batch.StartTime, medias.EvaluateBitrate(batch.Size(), time.Since(batch.StartTime)))
+	}
+}
+
+func (sess *ingestSession) setPublishState(state registry.PublisherState) {
+	sess.infoMu.Lock()
+	defer sess.infoMu.Unlock()
+	sess.publishState = state
+}
+
+func (sess *ingestSession) publishEvent(eventType events.Type) {
+	data := map[string]interface{}{"secure": sess.secure, "protocol": sess.protocol}
+	sess.infoMu.Lock()
+	if sess.publishState != "" {
+		data["publish_state"] = sess.publishState
+	}
+	sess.infoMu.Unlock()
+	events.Publish(events.Event{
+		Type:       eventType,
+		Stream:     sess.name,
+		Session:    sess.id,
+		RemoteAddr: sess.remoteAddr,
+		Data:       data,
+	})
+}
+
+func (sess *ingestSession) Info() SessionInfo {
+	sess.infoMu.Lock()
+	defer sess.infoMu.Unlock()
+	in, out := sess.counters()
+	return SessionInfo{
+		Id:           sess.id,
+		Protocol:     sess.protocol,
+		RemoteAddr:   sess.remoteAddr,
+		Role:         RolePublisher,
+		App:          sess.app,
+		Stream:       sess.name,
+		PublishState: sess.publishState,
+		Secure:       sess.secure,
+		Since:        sess.since,
+		BytesIn:      in,
+		BytesOut:     out,
+	}
+}
+
+func (sess *ingestSession) Close() error {
+	return sess.closer()
+}
+
+func (sess *ingestSession) Id() string {
+	return sess.id
+}
+
+func (sess *ingestSession) IsBackup() bool {
+	return sess.backup
+}
+
+func (sess *ingestSession) SetState(state registry.PublisherState) {
+	sess.setPublishState(state)
+	if state == registry.PublisherActive {
+		sess.publishEvent(events.PublishPromoted)
+	} else {
+		sess.publishEvent(events.PublishDemoted)
+	}
+}
+
+func (sess *ingestSession) Disconnect() {
+	sess.publishEvent(events.PublishTakeover)
+	_ = sess.closer()
+}
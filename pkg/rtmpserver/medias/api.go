@@ -14,6 +14,10 @@ type MediaFrame struct {
 	IsIFrame bool
 }
 
+func (f *MediaFrame) IsVideo() bool {
+	return f.Cid < codec.CODECID_AUDIO_AAC
+}
+
 func (f *MediaFrame) clone() MediaFrame {
 	frames := make([]byte, len(f.Frame))
 	copy(frames, f.Frame)
@@ -32,6 +36,23 @@ type MediaFrameBatch struct {
 	StartTime time.Time
 }
 
+// Size returns the number of bytes of the frames.
+func (b *MediaFrameBatch) Size() int {
+	var bytes int
+	for _, frame := range b.Frames {
+		bytes += len(frame.Frame)
+	}
+	return bytes
+}
+
+// EvaluateBitrate returns the bitrate in kbit/s.
+func EvaluateBitrate(bytes int, since time.Duration) uint {
+	if since == 0 {
+		return 0
+	}
+	return uint(time.Duration(bytes) * time.Second / since / 128)
+}
+
 func (b *MediaFrameBatch) Clone() *MediaFrameBatch {
 	frames := make([]MediaFrame, len(b.Frames))
 	for i, frame := range b.Frames {
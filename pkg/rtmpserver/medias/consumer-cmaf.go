@@ -0,0 +1,511 @@
+package medias
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/utils"
+	"github.com/yapingcat/gomedia/go-codec"
+	"github.com/yapingcat/gomedia/go-mp4"
+)
+
+// CMAFConsumer packages the stream into fragmented MP4, one track per file as CMAF requires,
+// and describes the same segments with a DASH MPD and HLS fMP4 playlists. The segments follow
+// the HLSConfig. Only H.264 and AAC tracks are packaged.
+type CMAFConsumer struct {
+	id         string
+	sourceName string
+	config     HLSConfig
+
+	mu       sync.Mutex
+	video    *cmafTrack
+	audio    *cmafTrack
+	pending  []*MediaFrame
+	probed   bool
+	keyed    bool // a video keyframe has been seen
+	hasVideo bool
+	origin   time.Time // wall clock time of the media time zero
+
+	quited atomic.Bool
+}
+
+type cmafTrack struct {
+	name   string // video or audio, also the name of its files
+	config HLSConfig
+	muxer  *mp4.Movmuxer
+	out    *fragmentBuffer
+	codecs string
+	init   []byte
+
+	width, height uint32
+	sampleRate    int
+
+	segments []*cmafSegment // completed, oldest first
+	current  *cmafSegment
+	nextSeq  uint64
+	longest  uint32 // ms, of the segments so far
+
+	lastDts       uint32
+	frameDuration uint32 // of audio, the last frame of a segment lasts as long as the one before like in the muxer
+}
+
+type cmafSegment struct {
+	seq      uint64
+	start    uint32 // ms, the name of the segment
+	duration uint32 // ms
+	data     []byte
+}
+
+func NewCMAFConsumer(sourceName string, config HLSConfig) *CMAFConsumer {
+	return &CMAFConsumer{
+		id:         utils.GenId(),
+		sourceName: sourceName,
+		config:     config.withDefaults(),
+	}
+}
+
+func (c *CMAFConsumer) Play(batch *MediaFrameBatch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range batch.Frames {
+		c.writeFrame(&batch.Frames[i])
+	}
+}
+
+// writeFrame starts at a keyframe, or at once for audio only streams, and buffers the frames
+// until it knows the tracks like the HLS segmenter.
+func (c *CMAFConsumer) writeFrame(frame *MediaFrame) {
+	if frame.IsVideo() && !c.keyed {
+		if !frame.IsIFrame {
+			return
+		}
+		c.keyed = true
+	}
+	if c.probed {
+		c.mux(frame)
+		return
+	}
+	c.pending = append(c.pending, frame)
+
+	var hasAudio bool
+	for _, f := range c.pending {
+		c.hasVideo = c.hasVideo || f.IsVideo()
+		hasAudio = hasAudio || !f.IsVideo()
+	}
+	probed := time.Duration(int64(frame.Dts)-int64(c.pending[0].Dts)) * time.Millisecond
+	if !(c.hasVideo && hasAudio) && probed < codecProbeTime {
+		return
+	}
+
+	c.probed = true
+	for _, f := range c.pending {
+		switch {
+		case f.Cid == codec.CODECID_VIDEO_H264 && c.video == nil:
+			c.video = newCMAFTrack("video", mp4.MP4_CODEC_H264, c.config)
+		case f.Cid == codec.CODECID_AUDIO_AAC && c.audio == nil:
+			c.audio = newCMAFTrack("audio", mp4.MP4_CODEC_AAC, c.config)
+		}
+	}
+	if c.video == nil && c.hasVideo {
+		log.Printf("CMAFConsumer (%s) packages no video: only H.264 is supported", c.sourceName)
+	}
+	if c.audio == nil && hasAudio {
+		log.Printf("CMAFConsumer (%s) packages no audio: only AAC is supported", c.sourceName)
+	}
+	// Audio only streams are cut at any frame
+	c.hasVideo = c.video != nil
+	c.origin = time.Now().Add(-time.Duration(c.pending[0].Dts) * time.Millisecond)
+	pending := c.pending
+	c.pending = nil
+	for _, f := range pending {
+		c.mux(f)
+	}
+}
+
+func newCMAFTrack(name string, cid mp4.MP4_CODEC_TYPE, config HLSConfig) *cmafTrack {
+	out := &fragmentBuffer{}
+	// The writer isn't seekable, the fragments are addressed from the moof box
+	muxer, _ := mp4.CreateMp4Muxer(out, mp4.WithMp4Flag(mp4.MP4_FLAG_FRAGMENT))
+	if cid == mp4.MP4_CODEC_H264 {
+		muxer.AddVideoTrack(cid)
+	} else {
+		muxer.AddAudioTrack(cid)
+	}
+	return &cmafTrack{name: name, config: config, muxer: muxer, out: out}
+}
+
+func (c *CMAFConsumer) mux(frame *MediaFrame) {
+	defer func() {
+		// The muxer panics on malformed parameter sets
+		if r := recover(); r != nil {
+			log.Printf("CMAFConsumer (%s) skips a frame: %v", c.sourceName, r)
+		}
+	}()
+	switch {
+	case frame.Cid == codec.CODECID_VIDEO_H264 && c.video != nil:
+		c.muxVideo(frame)
+	case frame.Cid == codec.CODECID_AUDIO_AAC && c.audio != nil:
+		if c.hasVideo && c.video.current == nil {
+			// The tracks start together at the first keyframe
+			return
+		}
+		if !c.hasVideo && c.audio.current != nil && c.audio.elapsed(frame.Dts) >= c.config.SegmentDuration {
+			c.cut(frame.Dts)
+		}
+		c.muxAudio(frame)
+	}
+}
+
+// muxVideo relies on the muxer, which completes a fragment when a keyframe comes, so segments
+// hold one or more whole GOPs and are cut at the first keyframe after the target duration.
+func (c *CMAFConsumer) muxVideo(frame *MediaFrame) {
+	track := c.video
+	if frame.IsIFrame && track.codecs == "" {
+		track.probeH264(frame.Frame)
+	}
+	if track.current == nil {
+		track.current = &cmafSegment{start: frame.Dts}
+	}
+	// The parameter sets stay in the samples too, so that a change of them reaches the players
+	if err := track.muxer.Write(1, frame.Frame, uint64(frame.Pts), uint64(frame.Dts)); err != nil {
+		log.Printf("CMAFConsumer (%s) skips a video frame: %v", c.sourceName, err)
+		return
+	}
+	track.lastDts = frame.Dts
+	if fragment := track.take(); len(fragment) > 0 {
+		track.current.data = append(track.current.data, fragment...)
+		if frame.IsIFrame && track.elapsed(frame.Dts) >= c.config.SegmentDuration {
+			c.cut(frame.Dts)
+		}
+	}
+}
+
+func (c *CMAFConsumer) muxAudio(frame *MediaFrame) {
+	track := c.audio
+	if track.codecs == "" {
+		if err := track.probeAAC(frame.Frame); err != nil {
+			log.Printf("CMAFConsumer (%s) skips an audio frame: %v", c.sourceName, err)
+			return
+		}
+	}
+	if track.current == nil {
+		track.current = &cmafSegment{start: frame.Dts}
+	}
+	if err := track.muxer.Write(1, frame.Frame, uint64(frame.Pts), uint64(frame.Dts)); err != nil {
+		log.Printf("CMAFConsumer (%s) skips an audio frame: %v", c.sourceName, err)
+		return
+	}
+	if frame.Dts > track.lastDts && track.lastDts != 0 {
+		track.frameDuration = frame.Dts - track.lastDts
+	}
+	track.lastDts = frame.Dts
+}
+
+// cut completes the segments of both tracks at dts. The audio segment ends with its last frame,
+// the next one starts with the next audio frame.
+func (c *CMAFConsumer) cut(dts uint32) {
+	if video := c.video; video != nil && video.current != nil {
+		video.current.duration = dts - video.current.start
+		video.complete()
+		video.current = &cmafSegment{start: dts}
+	}
+	if audio := c.audio; audio != nil && audio.current != nil {
+		if err := audio.muxer.FlushFragment(); err != nil {
+			log.Printf("CMAFConsumer (%s) drops an audio segment: %v", c.sourceName, err)
+		}
+		fragment := audio.take()
+		if len(fragment) > 0 {
+			audio.current.data = fragment
+			audio.current.duration = audio.lastDts + audio.frameDuration - audio.current.start
+			audio.complete()
+		}
+		audio.current = nil
+	}
+}
+
+// complete numbers the completed segments without gaps for the media sequence of the HLS playlists.
+func (t *cmafTrack) complete() {
+	t.current.seq = t.nextSeq
+	t.nextSeq++
+	t.longest = max(t.longest, t.current.duration)
+	t.segments = append(t.segments, t.current)
+	if extra := len(t.segments) - t.config.PlaylistSize - hlsRetainedSegments; extra > 0 {
+		t.segments = t.segments[extra:]
+	}
+}
+
+func (t *cmafTrack) elapsed(dts uint32) time.Duration {
+	return time.Duration(int64(dts)-int64(t.current.start)) * time.Millisecond
+}
+
+// take returns the fragment written by the muxer since the last call. The muxer writes
+// the ftyp and moov boxes before the first fragment, they are kept as the init segment.
+func (t *cmafTrack) take() []byte {
+	var fragment []byte
+	for data := t.out.data; len(data) >= 8; {
+		size := int(binary.BigEndian.Uint32(data))
+		if size < 8 || size > len(data) {
+			break
+		}
+		switch string(data[4:8]) {
+		case "ftyp", "moov":
+			t.init = append(t.init, data[:size]...)
+		case "moof", "mdat":
+			fragment = append(fragment, data[:size]...)
+		}
+		data = data[size:]
+	}
+	t.out.data = t.out.data[:0]
+	return fragment
+}
+
+// probeH264 takes the RFC 6381 codecs string and the resolution from the SPS of a keyframe.
+func (t *cmafTrack) probeH264(frame []byte) {
+	codec.SplitFrame(frame, func(nalu []byte) bool {
+		if codec.H264NaluTypeWithoutStartCode(nalu) != codec.H264_NAL_SPS || len(nalu) < 4 {
+			return true
+		}
+		t.codecs = fmt.Sprintf("avc1.%02x%02x%02x", nalu[1], nalu[2], nalu[3])
+		t.width, t.height = codec.GetH264Resolution(nalu)
+		return false
+	})
+}
+
+func (t *cmafTrack) probeAAC(frame []byte) error {
+	asc, err := codec.ConvertADTSToASC(frame)
+	if err != nil {
+		return err
+	}
+	t.codecs = fmt.Sprintf("mp4a.40.%d", asc.Audio_object_type)
+	t.sampleRate = codec.AACSampleIdxToSample(int(asc.Sample_freq_index))
+	if t.sampleRate > 0 {
+		t.frameDuration = uint32(1024 * 1000 / t.sampleRate)
+	}
+	return nil
+}
+
+// window returns the segments listed in the manifests.
+func (t *cmafTrack) window() []*cmafSegment {
+	if t == nil || t.init == nil {
+		return nil
+	}
+	return t.segments[max(len(t.segments)-t.config.PlaylistSize, 0):]
+}
+
+// bandwidth estimates the bits per second of the track from the listed segments.
+func (t *cmafTrack) bandwidth() int {
+	var size, duration int
+	for _, segment := range t.window() {
+		size += len(segment.data)
+		duration += int(segment.duration)
+	}
+	if duration == 0 {
+		return 0
+	}
+	return size * 8 * 1000 / duration
+}
+
+// tracks returns the tracks with segments to list.
+func (c *CMAFConsumer) tracks() []*cmafTrack {
+	var tracks []*cmafTrack
+	for _, track := range []*cmafTrack{c.video, c.audio} {
+		if len(track.window()) > 0 {
+			tracks = append(tracks, track)
+		}
+	}
+	return tracks
+}
+
+func (c *CMAFConsumer) track(name string) *cmafTrack {
+	switch name {
+	case "video":
+		return c.video
+	case "audio":
+		return c.audio
+	}
+	return nil
+}
+
+// MPD returns the dynamic DASH manifest with a segment timeline per track, the query is appended
+// to the segment URLs like in the HLS playlist. It is false until the first segments complete.
+func (c *CMAFConsumer) MPD(query string, now time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tracks := c.tracks()
+	if len(tracks) == 0 {
+		return "", false
+	}
+	if query != "" {
+		query = "?" + query
+	}
+
+	var maxDuration, depth uint32
+	for _, track := range tracks {
+		var trackDepth uint32
+		for _, segment := range track.window() {
+			trackDepth += segment.duration
+		}
+		maxDuration = max(maxDuration, track.longest)
+		depth = max(depth, trackDepth)
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&b, `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" profiles="urn:mpeg:dash:profile:isoff-live:2011,urn:mpeg:dash:profile:cmaf:2019" type="dynamic"`+
+		` availabilityStartTime="%s" publishTime="%s" minimumUpdatePeriod="%s" minBufferTime="%s" timeShiftBufferDepth="%s" suggestedPresentationDelay="%s" maxSegmentDuration="%s">`+"\n",
+		c.origin.UTC().Format(time.RFC3339Nano), now.UTC().Format(time.RFC3339), mpdDuration(c.config.SegmentDuration),
+		mpdDuration(c.config.SegmentDuration), mpdDuration(time.Duration(depth)*time.Millisecond),
+		mpdDuration(3*c.config.SegmentDuration), mpdDuration(time.Duration(maxDuration)*time.Millisecond))
+	b.WriteString(`  <Period id="0" start="PT0S">` + "\n")
+	for i, track := range tracks {
+		fmt.Fprintf(&b, `    <AdaptationSet id="%d" contentType="%s" mimeType="%s/mp4" segmentAlignment="true" startWithSAP="1">`+"\n", i, track.name, track.name)
+		fmt.Fprintf(&b, `      <Representation id="%s" codecs="%s" bandwidth="%d"`, track.name, track.codecs, track.bandwidth())
+		if track.width > 0 && track.height > 0 {
+			fmt.Fprintf(&b, ` width="%d" height="%d"`, track.width, track.height)
+		}
+		if track.sampleRate > 0 {
+			fmt.Fprintf(&b, ` audioSamplingRate="%d"`, track.sampleRate)
+		}
+		b.WriteString(">\n")
+		fmt.Fprintf(&b, `        <SegmentTemplate timescale="1000" initialization="%s" media="%s">`+"\n",
+			xmlEscaper.Replace("init-"+track.name+".mp4"+query), xmlEscaper.Replace(track.name+"-$Time$.m4s"+query))
+		b.WriteString("          <SegmentTimeline>\n")
+		for _, segment := range track.window() {
+			fmt.Fprintf(&b, `            <S t="%d" d="%d"/>`+"\n", segment.start, segment.duration)
+		}
+		b.WriteString("          </SegmentTimeline>\n        </SegmentTemplate>\n      </Representation>\n    </AdaptationSet>\n")
+	}
+	b.WriteString("  </Period>\n")
+	fmt.Fprintf(&b, `  <UTCTiming schemeIdUri="urn:mpeg:dash:utc:direct:2014" value="%s"/>`+"\n", now.UTC().Format(time.RFC3339))
+	b.WriteString("</MPD>\n")
+	return b.String(), true
+}
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func mpdDuration(d time.Duration) string {
+	return fmt.Sprintf("PT%.3fS", d.Seconds())
+}
+
+// HLSMaster returns the HLS multivariant playlist, in which the audio is a rendition of the video.
+func (c *CMAFConsumer) HLSMaster(query string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tracks := c.tracks()
+	if len(tracks) == 0 {
+		return "", false
+	}
+	if query != "" {
+		query = "?" + query
+	}
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-INDEPENDENT-SEGMENTS\n")
+	var bandwidth int
+	var codecs []string
+	for _, track := range tracks {
+		bandwidth += track.bandwidth()
+		codecs = append(codecs, track.codecs)
+	}
+	video, audio := len(c.video.window()) > 0, len(c.audio.window()) > 0
+	if video && audio {
+		fmt.Fprintf(&b, "#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"audio\",NAME=\"audio\",DEFAULT=YES,AUTOSELECT=YES,URI=\"audio.m3u8%s\"\n", query)
+	}
+	fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,CODECS=\"%s\"", bandwidth, strings.Join(codecs, ","))
+	if video && c.video.width > 0 && c.video.height > 0 {
+		fmt.Fprintf(&b, ",RESOLUTION=%dx%d", c.video.width, c.video.height)
+	}
+	if video && audio {
+		b.WriteString(",AUDIO=\"audio\"")
+	}
+	fmt.Fprintf(&b, "\n%s.m3u8%s\n", tracks[0].name, query)
+	return b.String(), true
+}
+
+// HLSPlaylist returns the live media playlist of the video or the audio track.
+func (c *CMAFConsumer) HLSPlaylist(name, query string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	track := c.track(name)
+	segments := track.window()
+	if len(segments) == 0 {
+		return "", false
+	}
+	if query != "" {
+		query = "?" + query
+	}
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:7\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", c.config.targetDuration(time.Duration(track.longest)*time.Millisecond))
+	fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", segments[0].seq)
+	fmt.Fprintf(&b, "#EXT-X-MAP:URI=\"init-%s.mp4%s\"\n", track.name, query)
+	for _, segment := range segments {
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n%s-%d.m4s%s\n", float64(segment.duration)/1000, track.name, segment.start, query)
+	}
+	return b.String(), true
+}
+
+// Init returns the initialization segment of the video or the audio track.
+func (c *CMAFConsumer) Init(name string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	track := c.track(name)
+	if track == nil || track.init == nil {
+		return nil, false
+	}
+	return track.init, true
+}
+
+// Segment returns a completed media segment of the video or the audio track by its start time.
+func (c *CMAFConsumer) Segment(name string, start uint32) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	track := c.track(name)
+	if track == nil {
+		return nil, false
+	}
+	for _, segment := range track.segments {
+		if segment.start == start {
+			return segment.data, true
+		}
+	}
+	return nil, false
+}
+
+func (c *CMAFConsumer) Id() string {
+	return c.id
+}
+
+func (c *CMAFConsumer) IsClosed() bool {
+	return c.quited.Load()
+}
+
+func (c *CMAFConsumer) Close() error {
+	c.quited.Store(true)
+	return nil
+}
+
+// fragmentBuffer collects the output of a fragmented muxer, which only asks for the current offset.
+type fragmentBuffer struct {
+	data []byte
+}
+
+func (b *fragmentBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	return len(p), nil
+}
+
+func (b *fragmentBuffer) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekCurrent {
+		return 0, errors.New("fragment buffer can't seek")
+	}
+	return int64(len(b.data)), nil
+}
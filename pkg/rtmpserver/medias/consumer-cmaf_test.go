@@ -0,0 +1,267 @@
+package medias
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/yapingcat/gomedia/go-codec"
+)
+
+// A 1080p SPS the stream switches to
+var testSPS1080 = []byte{0x67, 0x64, 0x00, 0x28, 0xac, 0x2c, 0xa4, 0x01, 0xe0, 0x08, 0x9f, 0x97, 0xff, 0x00, 0x01, 0x00, 0x01, 0x52, 0x02,
+	0x02, 0x02, 0x80, 0x00, 0x01, 0xf4, 0x80, 0x00, 0x75, 0x30, 0x70, 0x10, 0x00, 0x16, 0xe3, 0x60, 0x00, 0x08, 0x95, 0x45, 0xf8, 0xc7, 0x07,
+	0x68, 0x58, 0xb4, 0x48}
+
+// box returns the payload of the first box at the path, each element is the type of a box and the
+// bytes to skip at the start of its payload before its children.
+func box(data []byte, path ...string) []byte {
+	for _, boxType := range path {
+		found := false
+		for len(data) >= 8 {
+			size := int(binary.BigEndian.Uint32(data))
+			if size < 8 || size > len(data) {
+				return nil
+			}
+			if string(data[4:8]) == boxType {
+				data, found = data[8:size], true
+				break
+			}
+			data = data[size:]
+		}
+		if !found {
+			return nil
+		}
+		switch boxType {
+		case "stsd":
+			data = data[8:] // version, flags and entry count
+		case "avc1":
+			data = data[78:] // visual sample entry
+		}
+	}
+	return data
+}
+
+type fragmentSample struct {
+	dts      uint64
+	duration uint32
+	data     []byte
+}
+
+// segmentSamples parses the fragments of a media segment, one moof and mdat per GOP.
+func segmentSamples(t *testing.T, segment []byte) []fragmentSample {
+	t.Helper()
+	var samples []fragmentSample
+	for len(segment) >= 8 {
+		size := int(binary.BigEndian.Uint32(segment))
+		if size < 8 || size > len(segment) {
+			t.Fatalf("box of %d bytes in %d bytes", size, len(segment))
+		}
+		if string(segment[4:8]) == "moof" {
+			mdat := segment[size:]
+			if len(mdat) >= 8 {
+				mdat = mdat[:binary.BigEndian.Uint32(mdat)]
+			}
+			samples = append(samples, fragmentSamples(t, append(segment[:size:size], mdat...))...)
+		}
+		segment = segment[size:]
+	}
+	return samples
+}
+
+// fragmentSamples parses a moof and its mdat.
+func fragmentSamples(t *testing.T, segment []byte) []fragmentSample {
+	t.Helper()
+	tfhd := box(segment, "moof", "traf", "tfhd")
+	tfdt := box(segment, "moof", "traf", "tfdt")
+	trun := box(segment, "moof", "traf", "trun")
+	mdat := box(segment, "mdat")
+	if tfhd == nil || tfdt == nil || trun == nil || mdat == nil {
+		t.Fatal("the segment lacks a box of the track fragment")
+	}
+
+	read := func(b *[]byte, n int) uint64 {
+		v := uint64(0)
+		for _, c := range (*b)[:n] {
+			v = v<<8 | uint64(c)
+		}
+		*b = (*b)[n:]
+		return v
+	}
+	tfhdFlags := binary.BigEndian.Uint32(tfhd) & 0xffffff
+	fields := tfhd[8:]
+	for _, optional := range []uint32{0x1, 0x2} {
+		if tfhdFlags&optional != 0 {
+			read(&fields, map[uint32]int{0x1: 8, 0x2: 4}[optional])
+		}
+	}
+	var defaultDuration, defaultSize uint64
+	if tfhdFlags&0x8 != 0 {
+		defaultDuration = read(&fields, 4)
+	}
+	if tfhdFlags&0x10 != 0 {
+		defaultSize = read(&fields, 4)
+	}
+
+	dts := uint64(binary.BigEndian.Uint32(tfdt[4:]))
+	if tfdt[0] == 1 {
+		dts = binary.BigEndian.Uint64(tfdt[4:])
+	}
+
+	trunFlags := binary.BigEndian.Uint32(trun) & 0xffffff
+	fields = trun[4:]
+	count := int(read(&fields, 4))
+	for _, optional := range []uint32{0x1, 0x4} {
+		if trunFlags&optional != 0 {
+			read(&fields, 4)
+		}
+	}
+	samples := make([]fragmentSample, count)
+	for i := range samples {
+		duration, size := defaultDuration, defaultSize
+		if trunFlags&0x100 != 0 {
+			duration = read(&fields, 4)
+		}
+		if trunFlags&0x200 != 0 {
+			size = read(&fields, 4)
+		}
+		for _, optional := range []uint32{0x400, 0x800} {
+			if trunFlags&optional != 0 {
+				read(&fields, 4)
+			}
+		}
+		if int(size) > len(mdat) {
+			t.Fatalf("sample %d of %d bytes overflows the mdat", i, size)
+		}
+		samples[i] = fragmentSample{dts: dts, duration: uint32(duration), data: mdat[:size]}
+		mdat = mdat[size:]
+		dts += duration
+	}
+	return samples
+}
+
+// nalus splits a length prefixed sample.
+func nalus(t *testing.T, sample []byte) [][]byte {
+	t.Helper()
+	var units [][]byte
+	for len(sample) > 0 {
+		if len(sample) < 4 {
+			t.Fatalf("%d bytes left after the NAL units", len(sample))
+		}
+		size := int(binary.BigEndian.Uint32(sample))
+		if size == 0 || size > len(sample)-4 {
+			t.Fatalf("NAL unit of %d bytes in %d bytes", size, len(sample)-4)
+		}
+		units = append(units, sample[4:4+size])
+		sample = sample[4+size:]
+	}
+	return units
+}
+
+// aacFrames returns 48 kHz mono ADTS frames until the end (ms).
+func aacFrames(end uint32) []MediaFrame {
+	var frames []MediaFrame
+	for i := 0; ; i++ {
+		dts := uint32(i * 1024 * 1000 / 48000)
+		if dts >= end {
+			return frames
+		}
+		payload := []byte{0x21, 0x10, 0x04, 0x60, 0x8c, 0x1c}
+		size := 7 + len(payload)
+		header := []byte{0xff, 0xf1, 0x4c, 0x40 | byte(size>>11&3), byte(size >> 3), byte(size&7)<<5 | 0x1f, 0xfc}
+		frames = append(frames, MediaFrame{Cid: codec.CODECID_AUDIO_AAC, Pts: dts, Dts: dts, Frame: append(header, payload...)})
+	}
+}
+
+func TestCMAFSegments(t *testing.T) {
+	// Keyframes every second, the SPS changes at 5s
+	video := h264Frames(12000, func(dts uint32) bool { return dts%1000 == 0 })
+	for i := range video {
+		if video[i].IsIFrame && video[i].Dts >= 5000 {
+			video[i].Frame = annexB(testSPS1080, testPPS, []byte{0x65, 0x88, 0x84, 0x00, 0x33})
+		}
+	}
+	audio := aacFrames(12000)
+	var frames []MediaFrame
+	for len(video) > 0 || len(audio) > 0 {
+		if len(audio) == 0 || len(video) > 0 && video[0].Dts <= audio[0].Dts {
+			frames, video = append(frames, video[0]), video[1:]
+		} else {
+			frames, audio = append(frames, audio[0]), audio[1:]
+		}
+	}
+	originals := make([][]byte, len(frames))
+	for i := range frames {
+		originals[i] = bytes.Clone(frames[i].Frame)
+	}
+
+	c := NewCMAFConsumer("test", HLSConfig{})
+	c.Play(&MediaFrameBatch{Frames: frames})
+	for i := range frames {
+		if !bytes.Equal(frames[i].Frame, originals[i]) {
+			t.Fatalf("frame %d was modified by the muxer", i)
+		}
+	}
+
+	init, ok := c.Init("video")
+	if !ok {
+		t.Fatal("no video init segment")
+	}
+	avcC := box(init, "moov", "trak", "mdia", "minf", "stbl", "stsd", "avc1", "avcC")
+	if len(avcC) < 8 || avcC[5]&0x1f != 1 {
+		t.Fatalf("avcC %x, want a single SPS", avcC)
+	}
+	if size := int(binary.BigEndian.Uint16(avcC[6:])); !bytes.Equal(avcC[8:8+size], testSPS) {
+		t.Fatalf("the init segment has the SPS %x, want %x", avcC[8:8+size], testSPS)
+	}
+	if init, _ := c.Init("audio"); box(init, "moov", "trak", "mdia", "minf", "stbl", "stsd", "mp4a") == nil {
+		t.Fatal("no mp4a sample entry in the audio init segment")
+	}
+
+	for _, track := range []*cmafTrack{c.video, c.audio} {
+		if len(track.segments) < 4 {
+			t.Fatalf("%d %s segments, want at least 4", len(track.segments), track.name)
+		}
+		var next uint64
+		for i, segment := range track.segments {
+			samples := segmentSamples(t, segment.data)
+			// The last audio frame of a segment lasts as long as the one before, a millisecond off with 48 kHz
+			gap := int64(samples[0].dts) - int64(next)
+			if samples[0].dts != uint64(segment.start) || i > 0 && (gap < -1 || gap > 1 || track == c.video && gap != 0) {
+				t.Fatalf("%s segment %d starts at %d, want %d after %d", track.name, i, samples[0].dts, segment.start, next)
+			}
+			var duration uint32
+			for _, sample := range samples {
+				if sample.duration == 0 {
+					t.Fatalf("%s segment %d has a sample without duration", track.name, i)
+				}
+				duration += sample.duration
+			}
+			if duration != segment.duration {
+				t.Fatalf("%s segment %d: the samples last %dms, the playlist says %dms", track.name, i, duration, segment.duration)
+			}
+			next = samples[0].dts + uint64(duration)
+			if track == c.audio {
+				continue
+			}
+
+			// Every segment starts with a keyframe which carries the current SPS in band
+			var sps []byte
+			for _, nalu := range nalus(t, samples[0].data) {
+				if codec.H264NaluTypeWithoutStartCode(nalu) == codec.H264_NAL_SPS {
+					sps = nalu
+				}
+			}
+			want := testSPS
+			if segment.start >= 5000 {
+				want = testSPS1080
+			}
+			if !bytes.Equal(sps, want) {
+				t.Fatalf("video segment %d at %dms has the SPS %x, want %x", i, segment.start, sps, want)
+			}
+			for _, sample := range samples[1:] {
+				nalus(t, sample.data)
+			}
+		}
+	}
+}
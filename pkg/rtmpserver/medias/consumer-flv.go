@@ -0,0 +1,145 @@
+package medias
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/utils"
+	"github.com/yapingcat/gomedia/go-codec"
+	"github.com/yapingcat/gomedia/go-flv"
+)
+
+// FLVConsumer muxes the stream into FLV tags for an HTTP-FLV or WebSocket-FLV player.
+type FLVConsumer struct {
+	id         string
+	sourceName string
+
+	framesBatches []*MediaFrameBatch
+	framesMtx     sync.Mutex
+	frameCome     chan struct{}
+
+	quit   chan struct{}
+	quited atomic.Bool
+	die    sync.Once
+}
+
+func NewFLVConsumer(sourceName string) *FLVConsumer {
+	return &FLVConsumer{
+		id:         utils.GenId(),
+		sourceName: sourceName,
+		frameCome:  make(chan struct{}, 1),
+		quit:       make(chan struct{}),
+	}
+}
+
+func (c *FLVConsumer) Play(batch *MediaFrameBatch) {
+	c.framesMtx.Lock()
+	if len(c.framesBatches) >= 30 {
+		// A slow player loses the oldest batches rather than the server's memory
+		c.framesBatches = c.framesBatches[len(c.framesBatches)-15:]
+		log.Printf("FLVConsumer (%s) dropping old frame batches", c.sourceName)
+	}
+	c.framesBatches = append(c.framesBatches, batch)
+	c.framesMtx.Unlock()
+	select {
+	case c.frameCome <- struct{}{}:
+	default:
+	}
+}
+
+// Serve writes the FLV header and then the tags of every batch in a single write, until a write
+// fails or the consumer is closed. Video starts at a keyframe and the timestamps start at zero.
+func (c *FLVConsumer) Serve(write func(data []byte) error) error {
+	var buf bytes.Buffer
+	w := flv.CreateFlvWriter(&buf)
+	if err := w.WriteFlvHeader(); err != nil {
+		return err
+	}
+	if err := write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	keyed := false
+	started := false
+	var base uint32
+	for {
+		select {
+		case <-c.frameCome:
+			c.framesMtx.Lock()
+			batches := c.framesBatches
+			c.framesBatches = nil
+			c.framesMtx.Unlock()
+
+			buf.Reset()
+			for _, batch := range batches {
+				for _, frame := range batch.Frames {
+					if frame.IsVideo() && !keyed {
+						if !frame.IsIFrame {
+							continue
+						}
+						keyed = true
+					}
+					if !started {
+						started = true
+						base = frame.Dts
+					}
+					if err := writeFLVFrame(w, &frame, base); err != nil {
+						log.Printf("FLVConsumer (%s) skips a frame: %v", c.sourceName, err)
+					}
+				}
+			}
+			if buf.Len() == 0 {
+				continue
+			}
+			if err := write(buf.Bytes()); err != nil {
+				return err
+			}
+		case <-c.quit:
+			return nil
+		}
+	}
+}
+
+func writeFLVFrame(w *flv.FlvWriter, frame *MediaFrame, base uint32) (err error) {
+	defer func() {
+		// The writer panics when the codec of a track changes or a frame is malformed
+		if r := recover(); r != nil {
+			err = fmt.Errorf("flv writer: %v", r)
+		}
+	}()
+	pts, dts := max(int64(frame.Pts)-int64(base), 0), max(int64(frame.Dts)-int64(base), 0)
+	switch frame.Cid {
+	case codec.CODECID_VIDEO_H264:
+		return w.WriteH264(frame.Frame, uint32(pts), uint32(dts))
+	case codec.CODECID_VIDEO_H265:
+		return w.WriteH265(frame.Frame, uint32(pts), uint32(dts))
+	case codec.CODECID_AUDIO_AAC:
+		return w.WriteAAC(frame.Frame, uint32(pts), uint32(dts))
+	case codec.CODECID_AUDIO_G711A:
+		return w.WriteG711A(frame.Frame, uint32(pts), uint32(dts))
+	case codec.CODECID_AUDIO_G711U:
+		return w.WriteG711U(frame.Frame, uint32(pts), uint32(dts))
+	case codec.CODECID_AUDIO_MP3:
+		return w.WriteMp3(frame.Frame, uint32(pts), uint32(dts))
+	}
+	return nil
+}
+
+func (c *FLVConsumer) Id() string {
+	return c.id
+}
+
+func (c *FLVConsumer) Close() error {
+	c.quited.Store(true)
+	c.die.Do(func() {
+		close(c.quit)
+	})
+	return nil
+}
+
+func (c *FLVConsumer) IsClosed() bool {
+	return c.quited.Load()
+}
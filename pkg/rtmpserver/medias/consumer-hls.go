@@ -0,0 +1,221 @@
+package medias
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/utils"
+	"github.com/yapingcat/gomedia/go-codec"
+	"github.com/yapingcat/gomedia/go-mpeg2"
+)
+
+// HLSConfig sets up the HLS and CMAF segmenters.
+type HLSConfig struct {
+	SegmentDuration time.Duration // target, segments are cut at the next keyframe; defaults to 2s
+	PlaylistSize    int           // segments listed in the playlist, defaults to 6
+}
+
+func (c HLSConfig) withDefaults() HLSConfig {
+	if c.SegmentDuration <= 0 {
+		c.SegmentDuration = 2 * time.Second
+	}
+	if c.PlaylistSize <= 0 {
+		c.PlaylistSize = 6
+	}
+	return c
+}
+
+// targetDuration is the EXT-X-TARGETDURATION of a playlist, which RFC 8216 doesn't allow to change.
+// It is the segment duration, unless a keyframe came too late for a segment to end in time.
+func (c HLSConfig) targetDuration(longest time.Duration) int {
+	return int(math.Ceil(max(c.SegmentDuration, longest).Seconds()))
+}
+
+// hlsRetainedSegments are kept after they leave the playlist for players which just fetched it.
+const hlsRetainedSegments = 2
+
+// HLSConsumer segments the stream into MPEG-TS on keyframes and keeps a live playlist window in memory.
+type HLSConsumer struct {
+	id         string
+	sourceName string
+	config     HLSConfig
+
+	mu       sync.Mutex
+	segments []*hlsSegment // completed, oldest first
+	current  *hlsSegment
+	muxer    *mpeg2.TSMuxer
+	cids     []codec.CodecID
+	pids     map[codec.CodecID]uint16
+	pending  []*MediaFrame
+	keyed    bool // a video keyframe has been seen
+	hasVideo bool
+	nextSeq  uint64
+	longest  time.Duration // of the segments so far
+
+	quited atomic.Bool
+}
+
+type hlsSegment struct {
+	seq      uint64
+	startDts uint32
+	duration time.Duration
+	data     []byte
+}
+
+func (s *hlsSegment) elapsed(dts uint32) time.Duration {
+	return time.Duration(int64(dts)-int64(s.startDts)) * time.Millisecond
+}
+
+func NewHLSConsumer(sourceName string, config HLSConfig) *HLSConsumer {
+	return &HLSConsumer{
+		id:         utils.GenId(),
+		sourceName: sourceName,
+		config:     config.withDefaults(),
+	}
+}
+
+func (c *HLSConsumer) Play(batch *MediaFrameBatch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range batch.Frames {
+		c.writeFrame(&batch.Frames[i])
+	}
+}
+
+// writeFrame starts at a keyframe, or at once for audio only streams, and buffers the frames
+// until it knows the tracks like the writer of an SRT target.
+func (c *HLSConsumer) writeFrame(frame *MediaFrame) {
+	if frame.IsVideo() && !c.keyed {
+		if !frame.IsIFrame {
+			return
+		}
+		c.keyed = true
+	}
+	if c.pids != nil {
+		c.mux(frame)
+		return
+	}
+	c.pending = append(c.pending, frame)
+
+	var hasAudio bool
+	for _, f := range c.pending {
+		c.hasVideo = c.hasVideo || f.IsVideo()
+		hasAudio = hasAudio || !f.IsVideo()
+	}
+	probed := time.Duration(int64(frame.Dts)-int64(c.pending[0].Dts)) * time.Millisecond
+	if !(c.hasVideo && hasAudio) && probed < codecProbeTime {
+		return
+	}
+
+	c.pids = make(map[codec.CodecID]uint16)
+	for _, f := range c.pending {
+		switch f.Cid {
+		case codec.CODECID_VIDEO_H264, codec.CODECID_VIDEO_H265, codec.CODECID_AUDIO_AAC:
+			if _, ok := c.pids[f.Cid]; !ok {
+				c.pids[f.Cid] = 0
+				c.cids = append(c.cids, f.Cid)
+			}
+		}
+	}
+	pending := c.pending
+	c.pending = nil
+	for _, f := range pending {
+		c.mux(f)
+	}
+}
+
+func (c *HLSConsumer) mux(frame *MediaFrame) {
+	if _, ok := c.pids[frame.Cid]; !ok {
+		// A track which appeared after the tracks were probed
+		return
+	}
+	// Segments of video streams start with a keyframe, audio only streams are cut at any frame
+	boundary := frame.IsIFrame || !c.hasVideo
+	if c.current == nil || boundary && c.current.elapsed(frame.Dts) >= c.config.SegmentDuration {
+		c.cut(frame.Dts)
+	}
+	_ = c.muxer.Write(c.pids[frame.Cid], frame.Frame, uint64(frame.Pts), uint64(frame.Dts))
+}
+
+// cut completes the current segment and starts the next one with a new muxer,
+// so that every segment begins with the PAT and the PMT.
+func (c *HLSConsumer) cut(dts uint32) {
+	if c.current != nil {
+		c.current.duration = max(c.current.elapsed(dts), 0)
+		c.longest = max(c.longest, c.current.duration)
+		c.segments = append(c.segments, c.current)
+		if extra := len(c.segments) - c.config.PlaylistSize - hlsRetainedSegments; extra > 0 {
+			c.segments = c.segments[extra:]
+		}
+	}
+
+	segment := &hlsSegment{seq: c.nextSeq, startDts: dts}
+	c.nextSeq++
+	c.current = segment
+	c.muxer = mpeg2.NewTSMuxer()
+	c.muxer.OnPacket = func(pkg []byte) {
+		segment.data = append(segment.data, pkg...)
+	}
+	for _, cid := range c.cids {
+		switch cid {
+		case codec.CODECID_VIDEO_H264:
+			c.pids[cid] = c.muxer.AddStream(mpeg2.TS_STREAM_H264)
+		case codec.CODECID_VIDEO_H265:
+			c.pids[cid] = c.muxer.AddStream(mpeg2.TS_STREAM_H265)
+		case codec.CODECID_AUDIO_AAC:
+			c.pids[cid] = c.muxer.AddStream(mpeg2.TS_STREAM_AAC)
+		}
+	}
+}
+
+// Playlist returns the live media playlist, the query is appended to the segment URIs
+// so that they carry the signature of a signed playlist URL. It is false until the first segment completes.
+func (c *HLSConsumer) Playlist(query string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	segments := c.segments[max(len(c.segments)-c.config.PlaylistSize, 0):]
+	if len(segments) == 0 {
+		return "", false
+	}
+
+	if query != "" {
+		query = "?" + query
+	}
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", c.config.targetDuration(c.longest))
+	fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", segments[0].seq)
+	for _, segment := range segments {
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n%d.ts%s\n", segment.duration.Seconds(), segment.seq, query)
+	}
+	return b.String(), true
+}
+
+// Segment returns the MPEG-TS data of a completed segment.
+func (c *HLSConsumer) Segment(seq uint64) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, segment := range c.segments {
+		if segment.seq == seq {
+			return segment.data, true
+		}
+	}
+	return nil, false
+}
+
+func (c *HLSConsumer) Id() string {
+	return c.id
+}
+
+func (c *HLSConsumer) IsClosed() bool {
+	return c.quited.Load()
+}
+
+func (c *HLSConsumer) Close() error {
+	c.quited.Store(true)
+	return nil
+}
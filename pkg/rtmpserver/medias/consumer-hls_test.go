@@ -0,0 +1,119 @@
+package medias
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/yapingcat/gomedia/go-codec"
+)
+
+var (
+	testSPS, _ = base64.StdEncoding.DecodeString("Z0IAKeKQFAe2AtwEBAaQeJEV")
+	testPPS    = []byte{0x68, 0xce, 0x3c, 0x80}
+)
+
+func annexB(nalus ...[]byte) []byte {
+	var b []byte
+	for _, nalu := range nalus {
+		b = append(append(b, 0, 0, 0, 1), nalu...)
+	}
+	return b
+}
+
+// h264Frames returns 25 fps video frames until the end (ms) with keyframes where keyframe says.
+func h264Frames(end uint32, keyframe func(dts uint32) bool) []MediaFrame {
+	var frames []MediaFrame
+	for dts := uint32(0); dts < end; dts += 40 {
+		frame := MediaFrame{Cid: codec.CODECID_VIDEO_H264, Pts: dts, Dts: dts}
+		if keyframe(dts) {
+			frame.IsIFrame = true
+			frame.Frame = annexB(testSPS, testPPS, []byte{0x65, 0x88, 0x84, 0x00, 0x33})
+		} else {
+			frame.Frame = annexB([]byte{0x41, 0x9a, 0x00, 0x10})
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+var (
+	targetDurationTag = regexp.MustCompile(`#EXT-X-TARGETDURATION:(\d+)`)
+	extinfTag         = regexp.MustCompile(`#EXTINF:([\d.]+),`)
+)
+
+// checkTargetDuration plays the frames one by one and checks that the target duration of the
+// playlist only changes to cover a longer segment and that no segment is longer than it.
+func checkTargetDuration(t *testing.T, frames []MediaFrame, play func(*MediaFrame), playlist func() (string, bool)) []int {
+	t.Helper()
+	var targets []int
+	for i := range frames {
+		play(&frames[i])
+		m3u8, ok := playlist()
+		if !ok {
+			continue
+		}
+		target, _ := strconv.Atoi(targetDurationTag.FindStringSubmatch(m3u8)[1])
+		if len(targets) == 0 || targets[len(targets)-1] != target {
+			targets = append(targets, target)
+		}
+		for _, extinf := range extinfTag.FindAllStringSubmatch(m3u8, -1) {
+			if duration, _ := strconv.ParseFloat(extinf[1], 64); int(duration+0.5) > target {
+				t.Fatalf("a %ss segment is longer than the target duration %d", extinf[1], target)
+			}
+		}
+	}
+	return targets
+}
+
+// The GOP from 6s to 11s is too long for a 2s segment, the target stays 5s after that segment left the playlist.
+func longGOP(dts uint32) bool {
+	return dts%1000 == 0 && (dts <= 6000 || dts >= 11000)
+}
+
+func TestHLSTargetDuration(t *testing.T) {
+	c := NewHLSConsumer("test", HLSConfig{})
+	targets := checkTargetDuration(t, h264Frames(30000, longGOP),
+		func(frame *MediaFrame) { c.Play(&MediaFrameBatch{Frames: []MediaFrame{*frame}}) },
+		func() (string, bool) { return c.Playlist("") })
+	if len(targets) != 2 || targets[0] != 2 || targets[1] != 5 {
+		t.Fatalf("target durations %v, want [2 5]", targets)
+	}
+}
+
+func TestCMAFTargetDuration(t *testing.T) {
+	c := NewCMAFConsumer("test", HLSConfig{})
+	targets := checkTargetDuration(t, h264Frames(30000, longGOP),
+		func(frame *MediaFrame) { c.Play(&MediaFrameBatch{Frames: []MediaFrame{*frame}}) },
+		func() (string, bool) { return c.HLSPlaylist("video", "") })
+	if len(targets) != 2 || targets[0] != 2 || targets[1] != 5 {
+		t.Fatalf("target durations %v, want [2 5]", targets)
+	}
+}
+
+func TestHLSConfig(t *testing.T) {
+	config := HLSConfig{SegmentDuration: time.Second, PlaylistSize: 3}
+	frames := h264Frames(10000, func(dts uint32) bool { return dts%1000 == 0 })
+	hls := NewHLSConsumer("test", config)
+	cmaf := NewCMAFConsumer("test", config)
+	hls.Play(&MediaFrameBatch{Frames: frames})
+	cmaf.Play(&MediaFrameBatch{Frames: frames})
+
+	for name, playlist := range map[string]func() (string, bool){
+		"hls":  func() (string, bool) { return hls.Playlist("") },
+		"cmaf": func() (string, bool) { return cmaf.HLSPlaylist("video", "") },
+	} {
+		m3u8, ok := playlist()
+		if !ok {
+			t.Fatalf("%s: no playlist", name)
+		}
+		if target := targetDurationTag.FindStringSubmatch(m3u8)[1]; target != "1" {
+			t.Errorf("%s: target duration %s, want 1", name, target)
+		}
+		if segments := len(extinfTag.FindAllString(m3u8, -1)); segments != 3 {
+			t.Errorf("%s: %d segments in the playlist, want 3", name, segments)
+		}
+	}
+}
@@ -1,30 +1,39 @@
 package medias
 
 import (
-	"crypto/tls"
+	"context"
 	"errors"
 	"log"
 	"net"
 	"net/url"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/events"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/utils"
 	"github.com/yapingcat/gomedia/go-rtmp"
 )
 
 type PushConsumer struct {
-	id     string
-	client *rtmp.RtmpClient
-	conn   net.Conn
-	url    *url.URL
+	id      string
+	client  *rtmp.RtmpClient
+	url     *url.URL
+	options api.PushTargetOptions
+	config  PushConfig
 
-	isReady atomic.Bool
-	onReady chan struct{}
+	targetStatus
 
+	isReady  atomic.Bool
+	wasReady atomic.Bool // has been ready since the last connection attempt
+	onReady  chan struct{}
+
+	conn    net.Conn
+	connMtx sync.Mutex
+
+	ctx    context.Context // cancelled on Close, aborts dialing
+	cancel context.CancelFunc
 	quit   chan struct{}
 	quited atomic.Bool
 	die    sync.Once
@@ -36,18 +45,25 @@ type PushConsumer struct {
 	sourceName string
 }
 
-func NewPushConsumer(rtmpUrl *api.PushTargetUrl, sourceName string) (*PushConsumer, error) {
+func NewPushConsumer(rtmpUrl *api.PushTargetUrl, options api.PushTargetOptions, sourceName string, config PushConfig) (*PushConsumer, error) {
+	ctx, cancel := context.WithCancel(context.Background())
 	consumer := PushConsumer{
 		id:            utils.GenId(),
 		url:           (*url.URL)(rtmpUrl),
+		options:       options,
+		config:        config,
+		targetStatus:  newTargetStatus(),
 		frameCome:     make(chan struct{}, 1),
 		onReady:       make(chan struct{}),
+		ctx:           ctx,
+		cancel:        cancel,
 		quit:          make(chan struct{}),
 		framesBatches: make([]*MediaFrameBatch, 0, 90), // 24 + 8 * 90 = 744 bytes (64 bit)
 		sourceName:    sourceName,
 	}
 
 	go func() {
+		failures := 0
 		for {
 			if consumer.connect() {
 				break
@@ -55,6 +71,20 @@ func NewPushConsumer(rtmpUrl *api.PushTargetUrl, sourceName string) (*PushConsum
 			if consumer.quited.Load() {
 				break
 			}
+			if consumer.wasReady.Swap(false) {
+				failures = 0
+			}
+			failures++
+			if consumer.config.gaveUp(failures) {
+				log.Printf("RTMPPushClient (%s) from %s gave up after %d attempts", consumer.url, consumer.sourceName, failures)
+				consumer.setState(PushGaveUp, nil)
+				consumer.publishEvent(events.TargetGaveUp, nil)
+				break
+			}
+			select {
+			case <-time.After(2 * time.Second):
+			case <-consumer.quit:
+			}
 		}
 		log.Printf("RTMPPushClient (%s) from %s exited", consumer.url, consumer.sourceName)
 	}()
@@ -73,55 +103,28 @@ func (cn *PushConsumer) connect() bool {
 		return true
 	}
 	log.Printf("RTMPPushClient (%s) from %s failed: %v", cn.url, cn.sourceName, err)
-	time.Sleep(2 * time.Second)
+	cn.setState(PushFailed, err)
+	cn.publishEvent(events.TargetFailed, err)
 	return false
 }
 
+func (cn *PushConsumer) publishEvent(eventType events.Type, err error) {
+	publishTargetEvent(eventType, cn.sourceName, cn.url, err)
+}
+
 func (cn *PushConsumer) connection() error {
-	host := cn.url.Host
-	if cn.url.Port() == "" {
-		if strings.HasPrefix(cn.url.Scheme, "rtmps") {
-			host += ":443"
-		} else {
-			host += ":1935"
-		}
-	}
-	var err error
-	var c net.Conn
-	if strings.HasPrefix(cn.url.Scheme, "rtmps") {
-		conf := &tls.Config{
-			ServerName:         cn.url.Hostname(),
-			InsecureSkipVerify: true,
-		}
-		c, err = tls.Dial("tcp", host, conf)
-	} else {
-		c, err = net.Dial("tcp4", host)
-	}
+	c, err := dialRtmp(cn.ctx, cn.url, cn.options, cn.config)
 	if err != nil {
 		return err
 	}
+	if !cn.attach(c) {
+		return net.ErrClosed
+	}
 
-	cn.conn = c
-
-	cn.client = rtmp.NewRtmpClient(rtmp.WithComplexHandshake(), rtmp.WithEnablePublish())
-
-	cn.client.OnStateChange(func(newState rtmp.RtmpState) {
-		if newState == rtmp.STATE_RTMP_PUBLISH_START {
-			log.Printf("RTMPPushClient (%s) ready to publish", cn.url)
-			cn.isReady.Store(true)
-			cn.framesMtx.Lock()
-			cn.framesBatches = nil
-			cn.framesMtx.Unlock()
-			cn.onReady <- struct{}{}
-		}
-	})
+	cn.client = newRtmpClient(c, cn.url, cn.options, cn.onStateChange, rtmp.WithEnablePublish())
 	cn.client.OnError(func(code, describe string) {
 		log.Printf("RTMPPushClient (%s) client error: %s", cn.url, describe)
 	})
-	cn.client.SetOutput(func(data []byte) error {
-		_, err := c.Write(data)
-		return err
-	})
 
 	go func() {
 		<-cn.onReady
@@ -129,7 +132,33 @@ func (cn *PushConsumer) connection() error {
 		log.Printf("Stopped (%s) ...", cn.url)
 	}()
 
-	return cn.socketRead()
+	return cn.socketRead(c)
+}
+
+// attach keeps the connection for Close, unless the consumer was closed while dialing.
+func (cn *PushConsumer) attach(c net.Conn) bool {
+	cn.connMtx.Lock()
+	defer cn.connMtx.Unlock()
+	if cn.quited.Load() {
+		_ = c.Close()
+		return false
+	}
+	cn.conn = c
+	return true
+}
+
+func (cn *PushConsumer) onStateChange(newState rtmp.RtmpState) {
+	if newState == rtmp.STATE_RTMP_PUBLISH_START {
+		log.Printf("RTMPPushClient (%s) ready to publish", cn.url)
+		cn.setState(PushConnected, nil)
+		cn.isReady.Store(true)
+		cn.wasReady.Store(true)
+		cn.publishEvent(events.TargetConnected, nil)
+		cn.framesMtx.Lock()
+		cn.framesBatches = nil
+		cn.framesMtx.Unlock()
+		cn.onReady <- struct{}{}
+	}
 }
 
 func (cn *PushConsumer) Play(frame *MediaFrameBatch) {
@@ -157,11 +186,16 @@ func (cn *PushConsumer) Target() string {
 }
 
 func (cn *PushConsumer) Close() error {
-	cn.quited.Store(true)
 	var err error
 	cn.die.Do(func() {
+		cn.connMtx.Lock()
+		cn.quited.Store(true)
 		close(cn.quit)
-		err = cn.conn.Close()
+		cn.cancel()
+		if cn.conn != nil {
+			err = cn.conn.Close()
+		}
+		cn.connMtx.Unlock()
 	})
 	log.Printf("Closed RTMPPushConsumer %s", cn.url.String())
 	return err
@@ -171,12 +205,12 @@ func (cn *PushConsumer) IsClosed() bool {
 	return cn.quited.Load()
 }
 
-func (cn *PushConsumer) socketRead() (err error) {
+func (cn *PushConsumer) socketRead(c net.Conn) (err error) {
 	cn.client.Start(cn.url.String())
 	buf := make([]byte, 65536)
 	n := 0
 	for {
-		n, err = cn.conn.Read(buf)
+		n, err = c.Read(buf)
 		if err != nil && errors.Is(err, net.ErrClosed) {
 			break
 		} else if err != nil {
@@ -0,0 +1,100 @@
+package medias
+
+import (
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/events"
+)
+
+func TestPushConsumerCloseWhileDialing(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// The server never answers the TLS handshake
+	target, _ := url.Parse("rtmps://" + l.Addr().String() + "/live/key")
+	consumer, err := NewPushConsumer((*api.PushTargetUrl)(target), api.PushTargetOptions{}, "test", PushConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = l.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	c, err := l.Accept()
+	if err != nil {
+		t.Fatalf("the consumer doesn't dial: %v", err)
+	}
+	defer c.Close()
+	// Wait for the ClientHello, the handshake is in progress
+	if _, err := c.Read(make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = consumer.Close()
+	_ = c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 4096)
+	for {
+		if _, err := c.Read(buf); err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				t.Fatal("the connection stays open after Close")
+			}
+			break
+		}
+	}
+}
+
+func TestPushConfigGaveUp(t *testing.T) {
+	tests := []struct {
+		attempts, failures int
+		want               bool
+	}{
+		{0, DefaultMaxConnectAttempts - 1, false},
+		{0, DefaultMaxConnectAttempts, true},
+		{3, 2, false},
+		{3, 3, true},
+		{-1, 1000, false},
+	}
+	for _, tt := range tests {
+		if got := (PushConfig{MaxConnectAttempts: tt.attempts}).gaveUp(tt.failures); got != tt.want {
+			t.Errorf("MaxConnectAttempts %d after %d failures: gave up %v, want %v", tt.attempts, tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestPushConsumerGivesUp(t *testing.T) {
+	gaveUp := make(chan events.Event, 1)
+	events.Subscribe(func(event events.Event) {
+		if event.Stream == "gives-up" && event.Type == events.TargetGaveUp {
+			gaveUp <- event
+		}
+	})
+
+	// Nothing listens on the port once the listener is closed
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = l.Close()
+	target, _ := url.Parse("rtmp://" + l.Addr().String() + "/live/key")
+	consumer, err := NewPushConsumer((*api.PushTargetUrl)(target), api.PushTargetOptions{}, "gives-up", PushConfig{MaxConnectAttempts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+
+	select {
+	case event := <-gaveUp:
+		if event.Target != "rtmp://"+l.Addr().String()+"/live/****" {
+			t.Errorf("the event has the target %q", event.Target)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no gave up event")
+	}
+	if state := consumer.Status().State; state != PushGaveUp {
+		t.Fatalf("state %s, want %s", state, PushGaveUp)
+	}
+}
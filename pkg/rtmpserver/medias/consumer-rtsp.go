@@ -0,0 +1,459 @@
+package medias
+
+import (
+	"log"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/utils"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/yapingcat/gomedia/go-codec"
+)
+
+const (
+	rtpPayloadSize       = 1400
+	rtcpReportInterval   = 5000 // ms of media between sender reports
+	rtspMaxQueuedFrames  = 600
+	rtspAudioJitterRatio = 200 // AAC timestamps closer than 5 ms to the expected one are snapped to it
+)
+
+// RTSPConsumer packetizes the stream into RTP for an RTSP player. It starts at a keyframe
+// and probes the tracks like the HLS segmenter, only H.264, H.265 and AAC tracks are sent.
+type RTSPConsumer struct {
+	id         string
+	sourceName string
+
+	mu        sync.Mutex
+	pending   []*MediaFrame
+	keyed     bool
+	hasVideo  bool
+	tracks    []*RTSPTrack
+	probed    chan struct{}
+	frames    []*MediaFrame // probed, waiting to be sent
+	resync    bool          // frames were dropped, the video waits for a keyframe
+	frameCome chan struct{}
+
+	quit   chan struct{}
+	quited atomic.Bool
+	die    sync.Once
+}
+
+// RTSPTrack is a track of the stream with the parameters of its SDP media description.
+type RTSPTrack struct {
+	Cid           codec.CodecID
+	PayloadType   uint8
+	ClockRate     uint32
+	Channels      int
+	VPS, SPS, PPS []byte // of a video track, without start codes
+	ASC           []byte // AudioSpecificConfig of an AAC track
+
+	ssrc           uint32
+	firstSeq       uint16
+	firstTimestamp uint32
+	seq            uint16
+	h264           codecs.H264Payloader
+
+	packets, octets uint32
+	reported        bool
+	lastReport      int64 // ms of media
+
+	audioNext    uint32 // RTP timestamp of the next AAC frame
+	audioStarted bool
+}
+
+func NewRTSPConsumer(sourceName string) *RTSPConsumer {
+	return &RTSPConsumer{
+		id:         utils.GenId(),
+		sourceName: sourceName,
+		probed:     make(chan struct{}),
+		frameCome:  make(chan struct{}, 1),
+		quit:       make(chan struct{}),
+	}
+}
+
+func (c *RTSPConsumer) Play(batch *MediaFrameBatch) {
+	c.mu.Lock()
+	for i := range batch.Frames {
+		c.writeFrame(&batch.Frames[i])
+	}
+	if len(c.frames) > rtspMaxQueuedFrames {
+		// A slow player loses the oldest frames rather than the server's memory
+		c.frames = c.frames[len(c.frames)-rtspMaxQueuedFrames/2:]
+		c.resync = true
+		log.Printf("RTSPConsumer (%s) dropping old frames", c.sourceName)
+	}
+	queued := len(c.frames) > 0
+	c.mu.Unlock()
+	if queued {
+		select {
+		case c.frameCome <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// writeFrame starts at a keyframe, or at once for audio only streams, and buffers the frames
+// until it knows the tracks.
+func (c *RTSPConsumer) writeFrame(frame *MediaFrame) {
+	if frame.IsVideo() && !c.keyed {
+		if !frame.IsIFrame {
+			return
+		}
+		c.keyed = true
+	}
+	if c.tracks != nil {
+		c.frames = append(c.frames, frame)
+		return
+	}
+	c.pending = append(c.pending, frame)
+
+	var hasAudio bool
+	for _, f := range c.pending {
+		c.hasVideo = c.hasVideo || f.IsVideo()
+		hasAudio = hasAudio || !f.IsVideo()
+	}
+	probed := time.Duration(int64(frame.Dts)-int64(c.pending[0].Dts)) * time.Millisecond
+	if !(c.hasVideo && hasAudio) && probed < codecProbeTime {
+		return
+	}
+
+	var video, audio *RTSPTrack
+	for _, f := range c.pending {
+		switch {
+		case f.IsVideo() && video == nil && f.IsIFrame:
+			video = probeRTSPVideo(f)
+		case f.Cid == codec.CODECID_AUDIO_AAC && audio == nil:
+			audio = probeRTSPAudio(f)
+		}
+	}
+	c.tracks = make([]*RTSPTrack, 0, 2)
+	if video != nil {
+		c.tracks = append(c.tracks, video)
+	} else if c.hasVideo {
+		log.Printf("RTSPConsumer (%s) sends no video: only H.264 and H.265 with parameter sets are supported", c.sourceName)
+	}
+	if audio != nil {
+		c.tracks = append(c.tracks, audio)
+	} else if hasAudio {
+		log.Printf("RTSPConsumer (%s) sends no audio: only AAC is supported", c.sourceName)
+	}
+	c.frames = append(c.frames, c.pending...)
+	c.pending = nil
+	close(c.probed)
+}
+
+func newRTSPTrack(cid codec.CodecID, payloadType uint8, clockRate uint32) *RTSPTrack {
+	t := &RTSPTrack{
+		Cid:            cid,
+		PayloadType:    payloadType,
+		ClockRate:      clockRate,
+		ssrc:           rand.Uint32(),
+		firstSeq:       uint16(rand.Uint32()),
+		firstTimestamp: rand.Uint32(),
+	}
+	t.seq = t.firstSeq
+	return t
+}
+
+// probeRTSPVideo takes the parameter sets of a keyframe, they are required for the SDP.
+func probeRTSPVideo(frame *MediaFrame) *RTSPTrack {
+	t := newRTSPTrack(frame.Cid, 96, 90000)
+	codec.SplitFrame(frame.Frame, func(nalu []byte) bool {
+		if len(nalu) == 0 {
+			return true
+		}
+		switch frame.Cid {
+		case codec.CODECID_VIDEO_H264:
+			switch codec.H264NaluTypeWithoutStartCode(nalu) {
+			case codec.H264_NAL_SPS:
+				t.SPS = append([]byte(nil), nalu...)
+			case codec.H264_NAL_PPS:
+				t.PPS = append([]byte(nil), nalu...)
+			}
+		case codec.CODECID_VIDEO_H265:
+			switch codec.H265NaluTypeWithoutStartCode(nalu) {
+			case codec.H265_NAL_VPS:
+				t.VPS = append([]byte(nil), nalu...)
+			case codec.H265_NAL_SPS:
+				t.SPS = append([]byte(nil), nalu...)
+			case codec.H265_NAL_PPS:
+				t.PPS = append([]byte(nil), nalu...)
+			}
+		}
+		return true
+	})
+	switch {
+	case frame.Cid == codec.CODECID_VIDEO_H264 && len(t.SPS) >= 4 && t.PPS != nil:
+		return t
+	case frame.Cid == codec.CODECID_VIDEO_H265 && t.VPS != nil && t.SPS != nil && t.PPS != nil:
+		return t
+	}
+	return nil
+}
+
+func probeRTSPAudio(frame *MediaFrame) *RTSPTrack {
+	asc, err := codec.ConvertADTSToASC(frame.Frame)
+	if err != nil {
+		return nil
+	}
+	sampleRate := codec.AACSampleIdxToSample(int(asc.Sample_freq_index))
+	if sampleRate <= 0 {
+		return nil
+	}
+	t := newRTSPTrack(frame.Cid, 97, uint32(sampleRate))
+	t.Channels = int(asc.Channel_configuration)
+	t.ASC = asc.Encode()
+	return t
+}
+
+// Tracks waits until the tracks are probed, it returns nil if they aren't within the timeout
+// or the consumer is closed.
+func (c *RTSPConsumer) Tracks(timeout time.Duration) []*RTSPTrack {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-c.probed:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.tracks
+	case <-timer.C:
+	case <-c.quit:
+	}
+	return nil
+}
+
+// Serve sends the RTP packets and the RTCP sender reports of the given tracks until a write fails
+// or the consumer is closed. The track passed to write is an index into tracks.
+func (c *RTSPConsumer) Serve(tracks []*RTSPTrack, write func(track int, rtcp bool, packet []byte) error) error {
+	keyed := false
+	started := false
+	var base uint32
+	var wallBase time.Time
+	for {
+		select {
+		case <-c.frameCome:
+			c.mu.Lock()
+			frames := c.frames
+			c.frames = nil
+			if c.resync {
+				c.resync = false
+				keyed = false
+			}
+			c.mu.Unlock()
+
+			for _, frame := range frames {
+				i := -1
+				for j, t := range tracks {
+					if t.Cid == frame.Cid {
+						i = j
+					}
+				}
+				if i < 0 {
+					continue
+				}
+				if frame.IsVideo() && !keyed {
+					if !frame.IsIFrame {
+						continue
+					}
+					keyed = true
+				}
+				if !started {
+					started = true
+					base = frame.Dts
+					wallBase = time.Now()
+				}
+
+				t := tracks[i]
+				packets := t.packetize(frame, base)
+				if len(packets) == 0 {
+					continue
+				}
+				media := int64(frame.Pts) - int64(base)
+				if !t.reported || media-t.lastReport >= rtcpReportInterval {
+					t.reported = true
+					t.lastReport = media
+					if err := write(i, true, t.senderReport(wallBase.Add(time.Duration(media)*time.Millisecond), t.timestamp(frame.Pts, base))); err != nil {
+						return err
+					}
+				}
+				for _, packet := range packets {
+					if err := write(i, false, packet); err != nil {
+						return err
+					}
+				}
+			}
+		case <-c.quit:
+			return nil
+		}
+	}
+}
+
+// Start returns the sequence number and the timestamp of the first packet for the RTP-Info header.
+func (t *RTSPTrack) Start() (uint16, uint32) {
+	return t.firstSeq, t.firstTimestamp
+}
+
+// timestamp converts ms since the first frame to the RTP clock.
+func (t *RTSPTrack) timestamp(pts, base uint32) uint32 {
+	return t.firstTimestamp + uint32((int64(pts)-int64(base))*int64(t.ClockRate)/1000)
+}
+
+func (t *RTSPTrack) packetize(frame *MediaFrame, base uint32) (packets [][]byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("WARNING! RTSP packetizer skips a malformed frame: %v", r)
+			packets = nil
+		}
+	}()
+	timestamp := t.timestamp(frame.Pts, base)
+	switch t.Cid {
+	case codec.CODECID_VIDEO_H264:
+		packets = t.marshal(t.h264.Payload(rtpPayloadSize, frame.Frame), timestamp, true)
+	case codec.CODECID_VIDEO_H265:
+		packets = t.marshal(h265Payloads(frame.Frame), timestamp, true)
+	case codec.CODECID_AUDIO_AAC:
+		tolerance := int32(t.ClockRate / rtspAudioJitterRatio)
+		splitADTS(frame.Frame, func(raw []byte) {
+			// Millisecond timestamps are rounded, consecutive frames keep the exact sample count
+			if drift := int32(timestamp - t.audioNext); t.audioStarted && drift > -tolerance && drift < tolerance {
+				timestamp = t.audioNext
+			}
+			t.audioStarted = true
+			packets = append(packets, t.marshal(aacPayloads(raw), timestamp, false)...)
+			timestamp += aacFrameSamples
+			t.audioNext = timestamp
+		})
+	}
+	return packets
+}
+
+// marshal makes RTP packets of the payloads of a frame, the marker is set on the last one
+// of a video frame and on every packet of audio.
+func (t *RTSPTrack) marshal(payloads [][]byte, timestamp uint32, video bool) [][]byte {
+	packets := make([][]byte, 0, len(payloads))
+	for i, payload := range payloads {
+		packet := rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         !video || i == len(payloads)-1,
+				PayloadType:    t.PayloadType,
+				SequenceNumber: t.seq,
+				Timestamp:      timestamp,
+				SSRC:           t.ssrc,
+			},
+			Payload: payload,
+		}
+		data, err := packet.Marshal()
+		if err != nil {
+			continue
+		}
+		t.seq++
+		t.packets++
+		t.octets += uint32(len(payload))
+		packets = append(packets, data)
+	}
+	return packets
+}
+
+func (t *RTSPTrack) senderReport(wallClock time.Time, timestamp uint32) []byte {
+	report := rtcp.SenderReport{
+		SSRC:        t.ssrc,
+		NTPTime:     ntpTime(wallClock),
+		RTPTime:     timestamp,
+		PacketCount: t.packets,
+		OctetCount:  t.octets,
+	}
+	data, _ := report.Marshal()
+	return data
+}
+
+// ntpTime converts the time to the 64-bit NTP format of the sender reports.
+func ntpTime(t time.Time) uint64 {
+	const ntpEpochOffset = 2208988800 // seconds from 1900 to 1970
+	seconds := uint64(t.Unix()) + ntpEpochOffset
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return seconds<<32 | fraction
+}
+
+// h265Payloads makes single NAL unit packets and the fragmentation units of RFC 7798
+// of an Annex-B access unit.
+func h265Payloads(frame []byte) [][]byte {
+	var payloads [][]byte
+	codec.SplitFrame(frame, func(nalu []byte) bool {
+		if len(nalu) < 3 || codec.H265NaluTypeWithoutStartCode(nalu) == codec.H265_NAL_AUD {
+			return true
+		}
+		if len(nalu) <= rtpPayloadSize {
+			payloads = append(payloads, nalu)
+			return true
+		}
+		nalType := byte(codec.H265NaluTypeWithoutStartCode(nalu))
+		header := []byte{nalu[0]&0x81 | 49<<1, nalu[1]}
+		data := nalu[2:]
+		for first := true; len(data) > 0; first = false {
+			n := min(len(data), rtpPayloadSize-3)
+			fuHeader := nalType
+			if first {
+				fuHeader |= 0x80
+			}
+			if n == len(data) {
+				fuHeader |= 0x40
+			}
+			payload := append(append(header[:2:2], fuHeader), data[:n]...)
+			payloads = append(payloads, payload)
+			data = data[n:]
+		}
+		return true
+	})
+	return payloads
+}
+
+// aacPayloads makes the AAC-hbr packets of RFC 3640 with a single access unit, which is
+// fragmented if it doesn't fit. Every fragment carries the size of the whole access unit.
+func aacPayloads(raw []byte) [][]byte {
+	var payloads [][]byte
+	data := raw
+	for len(data) > 0 || payloads == nil {
+		n := min(len(data), rtpPayloadSize-4)
+		payload := []byte{0x00, 0x10, byte(len(raw) >> 5), byte(len(raw) << 3)}
+		payloads = append(payloads, append(payload, data[:n]...))
+		data = data[n:]
+	}
+	return payloads
+}
+
+// splitADTS calls onFrame with the raw data of every ADTS frame.
+func splitADTS(frames []byte, onFrame func(raw []byte)) {
+	for len(frames) >= 7 && frames[0] == 0xff && frames[1]&0xf0 == 0xf0 {
+		length := int(frames[3]&0x03)<<11 | int(frames[4])<<3 | int(frames[5])>>5
+		header := 7
+		if frames[1]&0x01 == 0 {
+			header = 9 // with CRC
+		}
+		if length <= header || length > len(frames) {
+			return
+		}
+		onFrame(frames[header:length])
+		frames = frames[length:]
+	}
+}
+
+func (c *RTSPConsumer) Id() string {
+	return c.id
+}
+
+func (c *RTSPConsumer) Close() error {
+	c.quited.Store(true)
+	c.die.Do(func() {
+		close(c.quit)
+	})
+	return nil
+}
+
+func (c *RTSPConsumer) IsClosed() bool {
+	return c.quited.Load()
+}
@@ -0,0 +1,311 @@
+package medias
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/events"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/srt"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/utils"
+	"github.com/yapingcat/gomedia/go-codec"
+	"github.com/yapingcat/gomedia/go-mpeg2"
+)
+
+// codecProbeTime is how long the frames are buffered to find the tracks of the stream,
+// the PMT can't change once it has been sent.
+const codecProbeTime = time.Second
+
+// SrtPushConsumer sends the stream as MPEG-TS to an srt:// target.
+type SrtPushConsumer struct {
+	id      string
+	url     *url.URL
+	options api.PushTargetOptions
+	config  PushConfig
+
+	targetStatus
+
+	conn    *srt.Conn
+	connMtx sync.Mutex
+
+	quit   chan struct{}
+	quited atomic.Bool
+	die    sync.Once
+
+	framesBatches []*MediaFrameBatch
+	framesMtx     sync.Mutex
+	frameCome     chan struct{}
+
+	sourceName string
+}
+
+// NewTargetConsumer creates the push consumer of the URL scheme.
+func NewTargetConsumer(target *api.PushTargetUrl, options api.PushTargetOptions, sourceName string, config PushConfig) (MediaPushConsumer, error) {
+	if target.Scheme == "srt" {
+		return NewSrtPushConsumer(target, options, sourceName, config)
+	}
+	return NewPushConsumer(target, options, sourceName, config)
+}
+
+func NewSrtPushConsumer(srtUrl *api.PushTargetUrl, options api.PushTargetOptions, sourceName string, config PushConfig) (*SrtPushConsumer, error) {
+	if srtUrl.Host == "" || (*url.URL)(srtUrl).Port() == "" {
+		return nil, errors.New("srt target must have a host and a port")
+	}
+	consumer := &SrtPushConsumer{
+		id:           utils.GenId(),
+		url:          (*url.URL)(srtUrl),
+		options:      options,
+		config:       config,
+		targetStatus: newTargetStatus(),
+		quit:         make(chan struct{}),
+		frameCome:    make(chan struct{}, 1),
+		sourceName:   sourceName,
+	}
+
+	go func() {
+		failures := 0
+		for {
+			connected, err := consumer.connection()
+			if consumer.quited.Load() {
+				break
+			}
+			if connected {
+				failures = 0
+			}
+			failures++
+			log.Printf("SRTPushClient (%s) from %s failed: %v", consumer.redactedURL(), consumer.sourceName, err)
+			consumer.setState(PushFailed, err)
+			consumer.publishEvent(events.TargetFailed, err)
+			if consumer.config.gaveUp(failures) {
+				log.Printf("SRTPushClient (%s) from %s gave up after %d attempts", consumer.redactedURL(), consumer.sourceName, failures)
+				consumer.setState(PushGaveUp, nil)
+				consumer.publishEvent(events.TargetGaveUp, nil)
+				break
+			}
+			select {
+			case <-time.After(2 * time.Second):
+			case <-consumer.quit:
+			}
+		}
+		log.Printf("SRTPushClient (%s) from %s exited", consumer.redactedURL(), consumer.sourceName)
+	}()
+
+	return consumer, nil
+}
+
+func (cn *SrtPushConsumer) redactedURL() string {
+	return (*api.PushTargetUrl)(cn.url).Redacted()
+}
+
+func (cn *SrtPushConsumer) publishEvent(eventType events.Type, err error) {
+	publishTargetEvent(eventType, cn.sourceName, cn.url, err)
+}
+
+func (cn *SrtPushConsumer) dialConfig() srt.DialConfig {
+	config := srt.DialConfig{
+		StreamID:   cn.options.SRTStreamID,
+		Passphrase: cn.options.SRTPassphrase,
+		KeyLength:  cn.options.SRTKeyLength,
+		Latency:    time.Duration(cn.options.SRTLatency) * time.Millisecond,
+	}
+	if config.StreamID == "" {
+		config.StreamID = cn.url.Query().Get("streamid")
+	}
+	return config
+}
+
+// connection reports whether the target accepted the connection and why it ended.
+func (cn *SrtPushConsumer) connection() (connected bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("SRTPushClient (%s) connection panic: %v", cn.redactedURL(), r)
+		}
+	}()
+	network := "udp" + strings.TrimPrefix(cn.options.Network, "tcp")
+	conn, err := srt.Dial(network, cn.url.Host, cn.dialConfig())
+	if err != nil {
+		return false, err
+	}
+	cn.connMtx.Lock()
+	if cn.quited.Load() {
+		cn.connMtx.Unlock()
+		_ = conn.Close()
+		return true, nil
+	}
+	cn.conn = conn
+	cn.connMtx.Unlock()
+	defer conn.Close()
+
+	log.Printf("SRTPushClient (%s) from %s connected", cn.redactedURL(), cn.sourceName)
+	cn.setState(PushConnected, nil)
+	cn.publishEvent(events.TargetConnected, nil)
+	cn.framesMtx.Lock()
+	cn.framesBatches = nil
+	cn.framesMtx.Unlock()
+
+	// The target sends nothing, reading ends when the connection breaks
+	readErr := make(chan error, 1)
+	go func() {
+		buf := make([]byte, srt.PayloadSize)
+		for {
+			if _, err := conn.Read(buf); err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+	return true, cn.sendToServer(conn, readErr)
+}
+
+func (cn *SrtPushConsumer) sendToServer(conn *srt.Conn, readErr <-chan error) error {
+	w := newTsWriter(conn)
+	for {
+		select {
+		case err := <-readErr:
+			return err
+		case <-cn.frameCome:
+			cn.framesMtx.Lock()
+			batches := cn.framesBatches
+			cn.framesBatches = nil
+			cn.framesMtx.Unlock()
+
+			for _, batch := range batches {
+				for i := range batch.Frames {
+					if err := w.writeFrame(&batch.Frames[i]); err != nil {
+						return err
+					}
+				}
+			}
+		case <-cn.quit:
+			return nil
+		}
+	}
+}
+
+func (cn *SrtPushConsumer) Play(frame *MediaFrameBatch) {
+	cn.framesMtx.Lock()
+	if len(cn.framesBatches) >= 90 {
+		cn.framesBatches = cn.framesBatches[:45]
+	}
+	cn.framesBatches = append(cn.framesBatches, frame)
+	cn.framesMtx.Unlock()
+	select {
+	case cn.frameCome <- struct{}{}:
+	default:
+	}
+}
+
+func (cn *SrtPushConsumer) Id() string {
+	return cn.id
+}
+
+func (cn *SrtPushConsumer) Target() string {
+	return cn.url.String()
+}
+
+func (cn *SrtPushConsumer) Close() error {
+	cn.quited.Store(true)
+	var err error
+	cn.die.Do(func() {
+		close(cn.quit)
+		cn.connMtx.Lock()
+		if cn.conn != nil {
+			err = cn.conn.Close()
+		}
+		cn.connMtx.Unlock()
+	})
+	log.Printf("Closed SRTPushConsumer %s", cn.redactedURL())
+	return err
+}
+
+func (cn *SrtPushConsumer) IsClosed() bool {
+	return cn.quited.Load()
+}
+
+// tsWriter muxes frames into MPEG-TS and writes it in SRT packets of 7 TS packets. It starts at a
+// keyframe, or at once for audio only streams, and buffers the frames until it knows the tracks.
+type tsWriter struct {
+	conn    *srt.Conn
+	muxer   *mpeg2.TSMuxer
+	pids    map[codec.CodecID]uint16
+	pending []*MediaFrame
+	keyed   bool // a video keyframe has been seen
+	out     []byte
+}
+
+func newTsWriter(conn *srt.Conn) *tsWriter {
+	return &tsWriter{conn: conn}
+}
+
+func (w *tsWriter) writeFrame(frame *MediaFrame) error {
+	if frame.IsVideo() && !w.keyed {
+		if !frame.IsIFrame {
+			return nil
+		}
+		w.keyed = true
+	}
+	if w.muxer != nil {
+		return w.mux(frame)
+	}
+	w.pending = append(w.pending, frame)
+
+	var hasVideo, hasAudio bool
+	for _, f := range w.pending {
+		hasVideo = hasVideo || f.IsVideo()
+		hasAudio = hasAudio || !f.IsVideo()
+	}
+	probed := time.Duration(int64(frame.Dts)-int64(w.pending[0].Dts)) * time.Millisecond
+	if !(hasVideo && hasAudio) && probed < codecProbeTime {
+		return nil
+	}
+
+	w.muxer = mpeg2.NewTSMuxer()
+	w.muxer.OnPacket = func(pkg []byte) {
+		w.out = append(w.out, pkg...)
+	}
+	w.pids = make(map[codec.CodecID]uint16)
+	pending := w.pending
+	w.pending = nil
+	for _, f := range pending {
+		if _, ok := w.pids[f.Cid]; ok {
+			continue
+		}
+		switch f.Cid {
+		case codec.CODECID_VIDEO_H264:
+			w.pids[f.Cid] = w.muxer.AddStream(mpeg2.TS_STREAM_H264)
+		case codec.CODECID_VIDEO_H265:
+			w.pids[f.Cid] = w.muxer.AddStream(mpeg2.TS_STREAM_H265)
+		case codec.CODECID_AUDIO_AAC:
+			w.pids[f.Cid] = w.muxer.AddStream(mpeg2.TS_STREAM_AAC)
+		}
+	}
+	for _, f := range pending {
+		if err := w.mux(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *tsWriter) mux(frame *MediaFrame) error {
+	pid, ok := w.pids[frame.Cid]
+	if !ok {
+		// A track which appeared after the PMT was sent
+		return nil
+	}
+	if err := w.muxer.Write(pid, frame.Frame, uint64(frame.Pts), uint64(frame.Dts)); err != nil {
+		return err
+	}
+	n := len(w.out) / srt.PayloadSize * srt.PayloadSize
+	if n == 0 {
+		return nil
+	}
+	_, err := w.conn.Write(w.out[:n])
+	w.out = append(w.out[:0], w.out[n:]...)
+	return err
+}
@@ -1,5 +1,15 @@
 package medias
 
+import (
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/events"
+)
+
 type MediaConsumer interface {
 	Play(batch *MediaFrameBatch)
 	Id() string
@@ -10,4 +20,97 @@ type MediaConsumer interface {
 type MediaPushConsumer interface {
 	MediaConsumer
 	Target() string
+	Status() PushConsumerStatus
+}
+
+// DefaultMaxConnectAttempts is used when PushConfig.MaxConnectAttempts is zero.
+const DefaultMaxConnectAttempts = 10
+
+// PushConfig holds the settings shared by all push targets and pull sources.
+type PushConfig struct {
+	// Consecutive failed connection attempts after which a target gives up,
+	// DefaultMaxConnectAttempts if zero, a negative value retries forever
+	MaxConnectAttempts int
+	// Directory the CA and client certificate files of the TLS options are read from,
+	// the file options are rejected if empty
+	TLSFilesDir string
+	Proxy       *url.URL // used by targets which don't set their own proxy
+	// Dialer builds the dialer of a target instead of NewDialer, e.g. to inject a custom dialer
+	Dialer func(options api.PushTargetOptions) (Dialer, error)
+}
+
+// gaveUp tells whether a target stops after the consecutive failures.
+func (c PushConfig) gaveUp(failures int) bool {
+	attempts := c.MaxConnectAttempts
+	if attempts == 0 {
+		attempts = DefaultMaxConnectAttempts
+	}
+	return attempts > 0 && failures >= attempts
+}
+
+func (c PushConfig) dialer(options api.PushTargetOptions) (Dialer, error) {
+	if c.Dialer != nil {
+		return c.Dialer(options)
+	}
+	return NewDialer(options, c.Proxy)
+}
+
+type PushConsumerState string
+
+const (
+	PushConnecting PushConsumerState = "connecting"
+	PushConnected  PushConsumerState = "connected"
+	PushFailed     PushConsumerState = "failed"
+	PushGaveUp     PushConsumerState = "gave_up"
+)
+
+type PushConsumerStatus struct {
+	State       PushConsumerState `json:"state"`
+	Since       time.Time         `json:"since"`
+	LastError   string            `json:"last_error,omitempty"`
+	LastErrorAt *time.Time        `json:"last_error_at,omitempty"`
+	TLSError    bool              `json:"tls_error,omitempty"` // the last error happened during the TLS handshake
+}
+
+// targetStatus tracks the connection state of a push consumer.
+type targetStatus struct {
+	status    PushConsumerStatus
+	statusMtx sync.Mutex
+}
+
+func newTargetStatus() targetStatus {
+	return targetStatus{status: PushConsumerStatus{State: PushConnecting, Since: time.Now()}}
+}
+
+func (t *targetStatus) setState(state PushConsumerState, err error) {
+	t.statusMtx.Lock()
+	defer t.statusMtx.Unlock()
+	if t.status.State != state {
+		t.status.State = state
+		t.status.Since = time.Now()
+	}
+	if err != nil {
+		now := time.Now()
+		t.status.LastError = err.Error()
+		t.status.LastErrorAt = &now
+		t.status.TLSError = errors.As(err, &tlsHandshakeError{})
+	}
+}
+
+func (t *targetStatus) Status() PushConsumerStatus {
+	t.statusMtx.Lock()
+	defer t.statusMtx.Unlock()
+	return t.status
+}
+
+func publishTargetEvent(eventType events.Type, sourceName string, target *url.URL, err error) {
+	event := events.Event{
+		Type:   eventType,
+		Stream: sourceName,
+		Target: (*api.PushTargetUrl)(target).Redacted(),
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	events.Publish(event)
 }
@@ -0,0 +1,219 @@
+package medias
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
+	"golang.org/x/net/proxy"
+)
+
+// Dialer opens the TCP connection to a push target.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+const dialTimeout = 10 * time.Second
+
+// NewDialer returns a dual-stack dialer honoring the source address and proxy options,
+// defaultProxy is used if the options don't set a proxy.
+func NewDialer(options api.PushTargetOptions, defaultProxy *url.URL) (Dialer, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	if options.SourceAddress != "" {
+		dialer.LocalAddr = &net.TCPAddr{IP: net.ParseIP(options.SourceAddress)}
+	}
+
+	proxyURL := defaultProxy
+	if options.Proxy != "" {
+		parsed, err := api.ParseProxy(options.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		proxyURL = parsed
+	}
+	if proxyURL == nil {
+		return dialer, nil
+	}
+	return NewProxyDialer(proxyURL, dialer)
+}
+
+// NewProxyDialer tunnels connections through a socks5://, socks5h:// or http:// proxy reached with forward.
+// The network of the dial applies to the connection to the proxy and, with socks5, to resolving the target.
+// socks5h and http proxies resolve the target host themselves.
+func NewProxyDialer(proxyURL *url.URL, forward Dialer) (Dialer, error) {
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if proxyURL.User != nil {
+			password, _ := proxyURL.User.Password()
+			auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+		}
+		return &socks5Dialer{
+			address:      proxyAddress(proxyURL, "1080"),
+			auth:         auth,
+			forward:      forward,
+			resolveLocal: proxyURL.Scheme == "socks5",
+		}, nil
+	case "http":
+		return &connectDialer{proxy: proxyURL, forward: forward}, nil
+	}
+	return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+}
+
+func proxyAddress(proxyURL *url.URL, defaultPort string) string {
+	if proxyURL.Port() != "" {
+		return proxyURL.Host
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), defaultPort)
+}
+
+// checkLiteralFamily rejects an IP address target which can't be reached over the network.
+func checkLiteralFamily(network, address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil
+	}
+	if network == "tcp4" && ip.To4() == nil || network == "tcp6" && ip.To4() != nil {
+		return fmt.Errorf("%s is not reachable over %s", host, network)
+	}
+	return nil
+}
+
+type socks5Dialer struct {
+	address      string
+	auth         *proxy.Auth
+	forward      Dialer
+	resolveLocal bool
+}
+
+func (d *socks5Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if err := checkLiteralFamily(network, address); err != nil {
+		return nil, err
+	}
+	if d.resolveLocal {
+		resolved, err := resolveTarget(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		address = resolved
+	}
+	dialer, err := proxy.SOCKS5(network, d.address, d.auth, forwardDialer{d.forward})
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialer.(proxy.ContextDialer).DialContext(ctx, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %w", d.address, err)
+	}
+	return conn, nil
+}
+
+// forwardDialer adapts a Dialer to golang.org/x/net/proxy, which only uses DialContext when available.
+type forwardDialer struct {
+	Dialer
+}
+
+func (d forwardDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// resolveTarget replaces the host name of the address by its first IP address of the network family.
+func resolveTarget(ctx context.Context, network, address string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) != nil {
+		return address, nil
+	}
+	ipNetwork := "ip"
+	switch network {
+	case "tcp4":
+		ipNetwork = "ip4"
+	case "tcp6":
+		ipNetwork = "ip6"
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, ipNetwork, host)
+	if err != nil {
+		return "", err
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("no %s address of %s", ipNetwork, host)
+	}
+	return net.JoinHostPort(ips[0].String(), port), nil
+}
+
+type connectDialer struct {
+	proxy   *url.URL
+	forward Dialer
+}
+
+// DialContext sends an HTTP CONNECT request, which golang.org/x/net/proxy doesn't implement.
+func (d *connectDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if err := checkLiteralFamily(network, address); err != nil {
+		return nil, err
+	}
+	proxyAddr := proxyAddress(d.proxy, "80")
+	conn, err := d.forward.DialContext(ctx, network, proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %w", proxyAddr, err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(dialTimeout)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if d.proxy.User != nil {
+		password, _ := d.proxy.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(d.proxy.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	reader := bufio.NewReader(conn)
+	err = req.Write(conn)
+	if err == nil {
+		var resp *http.Response
+		resp, err = http.ReadResponse(reader, req)
+		if err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				err = fmt.Errorf("CONNECT %s: %s", address, resp.Status)
+			}
+		}
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %s: %w", proxyAddr, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn returns bytes the proxy sent right after its response before reading the connection.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
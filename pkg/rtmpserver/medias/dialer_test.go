@@ -0,0 +1,159 @@
+package medias
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// serveSOCKS5 accepts one connection without authentication, records the requested address and replies with success.
+func serveSOCKS5(t *testing.T, requested chan<- string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		greeting := make([]byte, 2)
+		if _, err := io.ReadFull(conn, greeting); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, make([]byte, greeting[1])); err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte{5, 0})
+
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		var host string
+		switch header[3] {
+		case 1:
+			ip := make([]byte, 4)
+			_, _ = io.ReadFull(conn, ip)
+			host = net.IP(ip).String()
+		case 4:
+			ip := make([]byte, 16)
+			_, _ = io.ReadFull(conn, ip)
+			host = net.IP(ip).String()
+		case 3:
+			length := make([]byte, 1)
+			_, _ = io.ReadFull(conn, length)
+			name := make([]byte, length[0])
+			_, _ = io.ReadFull(conn, name)
+			host = "domain:" + string(name)
+		}
+		_, _ = io.ReadFull(conn, make([]byte, 2))
+		requested <- host
+		_, _ = conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+		_, _ = conn.Read(make([]byte, 1))
+	}()
+	return listener.Addr().String()
+}
+
+func TestSOCKS5Resolution(t *testing.T) {
+	tests := []struct {
+		scheme  string
+		network string
+		target  string
+		want    string
+	}{
+		{"socks5", "tcp4", "localhost:1935", "127.0.0.1"},
+		{"socks5h", "tcp4", "localhost:1935", "domain:localhost"},
+		{"socks5", "tcp", "192.0.2.1:1935", "192.0.2.1"},
+		{"socks5h", "tcp", "192.0.2.1:1935", "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scheme+" "+tt.target, func(t *testing.T) {
+			requested := make(chan string, 1)
+			address := serveSOCKS5(t, requested)
+			dialer, err := NewProxyDialer(&url.URL{Scheme: tt.scheme, Host: address}, &net.Dialer{Timeout: time.Second})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			conn, err := dialer.DialContext(ctx, tt.network, tt.target)
+			if err != nil {
+				t.Fatalf("DialContext: %v", err)
+			}
+			_ = conn.Close()
+			if got := <-requested; got != tt.want {
+				t.Fatalf("proxy was asked for %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyRejectsAddressFamily(t *testing.T) {
+	for _, scheme := range []string{"socks5", "socks5h", "http"} {
+		dialer, err := NewProxyDialer(&url.URL{Scheme: scheme, Host: "127.0.0.1:1"}, &net.Dialer{Timeout: time.Second})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dialer.DialContext(context.Background(), "tcp6", "192.0.2.1:1935"); err == nil {
+			t.Fatalf("%s: an IPv4 target was dialed over tcp6", scheme)
+		}
+	}
+}
+
+func TestHTTPConnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	requested := make(chan *http.Request, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		requested <- req
+		// The first bytes of the tunnel arrive together with the response
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\nhello"))
+		_, _ = conn.Read(make([]byte, 1))
+	}()
+
+	proxyURL := &url.URL{Scheme: "http", User: url.UserPassword("user", "pass"), Host: listener.Addr().String()}
+	dialer, err := NewProxyDialer(proxyURL, &net.Dialer{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := dialer.DialContext(ctx, "tcp", "example.com:1935")
+	if err != nil {
+		t.Fatalf("DialContext: %v", err)
+	}
+	defer conn.Close()
+
+	req := <-requested
+	if req.Method != http.MethodConnect || req.Host != "example.com:1935" {
+		t.Fatalf("got %s %s", req.Method, req.Host)
+	}
+	if got := req.Header.Get("Proxy-Authorization"); got != "Basic dXNlcjpwYXNz" {
+		t.Fatalf("Proxy-Authorization = %q", got)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "hello" {
+		t.Fatalf("read %q, %v", buf, err)
+	}
+}
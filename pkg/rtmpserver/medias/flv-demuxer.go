@@ -0,0 +1,29 @@
+package medias
+
+import (
+	"github.com/yapingcat/gomedia/go-codec"
+	"github.com/yapingcat/gomedia/go-flv"
+)
+
+// FLVDemuxer demuxes an FLV byte stream, like the body of an HTTP ingest request, into batches
+// of frames. The timestamps of the tags are kept like those of an RTMP publisher.
+type FLVDemuxer struct {
+	reader  *flv.FlvReader
+	batcher frameBatcher
+}
+
+func NewFLVDemuxer(onBatch func(batch *MediaFrameBatch)) *FLVDemuxer {
+	d := &FLVDemuxer{
+		reader:  flv.CreateFlvReader(),
+		batcher: frameBatcher{flush: onBatch},
+	}
+	d.reader.OnFrame = func(cid codec.CodecID, frame []byte, pts, dts uint32) {
+		// The reader reuses its buffer
+		d.batcher.add(cid, pts, dts, append([]byte(nil), frame...))
+	}
+	return d
+}
+
+func (d *FLVDemuxer) Input(data []byte) error {
+	return d.reader.Input(data)
+}
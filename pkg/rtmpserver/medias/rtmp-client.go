@@ -0,0 +1,172 @@
+package medias
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
+	"github.com/yapingcat/gomedia/go-rtmp"
+)
+
+// tlsHandshakeError marks errors of the TLS handshake, so they're reported in the status.
+type tlsHandshakeError struct {
+	err error
+}
+
+func (e tlsHandshakeError) Error() string {
+	return "TLS handshake: " + e.err.Error()
+}
+
+func (e tlsHandshakeError) Unwrap() error {
+	return e.err
+}
+
+// dialRtmp connects to the rtmp or rtmps URL, possibly through a proxy. Cancelling the context aborts it.
+func dialRtmp(ctx context.Context, u *url.URL, options api.PushTargetOptions, config PushConfig) (net.Conn, error) {
+	port := u.Port()
+	if port == "" {
+		if strings.HasPrefix(u.Scheme, "rtmps") {
+			port = "443"
+		} else {
+			port = "1935"
+		}
+	}
+	c, err := dial(ctx, net.JoinHostPort(u.Hostname(), port), options, config)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(u.Scheme, "rtmps") {
+		return c, nil
+	}
+	conf, err := tlsConfig(u, options.TLS, config.TLSFilesDir)
+	if err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	tlsConn := tls.Client(c, conf)
+	_ = tlsConn.SetDeadline(time.Now().Add(10 * time.Second))
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = c.Close()
+		return nil, tlsHandshakeError{err: err}
+	}
+	_ = tlsConn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+// newRtmpClient creates a gomedia client writing to the connection with the RTMP parameters of the options.
+func newRtmpClient(c net.Conn, u *url.URL, options api.PushTargetOptions, onStateChange func(rtmp.RtmpState), clientOptions ...func(*rtmp.RtmpClient)) *rtmp.RtmpClient {
+	// gomedia uses the simple handshake unless asked otherwise
+	if options.Handshake != "simple" {
+		clientOptions = append(clientOptions, rtmp.WithComplexHandshake())
+	}
+	if options.ChunkSize != 0 {
+		clientOptions = append(clientOptions, rtmp.WithChunkSize(options.ChunkSize))
+	}
+	app, playpath := rtmpAppAndPlaypath(u, options)
+	clientOptions = append(clientOptions, rtmp.WithApp(app), rtmp.WithStreamName(playpath))
+	if options.TcURL != "" {
+		clientOptions = append(clientOptions, rtmp.WithTcUrl(options.TcURL))
+	}
+	if options.FlashVer != "" {
+		clientOptions = append(clientOptions, rtmp.WithFlashVer(options.FlashVer))
+	}
+	client := rtmp.NewRtmpClient(clientOptions...)
+	client.SetOutput(func(data []byte) error {
+		_, err := c.Write(data)
+		return err
+	})
+	client.OnStateChange(onStateChange)
+	return client
+}
+
+// rtmpAppAndPlaypath splits the URL path into the app and the playpath.
+// The app is the first path segment unless the options set an app with slashes,
+// the query of the URL belongs to the playpath.
+func rtmpAppAndPlaypath(u *url.URL, options api.PushTargetOptions) (string, string) {
+	path := strings.TrimPrefix(u.Path, "/")
+	app, playpath, _ := strings.Cut(path, "/")
+	if options.App != "" {
+		app = options.App
+		if rest, ok := strings.CutPrefix(path, app+"/"); ok {
+			playpath = rest
+		}
+	}
+	if u.RawQuery != "" {
+		playpath += "?" + u.RawQuery
+	}
+	if options.Playpath != "" {
+		playpath = options.Playpath
+	}
+	return app, playpath
+}
+
+// dial connects to the address, possibly through a proxy.
+// The default "tcp" network races IPv6 and IPv4 addresses (Happy Eyeballs).
+func dial(ctx context.Context, address string, options api.PushTargetOptions, config PushConfig) (net.Conn, error) {
+	network := options.Network
+	if network == "" {
+		network = "tcp"
+	}
+	dialer, err := config.dialer(options)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	return dialer.DialContext(ctx, network, address)
+}
+
+// tlsConfig verifies the server certificate unless the options explicitly opt out.
+func tlsConfig(u *url.URL, opts *api.PushTargetTLS, tlsFilesDir string) (*tls.Config, error) {
+	conf := &tls.Config{ServerName: u.Hostname()}
+	if opts == nil {
+		return conf, nil
+	}
+	if opts.ServerName != "" {
+		conf.ServerName = opts.ServerName
+	}
+	if opts.CAFile != "" {
+		pool, err := opts.CertPool(tlsFilesDir)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = pool
+	}
+	if opts.ClientCertFile != "" {
+		cert, err := opts.ClientCertificate(tlsFilesDir)
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	if opts.Fingerprint != "" {
+		fingerprint, err := opts.FingerprintBytes()
+		if err != nil {
+			return nil, err
+		}
+		// The pinned certificate replaces chain verification
+		conf.InsecureSkipVerify = true
+		conf.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("no peer certificate")
+			}
+			sum := sha256.Sum256(state.PeerCertificates[0].Raw)
+			if subtle.ConstantTimeCompare(sum[:], fingerprint) != 1 {
+				return fmt.Errorf("certificate fingerprint mismatch: got %s", hex.EncodeToString(sum[:]))
+			}
+			return nil
+		}
+	} else if opts.InsecureSkipVerify {
+		conf.InsecureSkipVerify = true
+	}
+	return conf, nil
+}
@@ -0,0 +1,96 @@
+package medias
+
+import (
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
+	"github.com/yapingcat/gomedia/go-rtmp"
+)
+
+type publishRequest struct {
+	app, streamName, tcURL, flashVer string
+}
+
+// publishTo runs the client against a gomedia server over a pipe and returns what the server was asked to publish.
+func publishTo(t *testing.T, rawURL string, options api.PushTargetOptions) publishRequest {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	published := make(chan publishRequest, 1)
+	server := rtmp.NewRtmpServerHandle()
+	server.OnPublish(func(app, streamName string) rtmp.StatusCode {
+		published <- publishRequest{app: app, streamName: streamName, tcURL: server.GetTcUrl(), flashVer: server.GetFlashVer()}
+		return rtmp.NETSTREAM_PUBLISH_START
+	})
+	server.SetOutput(func(data []byte) error {
+		_, err := serverConn.Write(data)
+		return err
+	})
+	go pump(serverConn, server.Input)
+
+	client := newRtmpClient(clientConn, u, options, func(rtmp.RtmpState) {}, rtmp.WithEnablePublish())
+	go pump(clientConn, client.Input)
+	client.Start(u.String())
+
+	select {
+	case req := <-published:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server got no publish command")
+	}
+	return publishRequest{}
+}
+
+func pump(c net.Conn, input func([]byte) error) {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := c.Read(buf)
+		if err != nil {
+			return
+		}
+		if input(buf[:n]) != nil {
+			_ = c.Close()
+			return
+		}
+	}
+}
+
+func TestRtmpClientParameters(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		options api.PushTargetOptions
+		want    publishRequest
+	}{
+		{"from url", "rtmp://example.com/live/key", api.PushTargetOptions{},
+			publishRequest{"live", "key", "rtmp://example.com/live", "FMSc/1.0"}},
+		{"query belongs to playpath", "rtmp://example.com:1936/live/key?token=1", api.PushTargetOptions{},
+			publishRequest{"live", "key?token=1", "rtmp://example.com:1936/live", "FMSc/1.0"}},
+		{"app with slashes", "rtmps://example.com/live/sub/key", api.PushTargetOptions{App: "live/sub"},
+			publishRequest{"live/sub", "key", "rtmps://example.com/live/sub", "FMSc/1.0"}},
+		{"playpath override", "rtmp://example.com/live/key", api.PushTargetOptions{Playpath: "other?x=1"},
+			publishRequest{"live", "other?x=1", "rtmp://example.com/live", "FMSc/1.0"}},
+		{"url without path", "rtmp://example.com", api.PushTargetOptions{App: "live", Playpath: "key"},
+			publishRequest{"live", "key", "rtmp://example.com/live", "FMSc/1.0"}},
+		{"tcUrl and flashVer", "rtmp://example.com/live/key", api.PushTargetOptions{TcURL: "rtmp://origin/live?auth=1", FlashVer: "FMLE/3.0"},
+			publishRequest{"live", "key", "rtmp://origin/live?auth=1", "FMLE/3.0"}},
+		{"simple handshake", "rtmp://example.com/live/key", api.PushTargetOptions{Handshake: "simple", ChunkSize: 4096},
+			publishRequest{"live", "key", "rtmp://example.com/live", "FMSc/1.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := publishTo(t, tt.url, tt.options); got != tt.want {
+				t.Fatalf("published %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
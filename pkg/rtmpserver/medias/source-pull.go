@@ -0,0 +1,294 @@
+package medias
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/api"
+	"github.com/yapingcat/gomedia/go-codec"
+	"github.com/yapingcat/gomedia/go-rtmp"
+)
+
+const (
+	minPullBackoff  = time.Second
+	maxPullBackoff  = 30 * time.Second
+	pullReadTimeout = 10 * time.Second // the source is considered dead after this silence
+)
+
+// SourceHandler receives the frames of a PullSource.
+type SourceHandler interface {
+	// OnSourceStart is called when the source starts playing, an error disconnects it.
+	OnSourceStart() error
+	OnSourceBatch(batch *MediaFrameBatch)
+	// OnSourceStop is called when a started source disconnects.
+	OnSourceStop()
+}
+
+type PullSourceState string
+
+const (
+	PullConnecting PullSourceState = "connecting"
+	PullPlaying    PullSourceState = "playing"
+	PullFailed     PullSourceState = "failed"
+)
+
+type PullSourceStatus struct {
+	State       PullSourceState `json:"state"`
+	Since       time.Time       `json:"since"`
+	LastError   string          `json:"last_error,omitempty"`
+	LastErrorAt *time.Time      `json:"last_error_at,omitempty"`
+	TLSError    bool            `json:"tls_error,omitempty"`
+	Failures    int             `json:"failures,omitempty"` // consecutive failed attempts
+	RetryAt     *time.Time      `json:"retry_at,omitempty"`
+}
+
+// PullSource plays a remote rtmp, rtmps or rtsp URL, or listens for MPEG-TS over udp, and passes its frames to the handler in batches,
+// like a MediaProducer does for publishers. It reconnects with an exponential backoff.
+type PullSource struct {
+	url        *url.URL
+	options    api.PushTargetOptions
+	config     PushConfig
+	streamName string
+	handler    SourceHandler
+
+	status    PullSourceStatus
+	statusMtx sync.Mutex
+
+	conn    net.Conn
+	connMtx sync.Mutex
+
+	ctx    context.Context // cancelled on Close, aborts dialing
+	cancel context.CancelFunc
+	quit   chan struct{}
+	quited atomic.Bool
+	die    sync.Once
+	done   chan struct{}
+}
+
+func NewPullSource(sourceUrl *api.PushTargetUrl, options api.PushTargetOptions, config PushConfig, streamName string, handler SourceHandler) *PullSource {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &PullSource{
+		url:        (*url.URL)(sourceUrl),
+		options:    options,
+		config:     config,
+		streamName: streamName,
+		handler:    handler,
+		status:     PullSourceStatus{State: PullConnecting, Since: time.Now()},
+		ctx:        ctx,
+		cancel:     cancel,
+		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+func (s *PullSource) Start() {
+	go s.run()
+}
+
+func (s *PullSource) run() {
+	defer close(s.done)
+	backoff := minPullBackoff
+	for {
+		played, err := s.play()
+		if s.quited.Load() {
+			break
+		}
+		if played {
+			backoff = minPullBackoff
+		}
+		log.Printf("PullSource (%s) of %s failed: %v, retrying in %s", s.redactedURL(), s.streamName, err, backoff)
+		s.setFailed(err, backoff, played)
+		select {
+		case <-time.After(backoff):
+		case <-s.quit:
+			return
+		}
+		backoff = min(backoff*2, maxPullBackoff)
+		s.setState(PullConnecting)
+	}
+	log.Printf("PullSource (%s) of %s exited", s.redactedURL(), s.streamName)
+}
+
+// play reports whether the source started playing and why it stopped.
+func (s *PullSource) play() (played bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	switch s.url.Scheme {
+	case "rtsp":
+		return s.playRtsp()
+	case "udp":
+		return s.playUdp()
+	}
+	return s.playRtmp()
+}
+
+func (s *PullSource) playRtmp() (played bool, err error) {
+	c, err := dialRtmp(s.ctx, s.url, s.options, s.config)
+	if err != nil {
+		return false, err
+	}
+	if !s.attach(c) {
+		return false, net.ErrClosed
+	}
+	defer c.Close()
+
+	var playErr error
+	client := newRtmpClient(c, s.url, s.options, func(newState rtmp.RtmpState) {
+		switch newState {
+		case rtmp.STATE_RTMP_PLAY_START:
+			if err := s.handler.OnSourceStart(); err != nil {
+				playErr = err
+				return
+			}
+			log.Printf("RTMPPullSource (%s) of %s is playing", s.redactedURL(), s.streamName)
+			played = true
+			s.setState(PullPlaying)
+		case rtmp.STATE_RTMP_PLAY_FAILED:
+			playErr = errors.New("play failed")
+		}
+	})
+	client.OnError(func(code, describe string) {
+		log.Printf("RTMPPullSource (%s) client error: %s %s", s.redactedURL(), code, describe)
+	})
+	batcher := frameBatcher{flush: s.handler.OnSourceBatch}
+	client.OnFrame(func(cid codec.CodecID, pts, dts uint32, frame []byte) {
+		if played {
+			batcher.add(cid, pts, dts, frame)
+		}
+	})
+	defer func() {
+		if played {
+			s.handler.OnSourceStop()
+		}
+	}()
+
+	client.Start(s.url.String())
+	buf := make([]byte, 65536)
+	for playErr == nil {
+		_ = c.SetReadDeadline(time.Now().Add(pullReadTimeout))
+		n, err := c.Read(buf)
+		if err != nil {
+			return played, err
+		}
+		if err := client.Input(buf[:n]); err != nil {
+			return played, err
+		}
+	}
+	return played, playErr
+}
+
+// attach makes the connection closable by Close and Disconnect, it reports false if the source is closed.
+func (s *PullSource) attach(c net.Conn) bool {
+	s.connMtx.Lock()
+	defer s.connMtx.Unlock()
+	if s.quited.Load() {
+		_ = c.Close()
+		return false
+	}
+	s.conn = c
+	return true
+}
+
+// frameBatcher groups frames like a MediaProducer, a batch is flushed after a second or at a keyframe.
+type frameBatcher struct {
+	batch *MediaFrameBatch
+	flush func(batch *MediaFrameBatch)
+}
+
+func (b *frameBatcher) add(cid codec.CodecID, pts, dts uint32, frame []byte) {
+	if b.batch == nil {
+		b.batch = &MediaFrameBatch{StartTime: time.Now()}
+	}
+	isIFrame := cid == codec.CODECID_VIDEO_H264 && codec.IsH264IDRFrame(frame) ||
+		cid == codec.CODECID_VIDEO_H265 && codec.IsH265IDRFrame(frame)
+	b.batch.Frames = append(b.batch.Frames, MediaFrame{
+		Cid:      cid,
+		Frame:    frame,
+		Pts:      pts,
+		Dts:      dts,
+		Time:     time.Now(),
+		IsIFrame: isIFrame,
+	})
+	if time.Since(b.batch.StartTime) >= time.Second || isIFrame {
+		b.flush(b.batch)
+		b.batch = nil
+	}
+}
+
+func (s *PullSource) setState(state PullSourceState) {
+	s.statusMtx.Lock()
+	defer s.statusMtx.Unlock()
+	if s.status.State != state {
+		s.status.State = state
+		s.status.Since = time.Now()
+	}
+	if state == PullPlaying {
+		s.status.Failures = 0
+		s.status.RetryAt = nil
+	}
+}
+
+func (s *PullSource) setFailed(err error, backoff time.Duration, played bool) {
+	s.statusMtx.Lock()
+	defer s.statusMtx.Unlock()
+	now := time.Now()
+	retryAt := now.Add(backoff)
+	if s.status.State != PullFailed {
+		s.status.State = PullFailed
+		s.status.Since = now
+	}
+	if !played {
+		s.status.Failures++
+	}
+	s.status.RetryAt = &retryAt
+	if err != nil {
+		s.status.LastError = err.Error()
+		s.status.LastErrorAt = &now
+		s.status.TLSError = errors.As(err, &tlsHandshakeError{})
+	}
+}
+
+func (s *PullSource) Status() PullSourceStatus {
+	s.statusMtx.Lock()
+	defer s.statusMtx.Unlock()
+	return s.status
+}
+
+// Disconnect drops the current connection, the source reconnects after the backoff.
+func (s *PullSource) Disconnect() {
+	s.connMtx.Lock()
+	defer s.connMtx.Unlock()
+	if s.conn != nil {
+		_ = s.conn.Close()
+	}
+}
+
+// Close stops the source and waits until the handler is notified of the stop.
+func (s *PullSource) Close() error {
+	s.die.Do(func() {
+		s.connMtx.Lock()
+		s.quited.Store(true)
+		close(s.quit)
+		s.cancel()
+		if s.conn != nil {
+			_ = s.conn.Close()
+		}
+		s.connMtx.Unlock()
+	})
+	<-s.done
+	return nil
+}
+
+func (s *PullSource) redactedURL() string {
+	return (*api.PushTargetUrl)(s.url).Redacted()
+}
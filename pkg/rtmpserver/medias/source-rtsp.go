@@ -0,0 +1,413 @@
+package medias
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/yapingcat/gomedia/go-codec"
+	"github.com/yapingcat/gomedia/go-rtsp"
+	"github.com/yapingcat/gomedia/go-rtsp/sdp"
+)
+
+const aacFrameSamples = 1024
+
+// rtspPlayer handles the responses of the gomedia RTSP client for a PullSource
+// and converts the samples of the tracks into frames.
+type rtspPlayer struct {
+	source *PullSource
+	conn   net.Conn
+	udp    bool
+
+	client   *rtsp.RtspClient
+	clientMu sync.Mutex // the client and its tracks are used by the TCP and UDP readers
+
+	played  bool
+	timeout int // session timeout, s
+
+	start   time.Time // arrival of the first sample, the tracks are aligned to it
+	tracks  map[*rtsp.RtspTrack]*rtspTrackState
+	batcher frameBatcher
+
+	udpConns []*net.UDPConn
+	udpErr   chan error
+	quit     chan struct{}
+}
+
+type rtspTrackState struct {
+	cid       codec.CodecID
+	clockRate int64
+	rtp       *net.UDPConn
+	rtcp      *net.UDPConn
+
+	started bool
+	base    int64  // ms from the start of the session to the first sample
+	last    uint32 // last RTP timestamp
+	elapsed int64  // RTP clock ticks since the first sample, unwrapped
+
+	accessUnit   []byte // NAL units of the current video frame
+	accessUnitTs uint32
+	audioTs      uint32
+	audioIndex   int64 // of the AAC frame among the frames with the same RTP timestamp
+}
+
+func (s *PullSource) playRtsp() (played bool, err error) {
+	port := s.url.Port()
+	if port == "" {
+		port = "554"
+	}
+	c, err := dial(s.ctx, net.JoinHostPort(s.url.Hostname(), port), s.options, s.config)
+	if err != nil {
+		return false, err
+	}
+	if !s.attach(c) {
+		return false, net.ErrClosed
+	}
+	defer c.Close()
+
+	p := &rtspPlayer{
+		source: s,
+		conn:   c,
+		udp:    s.options.RTSPTransport == "udp",
+		tracks: make(map[*rtsp.RtspTrack]*rtspTrackState),
+		udpErr: make(chan error, 1),
+		quit:   make(chan struct{}),
+	}
+	p.batcher.flush = s.handler.OnSourceBatch
+	defer p.close()
+	defer func() {
+		if p.played {
+			s.handler.OnSourceStop()
+		}
+	}()
+
+	p.client, err = rtsp.NewRtspClient(s.url.String(), p)
+	if err != nil {
+		return false, err
+	}
+	p.client.SetOutput(func(data []byte) error {
+		_, err := c.Write(data)
+		return err
+	})
+	p.clientMu.Lock()
+	err = p.client.Start()
+	p.clientMu.Unlock()
+	if err != nil {
+		return false, err
+	}
+
+	buf := make([]byte, 65536)
+	for {
+		// With UDP the RTSP connection only carries the keepalive, the media sockets have deadlines instead
+		if !p.udp {
+			_ = c.SetReadDeadline(time.Now().Add(pullReadTimeout))
+		}
+		n, err := c.Read(buf)
+		if err != nil {
+			select {
+			case udpErr := <-p.udpErr:
+				return p.played, udpErr
+			default:
+				return p.played, err
+			}
+		}
+		p.clientMu.Lock()
+		err = p.client.Input(buf[:n])
+		p.clientMu.Unlock()
+		if err != nil {
+			return p.played, err
+		}
+	}
+}
+
+func (p *rtspPlayer) close() {
+	close(p.quit)
+	for _, conn := range p.udpConns {
+		_ = conn.Close()
+	}
+}
+
+// fail stops the session because of an error of the UDP transport.
+func (p *rtspPlayer) fail(err error) {
+	select {
+	case p.udpErr <- err:
+		_ = p.conn.Close()
+	default:
+	}
+}
+
+func (p *rtspPlayer) HandleOption(cli *rtsp.RtspClient, res rtsp.RtspResponse, public []string) error {
+	return nil
+}
+
+func (p *rtspPlayer) HandleDescribe(cli *rtsp.RtspClient, res rtsp.RtspResponse, description *sdp.Sdp, tracks map[string]*rtsp.RtspTrack) error {
+	if res.StatusCode != 200 {
+		return fmt.Errorf("DESCRIBE: %d %s", res.StatusCode, res.Reason)
+	}
+	for name, track := range tracks {
+		state := &rtspTrackState{clockRate: int64(track.Codec.SampleRate)}
+		switch track.Codec.Cid {
+		case rtsp.RTSP_CODEC_H264:
+			state.cid = codec.CODECID_VIDEO_H264
+		case rtsp.RTSP_CODEC_H265:
+			state.cid = codec.CODECID_VIDEO_H265
+		case rtsp.RTSP_CODEC_AAC:
+			state.cid = codec.CODECID_AUDIO_AAC
+		case rtsp.RTSP_CODEC_G711A:
+			state.cid = codec.CODECID_AUDIO_G711A
+		case rtsp.RTSP_CODEC_G711U:
+			state.cid = codec.CODECID_AUDIO_G711U
+		default:
+			// The client sets up the remaining tracks only
+			log.Printf("RTSPPullSource (%s) skips unsupported %s track", p.source.redactedURL(), name)
+			delete(tracks, name)
+			continue
+		}
+		if state.clockRate == 0 {
+			if name == "video" {
+				state.clockRate = 90000
+			} else {
+				state.clockRate = 8000
+			}
+		}
+		if p.udp {
+			rtp, rtcp, err := listenRtpPair()
+			if err != nil {
+				return err
+			}
+			p.udpConns = append(p.udpConns, rtp, rtcp)
+			state.rtp, state.rtcp = rtp, rtcp
+			track.SetTransport(rtsp.NewRtspTransport(
+				rtsp.WithEnableUdp(),
+				rtsp.WithClientUdpPort(uint16(rtp.LocalAddr().(*net.UDPAddr).Port), uint16(rtcp.LocalAddr().(*net.UDPAddr).Port)),
+				rtsp.WithMode(rtsp.MODE_PLAY),
+			))
+		}
+		p.tracks[track] = state
+		track.OnSample(func(sample rtsp.RtspSample) {
+			p.onSample(state, sample)
+		})
+	}
+	if len(p.tracks) == 0 {
+		return errors.New("no supported tracks")
+	}
+	return nil
+}
+
+func (p *rtspPlayer) HandleSetup(cli *rtsp.RtspClient, res rtsp.RtspResponse, track *rtsp.RtspTrack, tracks map[string]*rtsp.RtspTrack, sessionId string, timeout int) error {
+	if res.StatusCode != 200 {
+		return fmt.Errorf("SETUP: %d %s", res.StatusCode, res.Reason)
+	}
+	p.timeout = timeout
+	state := p.tracks[track]
+	if !p.udp || state == nil {
+		return nil
+	}
+	serverIP, err := p.serverIP()
+	if err != nil {
+		return err
+	}
+	ports := track.GetTransport().Server_ports
+	serverRtp := &net.UDPAddr{IP: serverIP, Port: int(ports[0])}
+	serverRtcp := &net.UDPAddr{IP: serverIP, Port: int(ports[1])}
+	track.OnPacket(func(b []byte, isRtcp bool) error {
+		if !isRtcp {
+			return nil
+		}
+		_, err := state.rtcp.WriteToUDP(b, serverRtcp)
+		return err
+	})
+	go p.readUdp(state.rtp, serverRtp, track, false)
+	go p.readUdp(state.rtcp, serverRtcp, track, true)
+	return nil
+}
+
+// serverIP is the address the media is expected from: the peer of the RTSP connection
+// when the url host resolves to it, the first address of the host otherwise (e.g. behind a proxy).
+func (p *rtspPlayer) serverIP() (net.IP, error) {
+	ips, err := net.LookupIP(p.source.url.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	if peer, ok := p.conn.RemoteAddr().(*net.TCPAddr); ok {
+		for _, ip := range ips {
+			if ip.Equal(peer.IP) {
+				return peer.IP, nil
+			}
+		}
+	}
+	return ips[0], nil
+}
+
+// readUdp feeds the track with the datagrams sent from the server ports and drops everything else.
+func (p *rtspPlayer) readUdp(conn *net.UDPConn, server *net.UDPAddr, track *rtsp.RtspTrack, isRtcp bool) {
+	buf := make([]byte, 65536)
+	if !isRtcp {
+		_ = conn.SetReadDeadline(time.Now().Add(pullReadTimeout))
+	}
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-p.quit:
+			default:
+				p.fail(err)
+			}
+			return
+		}
+		// Datagrams from anyone else do not extend the deadline either
+		if from.Port != server.Port || !from.IP.Equal(server.IP) {
+			continue
+		}
+		if !isRtcp {
+			_ = conn.SetReadDeadline(time.Now().Add(pullReadTimeout))
+		}
+		p.clientMu.Lock()
+		err = track.Input(buf[:n], isRtcp)
+		p.clientMu.Unlock()
+		if err != nil {
+			log.Printf("RTSPPullSource (%s) dropped a packet: %v", p.source.redactedURL(), err)
+		}
+	}
+}
+
+func (p *rtspPlayer) HandlePlay(cli *rtsp.RtspClient, res rtsp.RtspResponse, timeRange *rtsp.RangeTime, info *rtsp.RtpInfo) error {
+	if res.StatusCode != 200 {
+		return fmt.Errorf("PLAY: %d %s", res.StatusCode, res.Reason)
+	}
+	if err := p.source.handler.OnSourceStart(); err != nil {
+		return err
+	}
+	log.Printf("RTSPPullSource (%s) of %s is playing", p.source.redactedURL(), p.source.streamName)
+	p.played = true
+	p.source.setState(PullPlaying)
+	go p.keepAlive()
+	return nil
+}
+
+// keepAlive refreshes the session, which the server drops after the timeout otherwise.
+func (p *rtspPlayer) keepAlive() {
+	interval := max(time.Duration(p.timeout)*time.Second/2, 5*time.Second)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.clientMu.Lock()
+			err := p.client.KeepAlive(rtsp.OPTIONS)
+			p.clientMu.Unlock()
+			if err != nil {
+				return
+			}
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+func (p *rtspPlayer) HandlePause(cli *rtsp.RtspClient, res rtsp.RtspResponse) error {
+	return nil
+}
+
+func (p *rtspPlayer) HandleTeardown(cli *rtsp.RtspClient, res rtsp.RtspResponse) error {
+	return nil
+}
+
+func (p *rtspPlayer) HandleGetParameter(cli *rtsp.RtspClient, res rtsp.RtspResponse) error {
+	return nil
+}
+
+func (p *rtspPlayer) HandleSetParameter(cli *rtsp.RtspClient, res rtsp.RtspResponse) error {
+	return nil
+}
+
+func (p *rtspPlayer) HandleRedirect(cli *rtsp.RtspClient, req rtsp.RtspRequest, location string, timeRange *rtsp.RangeTime) error {
+	return fmt.Errorf("redirected to %s", location)
+}
+
+func (p *rtspPlayer) HandleAnnounce(cli *rtsp.RtspClient, res rtsp.RtspResponse) error {
+	return nil
+}
+
+func (p *rtspPlayer) HandleRecord(cli *rtsp.RtspClient, res rtsp.RtspResponse, timeRange *rtsp.RangeTime, info *rtsp.RtpInfo) error {
+	return nil
+}
+
+func (p *rtspPlayer) HandleRequest(cli *rtsp.RtspClient, req rtsp.RtspRequest) error {
+	return nil
+}
+
+// onSample is called with clientMu held. Video samples are single NAL units, which are joined
+// into frames by their timestamp, so a frame is forwarded when the next one starts.
+func (p *rtspPlayer) onSample(state *rtspTrackState, sample rtsp.RtspSample) {
+	if !p.played {
+		return
+	}
+	switch state.cid {
+	case codec.CODECID_VIDEO_H264, codec.CODECID_VIDEO_H265:
+		if len(state.accessUnit) > 0 && sample.Timestamp != state.accessUnitTs {
+			p.emit(state, state.accessUnitTs, 0, state.accessUnit)
+			state.accessUnit = nil
+		}
+		state.accessUnitTs = sample.Timestamp
+		// The client reuses the sample buffer
+		state.accessUnit = append(state.accessUnit, sample.Sample...)
+	case codec.CODECID_AUDIO_AAC:
+		// Frames without the ADTS header can't be muxed, the SDP lacked the AAC config
+		if len(sample.Sample) < 7 || sample.Sample[0] != 0xff || sample.Sample[1]&0xf0 != 0xf0 {
+			return
+		}
+		// All frames of a packet carry the timestamp of the first one
+		if sample.Timestamp == state.audioTs && state.started {
+			state.audioIndex++
+		} else {
+			state.audioIndex = 0
+		}
+		state.audioTs = sample.Timestamp
+		p.emit(state, sample.Timestamp, state.audioIndex*aacFrameSamples, append([]byte(nil), sample.Sample...))
+	default:
+		p.emit(state, sample.Timestamp, 0, append([]byte(nil), sample.Sample...))
+	}
+}
+
+// emit converts the RTP timestamp into milliseconds since the start of the session. Tracks are
+// aligned by the arrival of their first samples, RTSP cameras don't send B-frames, so pts = dts.
+func (p *rtspPlayer) emit(state *rtspTrackState, rtpTs uint32, extraTicks int64, frame []byte) {
+	if p.start.IsZero() {
+		p.start = time.Now()
+	}
+	if !state.started {
+		state.started = true
+		state.last = rtpTs
+		state.base = time.Since(p.start).Milliseconds()
+	}
+	state.elapsed += int64(int32(rtpTs - state.last))
+	state.last = rtpTs
+	ts := max(state.base+(state.elapsed+extraTicks)*1000/state.clockRate, 0)
+	p.batcher.add(state.cid, uint32(ts), uint32(ts), frame)
+}
+
+// listenRtpPair opens the RTP socket on an even port and the RTCP socket on the next one.
+func listenRtpPair() (*net.UDPConn, *net.UDPConn, error) {
+	for i := 0; i < 20; i++ {
+		rtp, err := net.ListenUDP("udp", &net.UDPAddr{})
+		if err != nil {
+			return nil, nil, err
+		}
+		port := rtp.LocalAddr().(*net.UDPAddr).Port
+		if port%2 != 0 {
+			_ = rtp.Close()
+			continue
+		}
+		rtcp, err := net.ListenUDP("udp", &net.UDPAddr{Port: port + 1})
+		if err != nil {
+			_ = rtp.Close()
+			continue
+		}
+		return rtp, rtcp, nil
+	}
+	return nil, nil, errors.New("no free UDP port pair")
+}
@@ -0,0 +1,75 @@
+package medias
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+)
+
+const udpReadBuffer = 4 << 20
+
+// playUdp listens on the host and port of the URL, a multicast host is joined on the interface
+// given by the iface query parameter or on the default one. The source is playing from the first frame.
+func (s *PullSource) playUdp() (played bool, err error) {
+	conn, err := listenUdp(s.url.Host, s.url.Query().Get("iface"))
+	if err != nil {
+		return false, err
+	}
+	if !s.attach(conn) {
+		return false, net.ErrClosed
+	}
+	defer conn.Close()
+	_ = conn.SetReadBuffer(udpReadBuffer)
+
+	var demuxer *TSDemuxer
+	// Silence only ends the session once it's playing, the listener waits for the sender otherwise
+	read := func(b []byte) (int, error) {
+		if demuxer.Played() {
+			_ = conn.SetReadDeadline(time.Now().Add(pullReadTimeout))
+		}
+		n, err := conn.Read(b)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			err = fmt.Errorf("no data for %s", pullReadTimeout)
+		}
+		return n, err
+	}
+	onStart := func() error {
+		if err := s.handler.OnSourceStart(); err != nil {
+			return err
+		}
+		log.Printf("UDPPullSource (%s) of %s is playing", s.redactedURL(), s.streamName)
+		s.setState(PullPlaying)
+		return nil
+	}
+	demuxer = NewTSDemuxer(read, onStart, s.handler.OnSourceBatch)
+	defer func() {
+		if demuxer.Played() {
+			s.handler.OnSourceStop()
+		}
+	}()
+
+	err = demuxer.Run(func(err error) {
+		log.Printf("UDPPullSource (%s) of %s: demuxer error %v", s.redactedURL(), s.streamName, err)
+	})
+	return demuxer.Played(), err
+}
+
+func listenUdp(address, iface string) (*net.UDPConn, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	if addr.IP == nil || !addr.IP.IsMulticast() {
+		return net.ListenUDP("udp", addr)
+	}
+	var ifi *net.Interface
+	if iface != "" {
+		if ifi, err = net.InterfaceByName(iface); err != nil {
+			return nil, err
+		}
+	}
+	return net.ListenMulticastUDP("udp", ifi, addr)
+}
@@ -0,0 +1,129 @@
+package medias
+
+import (
+	"github.com/yapingcat/gomedia/go-codec"
+	"github.com/yapingcat/gomedia/go-mpeg2"
+)
+
+// PES timestamps are 33 bit at 90 kHz, the demuxer reports them in ms
+const tsWrap = int64(1<<33) / 90
+
+// TSDemuxer demuxes MPEG-TS carried in datagrams, like those of a UDP socket or an SRT connection,
+// into batches of frames with timestamps in ms since the first frame.
+type TSDemuxer struct {
+	read    func(b []byte) (int, error) // returns one datagram
+	onStart func() error                // called before the first frame, an error stops the demuxer
+	batcher frameBatcher
+
+	buf     []byte
+	pending []byte
+	stopErr error // set by the reader or the frame callback
+	played  bool
+
+	started bool
+	last    int64 // last unwrapped timestamp of any track, ms
+	origin  int64 // unwrapped timestamp of the first frame
+}
+
+func NewTSDemuxer(read func(b []byte) (int, error), onStart func() error, onBatch func(batch *MediaFrameBatch)) *TSDemuxer {
+	return &TSDemuxer{
+		read:    read,
+		onStart: onStart,
+		batcher: frameBatcher{flush: onBatch},
+		buf:     make([]byte, 65536),
+	}
+}
+
+// Played reports whether the first frame has been passed on.
+func (d *TSDemuxer) Played() bool {
+	return d.played
+}
+
+// Run demuxes until reading fails or onStart returns an error. A lost datagram may break the
+// packet being demuxed, the demuxer starts over from the next one after reporting it to onBroken.
+func (d *TSDemuxer) Run(onBroken func(err error)) error {
+	for {
+		demuxer := mpeg2.NewTSDemuxer()
+		demuxer.OnFrame = d.onFrame
+		err := demuxer.Input(d)
+		if d.stopErr != nil {
+			return d.stopErr
+		}
+		onBroken(err)
+		d.pending = nil
+	}
+}
+
+// Read feeds the demuxer with the datagrams.
+func (d *TSDemuxer) Read(b []byte) (int, error) {
+	if d.stopErr != nil {
+		return 0, d.stopErr
+	}
+	for len(d.pending) == 0 {
+		n, err := d.read(d.buf)
+		if err != nil {
+			d.stopErr = err
+			return 0, err
+		}
+		d.pending = d.buf[:n]
+	}
+	n := copy(b, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+func (d *TSDemuxer) onFrame(streamType mpeg2.TS_STREAM_TYPE, frame []byte, pts, dts uint64) {
+	var cid codec.CodecID
+	switch streamType {
+	case mpeg2.TS_STREAM_H264:
+		cid = codec.CODECID_VIDEO_H264
+	case mpeg2.TS_STREAM_H265:
+		cid = codec.CODECID_VIDEO_H265
+	case mpeg2.TS_STREAM_AAC:
+		cid = codec.CODECID_AUDIO_AAC
+	default:
+		return
+	}
+	if len(frame) == 0 || d.stopErr != nil {
+		return
+	}
+	if !d.played {
+		if err := d.onStart(); err != nil {
+			d.stopErr = err
+			return
+		}
+		d.played = true
+	}
+
+	outDts := d.unwrap(int64(dts))
+	outPts := outDts + wrapDiff(int64(pts)-int64(dts))
+	// The demuxer reuses its buffers
+	d.batcher.add(cid, uint32(max(outPts, 0)), uint32(max(outDts, 0)), append([]byte(nil), frame...))
+}
+
+// unwrap converts a wrapping PES timestamp into ms since the first frame. The tracks share the
+// reference, so they stay aligned when one of them wraps first.
+func (d *TSDemuxer) unwrap(ts int64) int64 {
+	if !d.started {
+		d.started = true
+		d.last = ts
+		d.origin = ts
+		return 0
+	}
+	unwrapped := d.last + wrapDiff(ts-d.last%tsWrap)
+	if unwrapped > d.last {
+		d.last = unwrapped
+	}
+	return unwrapped - d.origin
+}
+
+// wrapDiff maps a difference of wrapping timestamps into [-tsWrap/2, tsWrap/2).
+func wrapDiff(d int64) int64 {
+	d %= tsWrap
+	if d >= tsWrap/2 {
+		d -= tsWrap
+	} else if d < -tsWrap/2 {
+		d += tsWrap
+	}
+	return d
+}
@@ -0,0 +1,44 @@
+package medias
+
+import "testing"
+
+func TestWrapDiff(t *testing.T) {
+	tests := []struct {
+		d, want int64
+	}{
+		{0, 0},
+		{40, 40},
+		{-40, -40},
+		{40 - tsWrap, 40},
+		{tsWrap - 40, -40},
+		{tsWrap/2 - 1, tsWrap/2 - 1},
+		{tsWrap / 2, tsWrap/2 - tsWrap}, // tsWrap is odd
+		{-tsWrap / 2, -tsWrap / 2},
+		{3*tsWrap + 5, 5},
+	}
+	for _, tt := range tests {
+		if got := wrapDiff(tt.d); got != tt.want {
+			t.Errorf("wrapDiff(%d) = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	d := &TSDemuxer{}
+	steps := []struct {
+		ts, want int64
+	}{
+		{tsWrap - 100, 0},
+		{tsWrap - 60, 40},
+		{tsWrap - 80, 20}, // B-frame order goes back
+		{20, 120},         // wrapped
+		{tsWrap - 20, 80}, // the other track is still before the wrap
+		{60, 160},
+		{tsWrap / 4, tsWrap/4 + 100},
+	}
+	for i, step := range steps {
+		if got := d.unwrap(step.ts); got != step.want {
+			t.Fatalf("step %d: unwrap(%d) = %d, want %d", i, step.ts, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package medias
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
+	"github.com/yapingcat/gomedia/go-codec"
+)
+
+const (
+	webrtcMaxLatePackets   = 256
+	webrtcKeyframeInterval = 3 * time.Second
+)
+
+// AudioPolicy tells what happens to the Opus audio of WebRTC publishers, RTMP targets need AAC.
+type AudioPolicy string
+
+const (
+	AudioDrop      AudioPolicy = "drop"
+	AudioTranscode AudioPolicy = "transcode"
+)
+
+func ParseAudioPolicy(s string) (AudioPolicy, error) {
+	switch policy := AudioPolicy(s); policy {
+	case "":
+		return AudioDrop, nil
+	case AudioDrop:
+		return policy, nil
+	case AudioTranscode:
+		if NewAudioTranscoder == nil {
+			return "", fmt.Errorf("audio policy %q needs a transcoder, none is registered", s)
+		}
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown audio policy %q", s)
+}
+
+// AudioTranscoder converts the Opus packets of a WebRTC publisher to AAC frames with ADTS headers.
+type AudioTranscoder interface {
+	// Transcode is called with an Opus packet and its timestamp in ms, it returns the AAC frames
+	// completed by the packet, possibly none.
+	Transcode(packet []byte, ts uint32) ([]MediaFrame, error)
+	Close() error
+}
+
+// NewAudioTranscoder creates the transcoder of a publisher under the AudioTranscode policy.
+// It is nil until a build with an audio codec library sets it.
+var NewAudioTranscoder func(sampleRate, channels int) (AudioTranscoder, error)
+
+// WebRTCTrack is a remote track of a WebRTC publisher.
+type WebRTCTrack interface {
+	Codec() webrtc.RTPCodecParameters
+	ReadRTP() (*rtp.Packet, interceptor.Attributes, error)
+}
+
+// WebRTCDemuxer depacketizes the H.264 and Opus tracks of a WebRTC publisher into batches of frames.
+// Tracks are aligned by the arrival of their first packets like those of an RTSP source.
+type WebRTCDemuxer struct {
+	policy AudioPolicy
+
+	mu      sync.Mutex // the tracks are read concurrently
+	start   time.Time
+	batcher frameBatcher
+}
+
+func NewWebRTCDemuxer(policy AudioPolicy, onBatch func(batch *MediaFrameBatch)) *WebRTCDemuxer {
+	return &WebRTCDemuxer{
+		policy:  policy,
+		batcher: frameBatcher{flush: onBatch},
+	}
+}
+
+// ReadTrack demuxes the track until it ends. requestKeyframe sends a PLI to the publisher,
+// which is done periodically since browsers only send keyframes on demand.
+func (d *WebRTCDemuxer) ReadTrack(track WebRTCTrack, requestKeyframe func()) error {
+	mimeType := track.Codec().MimeType
+	switch {
+	case strings.EqualFold(mimeType, webrtc.MimeTypeH264):
+		return d.readVideo(track, requestKeyframe)
+	case strings.EqualFold(mimeType, webrtc.MimeTypeOpus):
+		return d.readAudio(track)
+	}
+	return fmt.Errorf("unsupported %s track", mimeType)
+}
+
+func (d *WebRTCDemuxer) readVideo(track WebRTCTrack, requestKeyframe func()) error {
+	clock := &rtpClock{rate: int64(track.Codec().ClockRate)}
+	builder := samplebuilder.New(webrtcMaxLatePackets, &codecs.H264Packet{}, track.Codec().ClockRate)
+
+	requestKeyframe()
+	lastRequest := time.Now()
+	for {
+		packet, _, err := track.ReadRTP()
+		if err != nil {
+			return err
+		}
+		builder.Push(packet)
+		for sample := builder.Pop(); sample != nil; sample = builder.Pop() {
+			if sample.PrevDroppedPackets > 0 {
+				// The decoder of the targets needs a keyframe to recover from the gap
+				requestKeyframe()
+				lastRequest = time.Now()
+			}
+			d.emit(clock, codec.CODECID_VIDEO_H264, sample.PacketTimestamp, sample.Data)
+		}
+		if time.Since(lastRequest) >= webrtcKeyframeInterval {
+			requestKeyframe()
+			lastRequest = time.Now()
+		}
+	}
+}
+
+func (d *WebRTCDemuxer) readAudio(track WebRTCTrack) error {
+	if d.policy != AudioTranscode {
+		// The packets are still read, the receiver blocks when its buffer is full
+		for {
+			if _, _, err := track.ReadRTP(); err != nil {
+				return err
+			}
+		}
+	}
+
+	transcoder, err := NewAudioTranscoder(int(track.Codec().ClockRate), int(track.Codec().Channels))
+	if err != nil {
+		return err
+	}
+	defer transcoder.Close()
+	clock := &rtpClock{rate: int64(track.Codec().ClockRate)}
+	for {
+		packet, _, err := track.ReadRTP()
+		if err != nil {
+			return err
+		}
+		if len(packet.Payload) == 0 {
+			continue
+		}
+		d.mu.Lock()
+		ts := d.timestamp(clock, packet.Timestamp)
+		d.mu.Unlock()
+		frames, err := transcoder.Transcode(packet.Payload, ts)
+		if err != nil {
+			return err
+		}
+		d.mu.Lock()
+		for _, frame := range frames {
+			d.batcher.add(codec.CODECID_AUDIO_AAC, frame.Pts, frame.Dts, frame.Frame)
+		}
+		d.mu.Unlock()
+	}
+}
+
+func (d *WebRTCDemuxer) emit(clock *rtpClock, cid codec.CodecID, rtpTs uint32, frame []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Browsers don't send B-frames, so pts = dts
+	ts := d.timestamp(clock, rtpTs)
+	d.batcher.add(cid, ts, ts, frame)
+}
+
+// timestamp is called with mu held and returns the ms since the start of the session.
+func (d *WebRTCDemuxer) timestamp(clock *rtpClock, rtpTs uint32) uint32 {
+	if d.start.IsZero() {
+		d.start = time.Now()
+	}
+	if !clock.started {
+		clock.started = true
+		clock.last = rtpTs
+		clock.base = time.Since(d.start).Milliseconds()
+	}
+	clock.elapsed += int64(int32(rtpTs - clock.last))
+	clock.last = rtpTs
+	return uint32(max(clock.base+clock.elapsed*1000/clock.rate, 0))
+}
+
+// rtpClock unwraps the RTP timestamps of a track.
+type rtpClock struct {
+	rate    int64
+	started bool
+	base    int64  // ms from the start of the session to the first packet
+	last    uint32 // last RTP timestamp
+	elapsed int64  // RTP clock ticks since the first packet
+}
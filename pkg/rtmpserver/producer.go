@@ -1,6 +1,7 @@
 package rtmpserver
 
 import (
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/events"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
 	"github.com/yapingcat/gomedia/go-codec"
@@ -12,6 +13,7 @@ import (
 
 type MediaProducer struct {
 	name               string
+	backup             bool // backup source of a failover stream
 	session            *MediaSession
 	mtx                sync.Mutex
 	frames             chan *medias.MediaFrame
@@ -21,9 +23,10 @@ type MediaProducer struct {
 	stream             *registry.Stream
 }
 
-func newMediaProducer(name string, sess *MediaSession, stream *registry.Stream) *MediaProducer {
+func newMediaProducer(name string, backup bool, sess *MediaSession, stream *registry.Stream) *MediaProducer {
 	return &MediaProducer{
 		name:               name,
+		backup:             backup,
 		session:            sess,
 		currentFramesBatch: nil,
 		quit:               make(chan struct{}),
@@ -50,24 +53,15 @@ func (prod *MediaProducer) start() {
 
 		since := time.Since(prod.currentFramesBatch.StartTime)
 		if since >= time.Second || isIFrame {
-			var bytes int
-			for _, mediaFrame := range prod.currentFramesBatch.Frames {
-				bytes += len(mediaFrame.Frame)
+			// Frames of standby publishers are dropped by the stream
+			if prod.stream.OnPublisherBatch(prod, prod.currentFramesBatch) {
+				_ = sess.registry.UpdateStatus(prod.name, prod.currentFramesBatch.StartTime, medias.EvaluateBitrate(prod.currentFramesBatch.Size(), since))
 			}
-			_ = sess.registry.UpdateStatus(prod.name, prod.currentFramesBatch.StartTime, evaluateBitrate(bytes, since))
-			prod.stream.OnFrameBatch(prod.currentFramesBatch)
 			prod.currentFramesBatch = nil
 		}
 	})
 }
 
-func evaluateBitrate(bytes int, since time.Duration) uint {
-	if since == 0 {
-		return 0
-	}
-	return uint(time.Duration(bytes) * time.Second / since / 128)
-}
-
 func (prod *MediaProducer) stop() {
 	prod.die.Do(func() {
 		close(prod.quit)
@@ -76,12 +70,37 @@ func (prod *MediaProducer) stop() {
 }
 
 func (prod *MediaProducer) Close() error {
-	prod.stream.OnProducerClose()
+	wasActive := prod.stream.ReleasePublisher(prod)
 	prod.stop()
-	_ = prod.session.registry.UpdateStatus(prod.name, time.Unix(0, 0), 0)
+	prod.session.publishEvent(events.PublishStopped, prod.name)
+	if wasActive && !prod.stream.HasPublisher() {
+		_ = prod.session.registry.UpdateStatus(prod.name, time.Unix(0, 0), 0)
+	}
 	return nil
 }
 
+func (prod *MediaProducer) Id() string {
+	return prod.session.id
+}
+
+func (prod *MediaProducer) IsBackup() bool {
+	return prod.backup
+}
+
+func (prod *MediaProducer) SetState(state registry.PublisherState) {
+	prod.session.setPublishState(state)
+	if state == registry.PublisherActive {
+		prod.session.publishEvent(events.PublishPromoted, prod.name)
+	} else {
+		prod.session.publishEvent(events.PublishDemoted, prod.name)
+	}
+}
+
+func (prod *MediaProducer) Disconnect() {
+	prod.session.publishEvent(events.PublishTakeover, prod.name)
+	_ = prod.session.Close()
+}
+
 func (prod *MediaProducer) debugName() string {
 	if prod.session == nil {
 		return prod.name
@@ -0,0 +1,421 @@
+package rtmpserver
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/events"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/utils"
+	"github.com/yapingcat/gomedia/go-codec"
+	"github.com/yapingcat/gomedia/go-rtsp"
+	"github.com/yapingcat/gomedia/go-rtsp/sdp"
+)
+
+const (
+	rtspProbeTimeout = 5 * time.Second // for the tracks of the stream in DESCRIBE
+	rtspWriteTimeout = 10 * time.Second
+
+	rtspMethodNotAllowed = 405
+	rtspForbidden        = 403
+	rtspInvalidState     = 455
+)
+
+// rtspUDP are the RTP and RTCP sockets shared by the players which set up UDP transports.
+type rtspUDP struct {
+	rtp  *net.UDPConn
+	rtcp *net.UDPConn
+}
+
+func listenRTSPUDP(port int) (*rtspUDP, error) {
+	rtpConn, err := net.ListenUDP("udp", &net.UDPAddr{Port: port})
+	if err != nil {
+		return nil, err
+	}
+	rtcpConn, err := net.ListenUDP("udp", &net.UDPAddr{Port: port + 1})
+	if err != nil {
+		_ = rtpConn.Close()
+		return nil, err
+	}
+	return &rtspUDP{rtp: rtpConn, rtcp: rtcpConn}, nil
+}
+
+// rtspSession is a connection of an RTSP player. The gomedia server handles the protocol and calls
+// back the session, which plays the stream with an RTSPConsumer over the TCP connection or UDP.
+type rtspSession struct {
+	id        string
+	conn      net.Conn
+	counter   *countingConn
+	udp       *rtspUDP
+	udpOut    atomic.Uint64
+	since     time.Time
+	handle    *rtsp.RtspServer
+	writeMu   sync.Mutex
+	server    *MediaServer
+	die       sync.Once
+	startPlay func() // after the response to PLAY is written
+
+	infoMu sync.Mutex
+	role   string
+	app    string
+	stream string
+
+	consumer *medias.RTSPConsumer
+	tracks   []*rtspTrack
+	playing  bool
+}
+
+type rtspTrack struct {
+	media *medias.RTSPTrack
+	track *rtsp.RtspTrack
+	uri   string // of the SETUP request
+	setup bool
+
+	rtpAddr  *net.UDPAddr // of a UDP player
+	rtcpAddr *net.UDPAddr
+}
+
+func (s *MediaServer) serveRTSP(listen net.Listener) {
+	for {
+		conn, err := listen.Accept()
+		if err != nil {
+			log.Printf("Failed to accept RTSP connection: %v", err)
+			continue
+		}
+		counter := &countingConn{Conn: conn}
+		sess := &rtspSession{
+			id:      utils.GenId(),
+			conn:    counter,
+			counter: counter,
+			udp:     s.rtspUDP,
+			since:   time.Now(),
+			server:  s,
+		}
+		sess.handle = rtsp.NewRtspServer(sess)
+		sess.handle.SetOutput(sess.write)
+		s.mu.Lock()
+		s.sessions[sess.id] = sess
+		s.mu.Unlock()
+		go func() {
+			sess.start()
+			s.mu.Lock()
+			delete(s.sessions, sess.id)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (sess *rtspSession) start() {
+	defer sess.stop()
+	buf := make([]byte, 65536)
+	for {
+		n, err := sess.conn.Read(buf)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Printf("RTSP session read error: %v", err)
+			}
+			return
+		}
+		if err := sess.input(buf[:n]); err != nil {
+			log.Printf("RTSP session %s from %s closed: %v", sess.id, sess.conn.RemoteAddr(), err)
+			return
+		}
+		if sess.startPlay != nil {
+			go sess.startPlay()
+			sess.startPlay = nil
+		}
+	}
+}
+
+func (sess *rtspSession) input(data []byte) (err error) {
+	defer func() {
+		// The gomedia server panics on some malformed requests
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed request: %v", r)
+		}
+	}()
+	return sess.handle.Input(data)
+}
+
+func (sess *rtspSession) write(data []byte) error {
+	sess.writeMu.Lock()
+	defer sess.writeMu.Unlock()
+	_ = sess.conn.SetWriteDeadline(time.Now().Add(rtspWriteTimeout))
+	_, err := sess.conn.Write(data)
+	return err
+}
+
+func (sess *rtspSession) stop() {
+	sess.die.Do(func() {
+		if sess.consumer != nil {
+			_ = sess.consumer.Close()
+		}
+		_ = sess.conn.Close()
+	})
+}
+
+func (sess *rtspSession) HandleOption(svr *rtsp.RtspServer, req rtsp.RtspRequest, res *rtsp.RtspResponse) {
+}
+
+// HandleDescribe authorizes the player like an RTMP one and describes the tracks of the stream,
+// which are probed from its last keyframe.
+func (sess *rtspSession) HandleDescribe(svr *rtsp.RtspServer, req rtsp.RtspRequest, res *rtsp.RtspResponse) {
+	if sess.consumer != nil {
+		return
+	}
+	app, streamName, query, err := parseRTSPURL(req.Uri)
+	if err != nil {
+		res.StatusCode = rtsp.BAD_REQUEST
+		return
+	}
+	if _, err := sess.server.callbacks.authorize("play", sess.conn.RemoteAddr().String(), sess.id, app, streamName, query); err != nil {
+		log.Printf("Play of %s rejected: %v", streamName, err)
+		res.StatusCode = rtspForbidden
+		res.Reason = "Forbidden"
+		return
+	}
+
+	stream, err := sess.server.registry.GetInternalStream(streamName)
+	if err != nil {
+		log.Printf("Failed to get InternalStreamer for RTSP player %s: %v", streamName, err)
+		res.StatusCode = rtsp.Not_Found
+		return
+	} else if stream == nil {
+		res.StatusCode = rtsp.Not_Found
+		return
+	}
+	consumer := medias.NewRTSPConsumer(streamName)
+	stream.AddPlayer(consumer)
+	tracks := consumer.Tracks(rtspProbeTimeout)
+	if len(tracks) == 0 {
+		log.Printf("RTSP player of %s from %s: no tracks to play", streamName, sess.conn.RemoteAddr())
+		_ = consumer.Close()
+		res.StatusCode = rtsp.Not_Found
+		return
+	}
+
+	sess.consumer = consumer
+	for _, media := range tracks {
+		track := newRTSPServerTrack(media)
+		// Until SETUP, so that the server can match RTCP of the player against the tracks
+		track.SetTransport(rtsp.NewRtspTransport(rtsp.WithTcpInterleaved([2]int{-1, -1})))
+		svr.AddTrack(track)
+		sess.tracks = append(sess.tracks, &rtspTrack{media: media, track: track})
+	}
+	sess.setRole("", app, streamName)
+}
+
+func newRTSPServerTrack(media *medias.RTSPTrack) *rtsp.RtspTrack {
+	switch media.Cid {
+	case codec.CODECID_VIDEO_H264:
+		fmtp := sdp.NewH264FmtpParam(sdp.WithProfileLevelId(media.SPS[1:4]), sdp.WithH264SPS(media.SPS), sdp.WithH264PPS(media.PPS))
+		return rtsp.NewVideoTrack(rtsp.NewVideoCodec("H264", media.PayloadType, media.ClockRate), rtsp.WithCodecParamHandler(fmtp))
+	case codec.CODECID_VIDEO_H265:
+		fmtp := sdp.NewH265FmtpParam(sdp.WithH265VPS(media.VPS), sdp.WithH265SPS(media.SPS), sdp.WithH265PPS(media.PPS))
+		return rtsp.NewVideoTrack(rtsp.NewVideoCodec("H265", media.PayloadType, media.ClockRate), rtsp.WithCodecParamHandler(fmtp))
+	default:
+		fmtp := sdp.NewAACFmtpParam(sdp.WithAudioSpecificConfig(media.ASC))
+		return rtsp.NewAudioTrack(rtsp.NewAudioCodec("mpeg4-generic", media.PayloadType, media.ClockRate, media.Channels), rtsp.WithCodecParamHandler(fmtp))
+	}
+}
+
+// parseRTSPURL takes the stream name from the last path segment of rtsp://host/[app/]name?key=value.
+func parseRTSPURL(uri string) (app, name string, query url.Values, err error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", "", nil, err
+	}
+	path := strings.Trim(u.Path, "/")
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		app, name = path[:i], path[i+1:]
+	} else {
+		name = path
+	}
+	if name == "" {
+		return "", "", nil, errors.New("no stream name")
+	}
+	return app, name, u.Query(), nil
+}
+
+// HandleSetup accepts unicast TCP transports, and UDP ones if the RTP port is configured.
+// The gomedia server assigns the interleaved channels of TCP.
+func (sess *rtspSession) HandleSetup(svr *rtsp.RtspServer, req rtsp.RtspRequest, res *rtsp.RtspResponse, transport *rtsp.RtspTransport, track *rtsp.RtspTrack) {
+	var t *rtspTrack
+	for _, candidate := range sess.tracks {
+		if candidate.track == track {
+			t = candidate
+		}
+	}
+	if t == nil {
+		res.StatusCode = rtsp.Not_Found
+		return
+	} else if sess.playing {
+		res.StatusCode = rtspInvalidState
+		res.Reason = "Method Not Valid in This State"
+		return
+	} else if transport.IsMultiCast {
+		res.StatusCode = rtsp.Unsupported_Transport
+		return
+	}
+
+	if transport.Proto == rtsp.UDP {
+		addr, ok := sess.conn.RemoteAddr().(*net.TCPAddr)
+		if sess.udp == nil || !ok || transport.Client_ports[0] == 0 {
+			res.StatusCode = rtsp.Unsupported_Transport
+			return
+		}
+		rtcpPort := transport.Client_ports[1]
+		if rtcpPort == 0 {
+			rtcpPort = transport.Client_ports[0] + 1
+		}
+		t.rtpAddr = &net.UDPAddr{IP: addr.IP, Port: int(transport.Client_ports[0])}
+		t.rtcpAddr = &net.UDPAddr{IP: addr.IP, Port: int(rtcpPort)}
+		transport.Server_ports[0] = uint16(sess.udp.rtp.LocalAddr().(*net.UDPAddr).Port)
+		transport.Server_ports[1] = uint16(sess.udp.rtcp.LocalAddr().(*net.UDPAddr).Port)
+	} else {
+		t.rtpAddr, t.rtcpAddr = nil, nil
+	}
+	t.uri = req.Uri
+	t.setup = true
+}
+
+func (sess *rtspSession) HandleAnnounce(svr *rtsp.RtspServer, req rtsp.RtspRequest, tracks map[string]*rtsp.RtspTrack) {
+	// The gomedia server accepts ANNOUNCE unconditionally, publishing over RTSP isn't supported
+	log.Printf("RTSP session %s from %s tried to publish", sess.id, sess.conn.RemoteAddr())
+	_ = sess.conn.Close()
+}
+
+// HandlePlay starts the RTP of the tracks which were set up once the response is written.
+func (sess *rtspSession) HandlePlay(svr *rtsp.RtspServer, req rtsp.RtspRequest, res *rtsp.RtspResponse, timeRange *rtsp.RangeTime, info []*rtsp.RtpInfo) {
+	var playing []*medias.RTSPTrack
+	var routes []*rtspTrack
+	var rtpInfo []string
+	for _, t := range sess.tracks {
+		if !t.setup {
+			continue
+		}
+		seq, timestamp := t.media.Start()
+		playing = append(playing, t.media)
+		routes = append(routes, t)
+		rtpInfo = append(rtpInfo, fmt.Sprintf("url=%s;seq=%d;rtptime=%d", t.uri, seq, timestamp))
+	}
+	if len(routes) == 0 {
+		res.StatusCode = rtspInvalidState
+		res.Reason = "Method Not Valid in This State"
+		return
+	}
+	res.Fileds["RTP-Info"] = strings.Join(rtpInfo, ",")
+	res.Fileds[rtsp.Range] = "npt=0.000-"
+	if sess.playing {
+		return
+	}
+	sess.playing = true
+
+	sess.infoMu.Lock()
+	app, streamName := sess.app, sess.stream
+	sess.infoMu.Unlock()
+	sess.setRole(RolePlayer, app, streamName)
+	sess.publishEvent(events.ViewerJoined, streamName)
+	consumer := sess.consumer
+	sess.startPlay = func() {
+		// The connection is closed once the stream ends as well
+		defer sess.stop()
+		err := consumer.Serve(playing, func(i int, rtcp bool, packet []byte) error {
+			return sess.writePacket(routes[i], rtcp, packet)
+		})
+		log.Printf("RTSP player of %s from %s left: %v", streamName, sess.conn.RemoteAddr(), err)
+	}
+}
+
+// writePacket sends the packet to the UDP ports of the player or interleaved in the connection.
+func (sess *rtspSession) writePacket(t *rtspTrack, rtcp bool, packet []byte) error {
+	if t.rtpAddr != nil {
+		conn, addr := sess.udp.rtp, t.rtpAddr
+		if rtcp {
+			conn, addr = sess.udp.rtcp, t.rtcpAddr
+		}
+		n, err := conn.WriteToUDP(packet, addr)
+		sess.udpOut.Add(uint64(n))
+		return err
+	}
+	channel := t.track.GetTransport().Interleaved[0]
+	if rtcp {
+		channel = t.track.GetTransport().Interleaved[1]
+	}
+	frame := make([]byte, 4, 4+len(packet))
+	frame[0] = '$'
+	frame[1] = byte(channel)
+	binary.BigEndian.PutUint16(frame[2:], uint16(len(packet)))
+	return sess.write(append(frame, packet...))
+}
+
+func (sess *rtspSession) HandlePause(svr *rtsp.RtspServer, req rtsp.RtspRequest, res *rtsp.RtspResponse) {
+	res.StatusCode = rtsp.Not_Implemented
+}
+
+func (sess *rtspSession) HandleTeardown(svr *rtsp.RtspServer, req rtsp.RtspRequest, res *rtsp.RtspResponse) {
+	if sess.consumer != nil {
+		_ = sess.consumer.Close()
+	}
+}
+
+func (sess *rtspSession) HandleGetParameter(svr *rtsp.RtspServer, req rtsp.RtspRequest, res *rtsp.RtspResponse) {
+}
+
+func (sess *rtspSession) HandleSetParameter(svr *rtsp.RtspServer, req rtsp.RtspRequest, res *rtsp.RtspResponse) {
+}
+
+func (sess *rtspSession) HandleRecord(svr *rtsp.RtspServer, req rtsp.RtspRequest, res *rtsp.RtspResponse, timeRange *rtsp.RangeTime, info []*rtsp.RtpInfo) {
+	res.StatusCode = rtspMethodNotAllowed
+	res.Reason = "Method Not Allowed"
+}
+
+func (sess *rtspSession) HandleResponse(svr *rtsp.RtspServer, res rtsp.RtspResponse) {
+}
+
+func (sess *rtspSession) setRole(role, app, streamName string) {
+	sess.infoMu.Lock()
+	defer sess.infoMu.Unlock()
+	sess.role = role
+	sess.app = app
+	sess.stream = streamName
+}
+
+func (sess *rtspSession) publishEvent(eventType events.Type, streamName string) {
+	events.Publish(events.Event{
+		Type:       eventType,
+		Stream:     streamName,
+		Session:    sess.id,
+		RemoteAddr: sess.conn.RemoteAddr().String(),
+		Data:       map[string]interface{}{"secure": false, "protocol": ProtocolRTSP},
+	})
+}
+
+func (sess *rtspSession) Info() SessionInfo {
+	sess.infoMu.Lock()
+	defer sess.infoMu.Unlock()
+	return SessionInfo{
+		Id:         sess.id,
+		Protocol:   ProtocolRTSP,
+		RemoteAddr: sess.conn.RemoteAddr().String(),
+		Role:       sess.role,
+		App:        sess.app,
+		Stream:     sess.stream,
+		Since:      sess.since,
+		BytesIn:    sess.counter.in.Load(),
+		BytesOut:   sess.counter.out.Load() + sess.udpOut.Load(),
+	}
+}
+
+func (sess *rtspSession) Close() error {
+	return sess.conn.Close()
+}
@@ -1,43 +1,128 @@
 package rtmpserver
 
 import (
+	"crypto/tls"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/srt"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/tlsutil"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/utils"
 	"github.com/yapingcat/gomedia/go-rtmp"
 	"log"
 	"net"
 	"strconv"
+	"time"
 )
 
 func prepareConfig(config MediaServerConfig) MediaServerConfig {
 	if config.Port == 0 {
 		config.Port = 1935
 	}
+	if len(config.ListenAddrs) == 0 {
+		config.ListenAddrs = []string{":" + strconv.Itoa(config.Port)}
+	}
+	if config.TLSPort != 0 && len(config.TLSListenAddrs) == 0 {
+		config.TLSListenAddrs = []string{":" + strconv.Itoa(config.TLSPort)}
+	}
+	if config.CallbackTimeout == 0 {
+		config.CallbackTimeout = 5 * time.Second
+	}
+	if config.WHIPAudioPolicy == "" {
+		config.WHIPAudioPolicy = medias.AudioDrop
+	}
 	return config
 }
 
 func NewMediaServer(config MediaServerConfig, registry registry.Registry) *MediaServer {
 	config = prepareConfig(config)
+	// The API server accepts WHIP publishers before Start
+	whip, err := newWhipAPI(config)
+	if err != nil {
+		log.Fatalf("Failed to set up WHIP: %v", err)
+	}
 	return &MediaServer{
-		config:   config,
-		registry: registry,
-		sessions: make(map[string]*MediaSession),
+		config:    config,
+		registry:  registry,
+		sessions:  make(map[string]session),
+		callbacks: newCallbackClient(config),
+		whip:      whip,
 	}
 }
 
 func (s *MediaServer) Start() {
-	addr := "0.0.0.0:" + strconv.Itoa(s.config.Port)
-	listen, err := net.Listen("tcp4", addr)
-	if err != nil {
-		log.Fatalf("Failed to start RTMP server: %v", err)
+	for _, addr := range s.config.SRTListenAddrs {
+		listen, err := srt.Listen(addr, srt.ListenerConfig{
+			Latency:    s.config.SRTLatency,
+			Passphrase: s.config.SRTPassphrase,
+			Handler:    s.acceptSrt,
+		})
+		if err != nil {
+			log.Fatalf("Failed to start SRT server on %s: %v", addr, err)
+		}
+		log.Printf("SRT server listening on %s", listen.Addr())
+		go func() {
+			if err := listen.Serve(); err != nil {
+				log.Printf("SRT server on %s stopped: %v", listen.Addr(), err)
+			}
+		}()
+	}
+
+	if len(s.config.TLSListenAddrs) > 0 {
+		reloader, err := tlsutil.NewCertReloader(s.config.TLSCertFile, s.config.TLSKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load RTMPS certificate: %v", err)
+		}
+		for _, addr := range s.config.TLSListenAddrs {
+			listen, err := tls.Listen("tcp", addr, reloader.TLSConfig())
+			if err != nil {
+				log.Fatalf("Failed to start RTMPS server on %s: %v", addr, err)
+			}
+			log.Printf("RTMPS server listening on %s", listen.Addr())
+			go s.serve(listen, true)
+		}
+	}
+
+	// RTSP is disabled without listeners, the UDP ports only serve their players
+	if s.config.RTSPUDPPort != 0 && len(s.config.RTSPListenAddrs) > 0 {
+		udp, err := listenRTSPUDP(s.config.RTSPUDPPort)
+		if err != nil {
+			log.Fatalf("Failed to listen for RTSP over UDP on port %d: %v", s.config.RTSPUDPPort, err)
+		}
+		log.Printf("RTSP RTP/RTCP over UDP on ports %d-%d", s.config.RTSPUDPPort, s.config.RTSPUDPPort+1)
+		s.rtspUDP = udp
+	}
+	for _, addr := range s.config.RTSPListenAddrs {
+		listen, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatalf("Failed to start RTSP server on %s: %v", addr, err)
+		}
+		log.Printf("RTSP server listening on %s", listen.Addr())
+		go s.serveRTSP(listen)
 	}
+
+	listeners := make([]net.Listener, 0, len(s.config.ListenAddrs))
+	for _, addr := range s.config.ListenAddrs {
+		listen, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatalf("Failed to start RTMP server on %s: %v", addr, err)
+		}
+		log.Printf("RTMP server listening on %s", listen.Addr())
+		listeners = append(listeners, listen)
+	}
+	for _, listen := range listeners[1:] {
+		go s.serve(listen, false)
+	}
+	s.serve(listeners[0], false)
+}
+
+func (s *MediaServer) serve(listen net.Listener, secure bool) {
 	for {
 		conn, err := listen.Accept()
 		if err != nil {
 			log.Printf("Failed to accept connection: %v", err)
 			continue
 		}
-		sess := s.newMediaSession(conn)
+		sess := s.newMediaSession(conn, secure)
 		s.mu.Lock()
 		s.sessions[sess.id] = sess
 		s.mu.Unlock()
@@ -51,12 +136,17 @@ func (s *MediaServer) Start() {
 	}
 }
 
-func (s *MediaServer) newMediaSession(conn net.Conn) *MediaSession {
+func (s *MediaServer) newMediaSession(conn net.Conn, secure bool) *MediaSession {
+	counter := &countingConn{Conn: conn}
 	return &MediaSession{
-		id:       utils.GenId(),
-		conn:     conn,
-		handle:   rtmp.NewRtmpServerHandle(),
-		quit:     make(chan struct{}),
-		registry: s.registry,
+		id:        utils.GenId(),
+		conn:      counter,
+		counter:   counter,
+		secure:    secure,
+		since:     time.Now(),
+		handle:    rtmp.NewRtmpServerHandle(),
+		quit:      make(chan struct{}),
+		registry:  s.registry,
+		callbacks: s.callbacks,
 	}
 }
@@ -3,23 +3,38 @@ package rtmpserver
 import (
 	"errors"
 	"fmt"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/events"
 	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
 	"github.com/yapingcat/gomedia/go-rtmp"
 	"io"
 	"log"
 	"net"
+	"strconv"
 	"sync"
+	"time"
 )
 
 type MediaSession struct {
-	id     string
-	conn   net.Conn
-	handle *rtmp.RtmpServerHandle
+	id      string
+	conn    net.Conn
+	counter *countingConn
+	secure  bool // accepted on the RTMPS listener
+	since   time.Time
+	handle  *rtmp.RtmpServerHandle
 
-	quit     chan struct{}
-	resource io.Closer
-	die      sync.Once //?
-	registry registry.Registry
+	// Reported by Info, set once the client publishes or plays
+	infoMu       sync.Mutex
+	role         string
+	app          string
+	stream       string
+	flashVer     string
+	publishState registry.PublisherState
+
+	quit      chan struct{}
+	resource  io.Closer
+	die       sync.Once //?
+	registry  registry.Registry
+	callbacks *callbackClient
 
 	producer     *MediaProducer
 	pullConsumer *PullConsumer
@@ -32,6 +47,12 @@ func (sess *MediaSession) init() {
 	})
 
 	sess.handle.OnPlay(func(app, streamName string, start, duration float64, reset bool) rtmp.StatusCode {
+		streamName, query := splitStreamName(streamName)
+		if _, err := sess.callbacks.authorize("play", sess.conn.RemoteAddr().String(), sess.id, app, streamName, query); err != nil {
+			log.Printf("Play of %s rejected: %v", streamName, err)
+			return rtmp.NETSTREAM_PLAY_NOTFOUND
+		}
+
 		stream, err := sess.registry.GetInternalStream(streamName)
 		if err != nil {
 			log.Printf("Failed to get InternalStreamer for pull consumer %s: %v", streamName, err)
@@ -42,10 +63,19 @@ func (sess *MediaSession) init() {
 		}
 		sess.pullConsumer = NewPullConsumer(sess, streamName)
 		stream.AddConsumer(sess.pullConsumer)
+		sess.setRole(RolePlayer, app, streamName)
+		sess.publishEvent(events.ViewerJoined, streamName)
 		return rtmp.NETSTREAM_PLAY_START
 	})
 
 	sess.handle.OnPublish(func(app, streamName string) rtmp.StatusCode {
+		streamName, query := splitStreamName(streamName)
+		callback, err := sess.callbacks.authorize("publish", sess.conn.RemoteAddr().String(), sess.id, app, streamName, query)
+		if err != nil {
+			log.Printf("Publish of %s rejected: %v", streamName, err)
+			return rtmp.NETCONNECT_CONNECT_REJECTED
+		}
+
 		stream, err := sess.registry.GetInternalStream(streamName)
 		if err != nil {
 			log.Printf("Failed to get %s stream info: %v", streamName, err)
@@ -53,10 +83,27 @@ func (sess *MediaSession) init() {
 		} else if stream == nil {
 			log.Printf("No such %s stream info", streamName)
 			return rtmp.NETCONNECT_CONNECT_REJECTED
+		} else if stream.RequireTLS && !sess.secure {
+			log.Printf("Stream %s requires RTMPS, rejecting plain RTMP publisher", streamName)
+			return rtmp.NETCONNECT_CONNECT_REJECTED
 		}
 
-		p := newMediaProducer(streamName, sess, stream)
+		backup, _ := strconv.ParseBool(query.Get("backup"))
+		p := newMediaProducer(streamName, backup, sess, stream)
+		state, err := stream.AcquirePublisher(p, callback.Targets)
+		if errors.As(err, &registry.PublisherConflict{}) {
+			log.Printf("Publish of %s rejected: %v", streamName, err)
+			sess.publishEvent(events.PublishRejected, streamName)
+			return rtmp.NETCONNECT_CONNECT_REJECTED
+		} else if err != nil {
+			log.Printf("Invalid targets from publish callback for %s: %v", streamName, err)
+			return rtmp.NETCONNECT_CONNECT_REJECTED
+		}
 		sess.producer = p
+		// Released by the producer even if the client goes away before publishing starts
+		sess.resource = p
+		sess.setRole(RolePublisher, app, streamName)
+		sess.setPublishState(state)
 
 		return rtmp.NETSTREAM_PUBLISH_START
 	})
@@ -71,13 +118,13 @@ func (sess *MediaSession) init() {
 			go sess.pullConsumer.sendToClient()
 			sess.resource = sess.pullConsumer
 		} else if newState == rtmp.STATE_RTMP_PUBLISH_START {
-			name := sess.handle.GetStreamName()
+			name := sess.producer.name
 			log.Printf("New rtmp stream %s", name)
 
-			sess.resource = sess.producer
 			sess.producer.start()
+			sess.publishEvent(events.PublishStarted, name)
 		} else if newState == rtmp.STATE_RTMP_PUBLISH_FAILED {
-			name := sess.handle.GetStreamName()
+			name, _ := splitStreamName(sess.handle.GetStreamName())
 			log.Printf("Failed rtmp stream %s", name)
 			sess.stop()
 		} else {
@@ -86,6 +133,56 @@ func (sess *MediaSession) init() {
 	})
 }
 
+func (sess *MediaSession) setRole(role, app, streamName string) {
+	sess.infoMu.Lock()
+	defer sess.infoMu.Unlock()
+	sess.role = role
+	sess.app = app
+	sess.stream = streamName
+	sess.flashVer = sess.handle.GetFlashVer()
+}
+
+func (sess *MediaSession) setPublishState(state registry.PublisherState) {
+	sess.infoMu.Lock()
+	defer sess.infoMu.Unlock()
+	sess.publishState = state
+}
+
+func (sess *MediaSession) Info() SessionInfo {
+	sess.infoMu.Lock()
+	defer sess.infoMu.Unlock()
+	return SessionInfo{
+		Id:           sess.id,
+		Protocol:     ProtocolRTMP,
+		RemoteAddr:   sess.conn.RemoteAddr().String(),
+		Role:         sess.role,
+		App:          sess.app,
+		Stream:       sess.stream,
+		FlashVer:     sess.flashVer,
+		PublishState: sess.publishState,
+		Secure:       sess.secure,
+		Since:        sess.since,
+		BytesIn:      sess.counter.in.Load(),
+		BytesOut:     sess.counter.out.Load(),
+	}
+}
+
+func (sess *MediaSession) publishEvent(eventType events.Type, streamName string) {
+	data := map[string]interface{}{"secure": sess.secure}
+	sess.infoMu.Lock()
+	if sess.publishState != "" {
+		data["publish_state"] = sess.publishState
+	}
+	sess.infoMu.Unlock()
+	events.Publish(events.Event{
+		Type:       eventType,
+		Stream:     streamName,
+		Session:    sess.id,
+		RemoteAddr: sess.conn.RemoteAddr().String(),
+		Data:       data,
+	})
+}
+
 func (sess *MediaSession) start() {
 	defer sess.stop()
 	buf := make([]byte, 65536)
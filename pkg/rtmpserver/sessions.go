@@ -0,0 +1,100 @@
+package rtmpserver
+
+import (
+	"net"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+)
+
+const (
+	RolePublisher = "publisher"
+	RolePlayer    = "player"
+
+	ProtocolRTMP = "rtmp"
+	ProtocolSRT  = "srt"
+
+	ProtocolHTTPFLV = "http-flv"
+	ProtocolWSFLV   = "ws-flv"
+	ProtocolWHIP    = "whip"
+	ProtocolRTSP    = "rtsp"
+)
+
+// session is a live connection of any protocol.
+type session interface {
+	Info() SessionInfo
+	Close() error
+}
+
+// SessionInfo describes a live RTMP, SRT, HTTP-FLV, WebSocket-FLV, WHIP or RTSP connection.
+type SessionInfo struct {
+	Id           string                  `json:"id"`
+	Protocol     string                  `json:"protocol"`
+	RemoteAddr   string                  `json:"remote_addr"`
+	Role         string                  `json:"role,omitempty"` // empty until the client publishes or plays
+	App          string                  `json:"app,omitempty"`
+	Stream       string                  `json:"stream,omitempty"`
+	FlashVer     string                  `json:"flash_ver,omitempty"`
+	PublishState registry.PublisherState `json:"publish_state,omitempty"` // "active" or "standby" for publishers
+	Secure       bool                    `json:"secure"`                  // RTMPS, an encrypted SRT connection, HTTPS or WebRTC
+	Since        time.Time               `json:"since"`
+	BytesIn      uint64                  `json:"bytes_in"`
+	BytesOut     uint64                  `json:"bytes_out"`
+}
+
+type SessionNotFound struct{}
+
+func (e SessionNotFound) Error() string {
+	return "SessionNotExist"
+}
+
+// Sessions returns the live sessions, oldest first.
+func (s *MediaServer) Sessions() []SessionInfo {
+	s.mu.Lock()
+	sessions := make([]session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.mu.Unlock()
+
+	infos := make([]SessionInfo, len(sessions))
+	for i, sess := range sessions {
+		infos[i] = sess.Info()
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Since.Before(infos[j].Since)
+	})
+	return infos
+}
+
+// CloseSession disconnects the session, which releases its stream.
+func (s *MediaServer) CloseSession(id string) error {
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	s.mu.Unlock()
+	if !ok {
+		return SessionNotFound{}
+	}
+	return sess.Close()
+}
+
+// countingConn counts the bytes transferred over the connection.
+type countingConn struct {
+	net.Conn
+	in  atomic.Uint64
+	out atomic.Uint64
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.in.Add(uint64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.out.Add(uint64(n))
+	return n, err
+}
@@ -0,0 +1,91 @@
+package rtmpserver
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/srt"
+)
+
+// acceptSrt authorizes a caller like an RTMP publisher, the stream ID carries the stream name and
+// the query parameters passed to the publish callback.
+func (s *MediaServer) acceptSrt(conn *srt.Conn) (func(), srt.RejectReason) {
+	app, streamName, query, err := parseSrtStreamID(conn.StreamID())
+	if errors.Is(err, errSrtModeNotSupported) {
+		log.Printf("SRT caller %s rejected: %v", conn.RemoteAddr(), err)
+		return nil, srt.RejectBadMode
+	} else if err != nil {
+		log.Printf("SRT caller %s rejected: %v", conn.RemoteAddr(), err)
+		return nil, srt.RejectBadRequest
+	}
+
+	counters := func() (uint64, uint64) {
+		stats := conn.Stats()
+		return stats.BytesIn, stats.BytesOut
+	}
+	sess := newIngestSession(ProtocolSRT, conn.RemoteAddr().String(), conn.Encrypted(), conn.Close, counters)
+	if err := s.acquireIngest(sess, app, streamName, query); err != nil {
+		switch {
+		case errors.As(err, &PublishUnauthorized{}):
+			return nil, srt.RejectUnauthorized
+		case errors.As(err, &registry.StreamNotFound{}):
+			return nil, srt.RejectNotFound
+		case errors.As(err, &PublishForbidden{}):
+			return nil, srt.RejectForbidden
+		case errors.As(err, &registry.PublisherConflict{}):
+			return nil, srt.RejectConflict
+		}
+		return nil, srt.RejectInternal
+	}
+
+	return func() {
+		defer s.releaseIngest(sess)
+		demuxer := medias.NewTSDemuxer(conn.Read, func() error { return nil }, sess.onBatch)
+		err := demuxer.Run(func(err error) {
+			log.Printf("SRT stream %s: demuxer error %v", streamName, err)
+		})
+		log.Printf("SRT stream %s from %s closed: %v", streamName, conn.RemoteAddr(), err)
+	}, 0
+}
+
+var (
+	errSrtModeNotSupported = errors.New("only publishing is supported")
+	errNoName              = errors.New("no stream name in the stream ID")
+)
+
+// parseSrtStreamID accepts the access control syntax "#!::r=[app/]name,m=publish,key=value"
+// and plain "[app/]name?key=value" stream IDs. The other keys become query parameters.
+func parseSrtStreamID(streamID string) (app, name string, query url.Values, err error) {
+	resource := streamID
+	query = url.Values{}
+	if rest, ok := strings.CutPrefix(streamID, "#!::"); ok {
+		resource = ""
+		for _, pair := range strings.Split(rest, ",") {
+			key, value, _ := strings.Cut(pair, "=")
+			switch key {
+			case "r":
+				resource = value
+			case "m":
+				if value != "publish" {
+					return "", "", nil, fmt.Errorf("%w, got mode %q", errSrtModeNotSupported, value)
+				}
+			default:
+				query.Add(key, value)
+			}
+		}
+	} else {
+		resource, query = splitStreamName(streamID)
+	}
+	if i := strings.LastIndex(resource, "/"); i >= 0 {
+		app, resource = resource[:i], resource[i+1:]
+	}
+	if resource == "" {
+		return "", "", nil, errNoName
+	}
+	return app, resource, query, nil
+}
@@ -0,0 +1,47 @@
+package rtmpserver
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseSrtStreamID(t *testing.T) {
+	tests := []struct {
+		streamID string
+		app      string
+		name     string
+		query    url.Values
+		err      error
+	}{
+		{"s1", "", "s1", url.Values{}, nil},
+		{"live/s1", "live", "s1", url.Values{}, nil},
+		{"live/sub/s1?token=abc&x=1", "live/sub", "s1", url.Values{"token": {"abc"}, "x": {"1"}}, nil},
+		{"#!::r=live/s1,m=publish", "live", "s1", url.Values{}, nil},
+		{"#!::m=publish,r=s1,token=abc,u=alice", "", "s1", url.Values{"token": {"abc"}, "u": {"alice"}}, nil},
+		{"#!::r=s1", "", "s1", url.Values{}, nil},
+		{"#!::r=s1,m=request", "", "", nil, errSrtModeNotSupported},
+		{"#!::m=publish", "", "", nil, errNoName},
+		{"#!::r=live/", "", "", nil, errNoName},
+		{"", "", "", nil, errNoName},
+		{"?token=abc", "", "", nil, errNoName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.streamID, func(t *testing.T) {
+			app, name, query, err := parseSrtStreamID(tt.streamID)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSrtStreamID: %v", err)
+			}
+			if app != tt.app || name != tt.name || !reflect.DeepEqual(query, tt.query) {
+				t.Fatalf("got %q, %q, %v, want %q, %q, %v", app, name, query, tt.app, tt.name, tt.query)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package rtmpserver
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+)
+
+// whipConnectTimeout limits the time from the answer to an established connection.
+const whipConnectTimeout = 30 * time.Second
+
+// WHIPOfferInvalid is returned when the SDP offer of a WHIP publisher can't be answered.
+type WHIPOfferInvalid struct {
+	Err error
+}
+
+func (e WHIPOfferInvalid) Error() string {
+	return fmt.Sprintf("invalid WHIP offer: %v", e.Err)
+}
+
+func (e WHIPOfferInvalid) Unwrap() error {
+	return e.Err
+}
+
+// newWhipAPI negotiates H.264 and Opus only, so that offers fall back to neither VP8 nor VP9.
+func newWhipAPI(config MediaServerConfig) (*webrtc.API, error) {
+	media := &webrtc.MediaEngine{}
+	feedback := []webrtc.RTCPFeedback{{Type: "goog-remb"}, {Type: "ccm", Parameter: "fir"}, {Type: "nack"}, {Type: "nack", Parameter: "pli"}}
+	payloadType := webrtc.PayloadType(102)
+	for _, profile := range []string{"42001f", "42e01f", "4d001f", "64001f"} {
+		err := media.RegisterCodec(webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{
+				MimeType:     webrtc.MimeTypeH264,
+				ClockRate:    90000,
+				SDPFmtpLine:  "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=" + profile,
+				RTCPFeedback: feedback,
+			},
+			PayloadType: payloadType,
+		}, webrtc.RTPCodecTypeVideo)
+		if err != nil {
+			return nil, err
+		}
+		payloadType++
+	}
+	err := media.RegisterCodec(webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{
+			MimeType:    webrtc.MimeTypeOpus,
+			ClockRate:   48000,
+			Channels:    2,
+			SDPFmtpLine: "minptime=10;useinbandfec=1",
+		},
+		PayloadType: 111,
+	}, webrtc.RTPCodecTypeAudio)
+	if err != nil {
+		return nil, err
+	}
+
+	interceptors := &interceptor.Registry{}
+	if err := webrtc.RegisterDefaultInterceptors(media, interceptors); err != nil {
+		return nil, err
+	}
+
+	settings := webrtc.SettingEngine{}
+	if len(config.WHIPPublicIPs) > 0 {
+		settings.SetNAT1To1IPs(config.WHIPPublicIPs, webrtc.ICECandidateTypeHost)
+	}
+	if config.WHIPUDPAddr != "" {
+		conn, err := net.ListenPacket("udp", config.WHIPUDPAddr)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("WHIP media listening on %s", conn.LocalAddr())
+		settings.SetICEUDPMux(webrtc.NewICEUDPMux(nil, conn))
+	}
+
+	return webrtc.NewAPI(webrtc.WithMediaEngine(media), webrtc.WithInterceptorRegistry(interceptors), webrtc.WithSettingEngine(settings)), nil
+}
+
+// AcceptWHIP answers the SDP offer of a WHIP publisher, which is authorized like an RTMP publisher.
+// The media is encrypted by DTLS, so the session is secure. The session ends when the peer
+// connection fails or the session is closed.
+func (s *MediaServer) AcceptWHIP(offer, remoteAddr, streamName string, query url.Values) (answer, sessionId string, err error) {
+	pc, err := s.whip.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		return "", "", err
+	}
+	answer, err = negotiateWHIP(pc, offer)
+	if err != nil {
+		_ = pc.Close()
+		return "", "", err
+	}
+
+	var bytesIn atomic.Uint64
+	counters := func() (uint64, uint64) {
+		return bytesIn.Load(), 0
+	}
+	sess := newIngestSession(ProtocolWHIP, remoteAddr, true, pc.Close, counters)
+	if err := s.acquireIngest(sess, "", streamName, query); err != nil {
+		_ = pc.Close()
+		return "", "", err
+	}
+
+	var release sync.Once
+	stop := func() {
+		release.Do(func() {
+			s.releaseIngest(sess)
+			log.Printf("WHIP stream %s from %s closed", streamName, remoteAddr)
+		})
+	}
+	connectTimer := time.AfterFunc(whipConnectTimeout, stop)
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		switch state {
+		case webrtc.PeerConnectionStateConnected:
+			connectTimer.Stop()
+		case webrtc.PeerConnectionStateFailed, webrtc.PeerConnectionStateClosed:
+			connectTimer.Stop()
+			stop()
+		}
+	})
+
+	demuxer := medias.NewWebRTCDemuxer(s.config.WHIPAudioPolicy, sess.onBatch)
+	pc.OnTrack(func(track *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		requestKeyframe := func() {
+			_ = pc.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(track.SSRC())}})
+		}
+		err := demuxer.ReadTrack(&countingTrack{TrackRemote: track, bytes: &bytesIn}, requestKeyframe)
+		log.Printf("WHIP stream %s: %s track ended: %v", streamName, track.Kind(), err)
+	})
+	return answer, sess.id, nil
+}
+
+// negotiateWHIP returns the answer with all the ICE candidates, WHIP doesn't require trickle ICE.
+func negotiateWHIP(pc *webrtc.PeerConnection, offer string) (string, error) {
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer}); err != nil {
+		return "", WHIPOfferInvalid{Err: err}
+	}
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		return "", WHIPOfferInvalid{Err: err}
+	}
+	gathered := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(answer); err != nil {
+		return "", err
+	}
+	<-gathered
+	return pc.LocalDescription().SDP, nil
+}
+
+// countingTrack counts the bytes of the RTP packets read from the track.
+type countingTrack struct {
+	*webrtc.TrackRemote
+	bytes *atomic.Uint64
+}
+
+func (t *countingTrack) ReadRTP() (*rtp.Packet, interceptor.Attributes, error) {
+	packet, attributes, err := t.TrackRemote.ReadRTP()
+	if err == nil {
+		t.bytes.Add(uint64(packet.MarshalSize()))
+	}
+	return packet, attributes, err
+}
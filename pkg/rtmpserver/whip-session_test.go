@@ -0,0 +1,152 @@
+package rtmpserver
+
+import (
+	"errors"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/registry"
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/rtmpserver/medias"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/yapingcat/gomedia/go-codec"
+)
+
+// frameConsumer passes the frames of the stream to a channel.
+type frameConsumer struct {
+	frames chan medias.MediaFrame
+}
+
+func (c *frameConsumer) Play(batch *medias.MediaFrameBatch) {
+	for _, frame := range batch.Frames {
+		select {
+		case c.frames <- frame:
+		default:
+		}
+	}
+}
+
+func (c *frameConsumer) Id() string     { return "test" }
+func (c *frameConsumer) IsClosed() bool { return false }
+func (c *frameConsumer) Close() error   { return nil }
+
+func newTestServer(t *testing.T) (*MediaServer, registry.Registry) {
+	t.Helper()
+	old := registry.REGESTRY_STORAGE_FILE
+	registry.REGESTRY_STORAGE_FILE = filepath.Join(t.TempDir(), "data.json")
+	t.Cleanup(func() { registry.REGESTRY_STORAGE_FILE = old })
+	r := registry.NewRegistry(registry.Config{})
+	if err := r.Update(&registry.ExternalStream{Name: "s1"}); err != nil {
+		t.Fatal(err)
+	}
+	return NewMediaServer(MediaServerConfig{}, r), r
+}
+
+// newWhipPublisher returns a peer connection with an H.264 track and its complete offer.
+func newWhipPublisher(t *testing.T) (*webrtc.PeerConnection, *webrtc.TrackLocalStaticSample, string) {
+	t.Helper()
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = pc.Close() })
+	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{
+		MimeType:    webrtc.MimeTypeH264,
+		ClockRate:   90000,
+		SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f",
+	}, "video", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pc.AddTransceiverFromTrack(track, webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionSendonly}); err != nil {
+		t.Fatal(err)
+	}
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gathered := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	<-gathered
+	return pc, track, pc.LocalDescription().SDP
+}
+
+func TestWHIPPublish(t *testing.T) {
+	s, r := newTestServer(t)
+	stream, err := r.GetInternalStream("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	consumer := &frameConsumer{frames: make(chan medias.MediaFrame, 64)}
+	stream.AddConsumer(consumer)
+
+	pc, track, offer := newWhipPublisher(t)
+	answer, sessionId, err := s.AcceptWHIP(offer, "127.0.0.1:1", "s1", url.Values{})
+	if err != nil {
+		t.Fatalf("AcceptWHIP: %v", err)
+	}
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A keyframe with its parameter sets every 40ms, until the stream gets one
+	sps := []byte{0, 0, 0, 1, 0x67, 0x42, 0xc0, 0x1f, 0xda, 0x01, 0x40, 0x16, 0xe8, 0x40}
+	pps := []byte{0, 0, 0, 1, 0x68, 0xce, 0x3c, 0x80}
+	idr := []byte{0, 0, 0, 1, 0x65, 0x88, 0x84, 0x00, 0x33}
+	keyframe := append(append(append([]byte(nil), sps...), pps...), idr...)
+	ticker := time.NewTicker(40 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(10 * time.Second)
+	for got := false; !got; {
+		select {
+		case <-ticker.C:
+			if err := track.WriteSample(media.Sample{Data: keyframe, Duration: 40 * time.Millisecond}); err != nil && !errors.Is(err, webrtc.ErrConnectionClosed) {
+				t.Fatalf("WriteSample: %v", err)
+			}
+		case frame := <-consumer.frames:
+			got = frame.Cid == codec.CODECID_VIDEO_H264 && frame.IsIFrame
+		case <-timeout:
+			t.Fatalf("no keyframe reached the stream, the peer connection is %s", pc.ConnectionState())
+		}
+	}
+
+	var found bool
+	for _, sess := range s.Sessions() {
+		found = found || sess.Id == sessionId && sess.Protocol == ProtocolWHIP && sess.Stream == "s1"
+	}
+	if !found {
+		t.Fatalf("no WHIP session %s in %+v", sessionId, s.Sessions())
+	}
+
+	// Another publisher is rejected while the stream is live
+	_, _, other := newWhipPublisher(t)
+	if _, _, err := s.AcceptWHIP(other, "127.0.0.1:2", "s1", url.Values{}); !errors.As(err, &registry.PublisherConflict{}) {
+		t.Fatalf("second publisher: %v, want a conflict", err)
+	}
+
+	if err := s.CloseSession(sessionId); err != nil {
+		t.Fatalf("CloseSession: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.Sessions()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("sessions left after the close: %+v", s.Sessions())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWHIPInvalidOffer(t *testing.T) {
+	s, _ := newTestServer(t)
+	if _, _, err := s.AcceptWHIP("not an offer", "127.0.0.1:1", "s1", url.Values{}); !errors.As(err, &WHIPOfferInvalid{}) {
+		t.Fatalf("AcceptWHIP: %v, want an invalid offer", err)
+	}
+	_, _, offer := newWhipPublisher(t)
+	if _, _, err := s.AcceptWHIP(offer, "127.0.0.1:1", "missing", url.Values{}); !errors.As(err, &registry.StreamNotFound{}) {
+		t.Fatalf("AcceptWHIP of a missing stream: %v", err)
+	}
+}
@@ -0,0 +1,568 @@
+package srt
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	tickInterval      = 10 * time.Millisecond // full ACK interval
+	minNakInterval    = 20 * time.Millisecond
+	keepaliveInterval = time.Second
+	peerIdleTimeout   = 5 * time.Second
+	minSendRetention  = time.Second // unacknowledged packets are kept at least this long for retransmission
+	receiveQueueSize  = 4096
+	driftWindow       = 10 * time.Second // the time mapping follows the sender clock at this pace
+	maxLossRanges     = PayloadSize / 8  // in a NAK
+	DefaultLatency    = 120 * time.Millisecond
+)
+
+var ErrPeerIdle = errors.New("srt: peer idle timeout")
+
+// Conn is an established SRT connection in live mode. Each Write sends its data in packets of
+// at most PayloadSize bytes, each Read returns the payload of one packet. Lost packets are
+// retransmitted until the latency passes, later ones are skipped by the receiver.
+// Packets are delivered the latency after their sender timestamp (TSBPD).
+type Conn struct {
+	localID  uint32
+	peerID   uint32
+	local    net.Addr
+	remote   net.Addr
+	output   func(b []byte) error
+	onClose  func()
+	streamID string
+	latency  time.Duration
+	start    time.Time
+
+	mu           sync.Mutex
+	km           *keyMaterial // nil if the connection isn't encrypted
+	kmPassphrase string
+
+	// sender
+	sndNext  uint32
+	msgNo    uint32
+	sndBuf   []sentPacket // unacknowledged packets in order
+	lastSent time.Time
+
+	// receiver
+	rcvNext    uint32 // next packet to deliver
+	rcvContig  uint32 // first packet not received, acknowledged to the sender
+	rcvLast    uint32 // highest received packet
+	rcvAny     bool
+	rcvBuf     []*rcvPacket // ring of the packets from rcvNext on, indexed by sequence number
+	rcvCount   int
+	tsbpdTs    uint32    // newest sender timestamp
+	tsbpdBase  time.Time // local time tsbpdTs maps to
+	driftSince time.Time
+	driftSlack time.Duration // the least a packet arrived after its mapped time since driftSince
+	lastRecv   time.Time
+	ackNo      uint32
+	ackSent    map[uint32]time.Time
+	lastAckSeq uint32
+	lastAckAt  time.Time
+	lastNakAt  time.Time
+	rtt        time.Duration
+	rttVar     time.Duration
+	queue      chan []byte
+
+	bytesIn   atomic.Uint64
+	bytesOut  atomic.Uint64
+	lost      atomic.Uint64
+	resent    atomic.Uint64
+	closed    chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+type rcvPacket struct {
+	data   []byte
+	playAt time.Time
+}
+
+type sentPacket struct {
+	seq    uint32
+	flags  uint32
+	ts     uint32
+	data   []byte
+	sentAt time.Time // first transmission
+	lastAt time.Time // last transmission
+}
+
+// Stats are the counters of a connection.
+type Stats struct {
+	BytesIn     uint64
+	BytesOut    uint64
+	PacketsLost uint64 // packets the receiver skipped
+	Retransmits uint64 // packets sent again
+	RTT         time.Duration
+}
+
+func newConn(localID, peerID, isn uint32, latency time.Duration, km *keyMaterial) *Conn {
+	now := time.Now()
+	return &Conn{
+		localID:    localID,
+		peerID:     peerID,
+		latency:    latency,
+		km:         km,
+		start:      now,
+		sndNext:    isn,
+		msgNo:      1,
+		rcvNext:    isn,
+		rcvContig:  isn,
+		rcvLast:    seqAdd(isn, -1),
+		lastAckSeq: isn,
+		rcvBuf:     make([]*rcvPacket, flowWindow),
+		ackSent:    make(map[uint32]time.Time),
+		lastRecv:   now,
+		lastSent:   now,
+		rtt:        100 * time.Millisecond,
+		rttVar:     50 * time.Millisecond,
+		queue:      make(chan []byte, receiveQueueSize),
+		closed:     make(chan struct{}),
+	}
+}
+
+func (c *Conn) run() {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			c.tick(now)
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+func (c *Conn) StreamID() string {
+	return c.streamID
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// Encrypted reports whether the payload is encrypted with a passphrase.
+func (c *Conn) Encrypted() bool {
+	return c.km != nil
+}
+
+func (c *Conn) Latency() time.Duration {
+	return c.latency
+}
+
+func (c *Conn) Stats() Stats {
+	c.mu.Lock()
+	rtt := c.rtt
+	c.mu.Unlock()
+	return Stats{
+		BytesIn:     c.bytesIn.Load(),
+		BytesOut:    c.bytesOut.Load(),
+		PacketsLost: c.lost.Load(),
+		Retransmits: c.resent.Load(),
+		RTT:         rtt,
+	}
+}
+
+// Read returns the payload of the next packet, b must be at least PayloadSize bytes long.
+func (c *Conn) Read(b []byte) (int, error) {
+	select {
+	case data := <-c.queue:
+		return copy(b, data), nil
+	case <-c.closed:
+		select {
+		case data := <-c.queue:
+			return copy(b, data), nil
+		default:
+		}
+		return 0, c.err
+	}
+}
+
+// Write sends the data in packets of up to PayloadSize bytes.
+func (c *Conn) Write(b []byte) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, c.err
+	default:
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for written := 0; written < len(b); {
+		n := min(len(b)-written, PayloadSize)
+		data := append([]byte(nil), b[written:written+n]...)
+		flags := uint32(flagSolo) | c.msgNo
+		if c.km != nil {
+			if err := c.km.crypt(keyEven, c.sndNext, data); err != nil {
+				return written, err
+			}
+			flags |= keyEven << flagKeyShift
+		}
+		now := time.Now()
+		sp := sentPacket{seq: c.sndNext, flags: flags, ts: c.timestamp(), data: data, sentAt: now, lastAt: now}
+		c.sndBuf = append(c.sndBuf, sp)
+		c.sndNext = seqAdd(c.sndNext, 1)
+		c.msgNo = c.msgNo%msgNoMask + 1
+		if err := c.sendData(sp, false); err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return len(b), nil
+}
+
+func (c *Conn) Close() error {
+	c.closeWith(io.EOF, true)
+	return nil
+}
+
+// closeWith stops the connection, the error is returned by the following reads and writes.
+// It locks mu, so the packet handlers call it in a goroutine.
+func (c *Conn) closeWith(err error, notify bool) {
+	c.closeOnce.Do(func() {
+		if notify {
+			c.mu.Lock()
+			_ = c.sendControl(ctrlShutdown, 0, 0, words(0))
+			c.mu.Unlock()
+		}
+		c.err = err
+		close(c.closed)
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+}
+
+func (c *Conn) timestamp() uint32 {
+	return uint32(time.Since(c.start).Microseconds())
+}
+
+func (c *Conn) sendData(sp sentPacket, retransmit bool) error {
+	flags := sp.flags
+	if retransmit {
+		flags |= flagRetransmit
+		c.resent.Add(1)
+	}
+	p := packet{seq: sp.seq, flags: flags, timestamp: sp.ts, dest: c.peerID, payload: sp.data}
+	c.lastSent = time.Now()
+	c.bytesOut.Add(uint64(len(sp.data)))
+	return c.output(p.marshal())
+}
+
+func (c *Conn) sendControl(kind controlType, subtype uint16, info uint32, payload []byte) error {
+	p := packet{control: true, ctrlType: kind, subtype: subtype, info: info, timestamp: c.timestamp(), dest: c.peerID, payload: payload}
+	c.lastSent = time.Now()
+	return c.output(p.marshal())
+}
+
+// handlePacket is called by the reader of the socket.
+func (c *Conn) handlePacket(p *packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastRecv = time.Now()
+	if !p.control {
+		c.handleData(p)
+		return
+	}
+	switch p.ctrlType {
+	case ctrlAck:
+		c.handleAck(p)
+	case ctrlNak:
+		c.handleNak(p)
+	case ctrlAckAck:
+		if sent, ok := c.ackSent[p.info]; ok {
+			delete(c.ackSent, p.info)
+			sample := time.Since(sent)
+			c.rttVar = (3*c.rttVar + (c.rtt - sample).Abs()) / 4
+			c.rtt = (7*c.rtt + sample) / 8
+		}
+	case ctrlShutdown:
+		go c.closeWith(io.EOF, false)
+	case ctrlUser:
+		c.handleUser(p)
+	case ctrlHandshake:
+		// A retransmitted conclusion of the caller, answered by the listener
+	}
+}
+
+func (c *Conn) handleData(p *packet) {
+	c.bytesIn.Add(uint64(len(p.payload)))
+	// Packets beyond the window would be buffered for longer than the latency anyway
+	if seqLess(p.seq, c.rcvNext) || seqDiff(p.seq, c.rcvNext) >= flowWindow {
+		return
+	}
+	if c.rcvBuf[p.seq%flowWindow] != nil {
+		return
+	}
+	data := append([]byte(nil), p.payload...)
+	if key := p.keyIndex(); key != 0 {
+		if c.km == nil || c.km.crypt(key, p.seq, data) != nil {
+			return
+		}
+	}
+
+	now := time.Now()
+	if !c.rcvAny || seqLess(c.rcvLast, p.seq) {
+		if c.rcvAny && seqDiff(p.seq, c.rcvLast) > 1 {
+			// Report the loss right away, the periodic NAK repeats it
+			first, last := seqAdd(c.rcvLast, 1), seqAdd(p.seq, -1)
+			_ = c.sendControl(ctrlNak, 0, 0, encodeLossRange(nil, first, last))
+		}
+		c.rcvLast = p.seq
+		c.rcvAny = true
+	}
+	c.rcvBuf[p.seq%flowWindow] = &rcvPacket{data: data, playAt: c.playTime(p.timestamp, now)}
+	c.rcvCount++
+	c.advanceContig()
+	c.deliverReady(now)
+}
+
+// playTime maps the sender timestamp to the local time the packet is delivered at. The mapping is set by the
+// packet that arrived the fastest and follows the newest timestamp, so that it survives the wraparound.
+func (c *Conn) playTime(ts uint32, now time.Time) time.Time {
+	if c.tsbpdBase.IsZero() {
+		c.tsbpdTs, c.tsbpdBase, c.driftSince = ts, now, now
+	}
+	if d := int32(ts - c.tsbpdTs); d > 0 {
+		c.tsbpdTs = ts
+		c.tsbpdBase = c.tsbpdBase.Add(time.Duration(d) * time.Microsecond)
+	}
+	sent := c.tsbpdBase.Add(time.Duration(int32(ts-c.tsbpdTs)) * time.Microsecond)
+	if late := now.Sub(sent); late < 0 {
+		c.tsbpdBase = c.tsbpdBase.Add(late)
+		sent = now
+		c.driftSlack = 0
+	} else if late < c.driftSlack {
+		c.driftSlack = late
+	}
+	if now.Sub(c.driftSince) >= driftWindow {
+		// Every packet of the window came late, the sender clock is slower than ours
+		c.tsbpdBase = c.tsbpdBase.Add(c.driftSlack)
+		c.driftSince, c.driftSlack = now, driftWindow
+	}
+	return sent.Add(c.latency)
+}
+
+// advanceContig moves rcvContig past the received packets.
+func (c *Conn) advanceContig() {
+	if seqLess(c.rcvContig, c.rcvNext) {
+		c.rcvContig = c.rcvNext
+	}
+	for !seqLess(c.rcvLast, c.rcvContig) && c.rcvBuf[c.rcvContig%flowWindow] != nil {
+		c.rcvContig = seqAdd(c.rcvContig, 1)
+	}
+}
+
+// deliverReady delivers the packets whose time has come. The missing packets before one are
+// given up when it is due, that is the latency after the loss at the latest.
+func (c *Conn) deliverReady(now time.Time) {
+	for c.rcvCount > 0 {
+		rp := c.rcvBuf[c.rcvNext%flowWindow]
+		if rp == nil {
+			// rcvContig is missing, rcvNext is before it or equal
+			next := c.rcvContig
+			for c.rcvBuf[next%flowWindow] == nil {
+				next = seqAdd(next, 1)
+			}
+			if now.Before(c.rcvBuf[next%flowWindow].playAt) {
+				return
+			}
+			c.lost.Add(uint64(seqDiff(next, c.rcvNext)))
+			c.rcvNext = next
+			c.advanceContig()
+			continue
+		}
+		if now.Before(rp.playAt) {
+			return
+		}
+		c.rcvBuf[c.rcvNext%flowWindow] = nil
+		c.rcvCount--
+		c.deliver(rp.data)
+		c.rcvNext = seqAdd(c.rcvNext, 1)
+	}
+}
+
+func (c *Conn) deliver(data []byte) {
+	select {
+	case c.queue <- data:
+	default:
+		// The reader is too slow, like a packet lost too late
+		c.lost.Add(1)
+	}
+}
+
+func (c *Conn) handleAck(p *packet) {
+	if len(p.payload) < 4 {
+		return
+	}
+	ackSeq := binary.BigEndian.Uint32(p.payload) & seqMask
+	acked := 0
+	for acked < len(c.sndBuf) && seqLess(c.sndBuf[acked].seq, ackSeq) {
+		acked++
+	}
+	c.sndBuf = c.sndBuf[acked:]
+	if len(p.payload) >= 12 {
+		// The receiver measures the RTT
+		c.rtt = time.Duration(binary.BigEndian.Uint32(p.payload[4:])) * time.Microsecond
+		c.rttVar = time.Duration(binary.BigEndian.Uint32(p.payload[8:])) * time.Microsecond
+	}
+	// Light ACKs carry only the sequence number and aren't acknowledged
+	if len(p.payload) >= 16 {
+		_ = c.sendControl(ctrlAckAck, 0, p.info, words(0))
+	}
+}
+
+func (c *Conn) handleNak(p *packet) {
+	for _, r := range decodeLossList(p.payload) {
+		for i := range c.sndBuf {
+			if sp := &c.sndBuf[i]; !seqLess(sp.seq, r[0]) && !seqLess(r[1], sp.seq) {
+				sp.lastAt = time.Now()
+				_ = c.sendData(*sp, true)
+			}
+		}
+	}
+}
+
+// handleUser answers the key material refresh of the peer.
+func (c *Conn) handleUser(p *packet) {
+	if p.subtype != extKMReq || c.km == nil {
+		return
+	}
+	km, err := parseKeyMaterial(p.payload, c.kmPassphrase)
+	if err != nil {
+		_ = c.sendControl(ctrlUser, extKMRsp, 0, words(kmStateBadSecret))
+		return
+	}
+	for i := keyEven; i <= keyOdd; i++ {
+		if km.keys[i] != nil {
+			c.km.keys[i] = km.keys[i]
+			c.km.blocks[i] = nil
+		}
+	}
+	c.km.salt = km.salt
+	_ = c.sendControl(ctrlUser, extKMRsp, 0, p.payload)
+}
+
+func (c *Conn) tick(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastRecv) > peerIdleTimeout {
+		go c.closeWith(ErrPeerIdle, true)
+		return
+	}
+	if c.rcvAny {
+		c.deliverReady(now)
+		if c.rcvContig != c.lastAckSeq || now.Sub(c.lastAckAt) >= 100*time.Millisecond {
+			c.sendAck(now)
+		}
+		nakInterval := max(c.rtt+4*c.rttVar, minNakInterval)
+		if seqLess(c.rcvContig, c.rcvLast) && now.Sub(c.lastNakAt) >= nakInterval {
+			c.sendLossReport()
+			c.lastNakAt = now
+		}
+	}
+
+	// Packets the receiver can't play anymore aren't retransmitted
+	retention := max(c.latency*5/4, minSendRetention)
+	dropped := 0
+	for dropped < len(c.sndBuf) && now.Sub(c.sndBuf[dropped].sentAt) > retention {
+		dropped++
+	}
+	c.sndBuf = c.sndBuf[dropped:]
+	// The receiver can't report the loss of the last packets before a pause, they
+	// are sent again if still unacknowledged when an ACK should have come back
+	resend := max(c.rtt+4*c.rttVar, minNakInterval) + 2*tickInterval
+	if n := len(c.sndBuf); n > 0 && now.Sub(c.sndBuf[n-1].sentAt) > resend {
+		for i := range c.sndBuf {
+			if sp := &c.sndBuf[i]; now.Sub(sp.lastAt) > resend {
+				sp.lastAt = now
+				_ = c.sendData(*sp, true)
+			}
+		}
+	}
+
+	if now.Sub(c.lastSent) >= keepaliveInterval {
+		_ = c.sendControl(ctrlKeepalive, 0, 0, words(0))
+	}
+}
+
+func (c *Conn) sendAck(now time.Time) {
+	c.ackNo++
+	c.ackSent[c.ackNo] = now
+	for no, sent := range c.ackSent {
+		if now.Sub(sent) > time.Second {
+			delete(c.ackSent, no)
+		}
+	}
+	c.lastAckSeq = c.rcvContig
+	c.lastAckAt = now
+	_ = c.sendControl(ctrlAck, 0, c.ackNo, words(
+		c.rcvContig,
+		uint32(c.rtt.Microseconds()),
+		uint32(c.rttVar.Microseconds()),
+		uint32(flowWindow-c.rcvCount),
+		0, 0, 0,
+	))
+}
+
+// sendLossReport reports the missing packets of the window, the first maxLossRanges gaps of them.
+func (c *Conn) sendLossReport() {
+	var payload []byte
+	var first uint32
+	inGap, ranges := false, 0
+	for seq := c.rcvContig; seqLess(seq, c.rcvLast) && ranges < maxLossRanges; seq = seqAdd(seq, 1) {
+		received := c.rcvBuf[seq%flowWindow] != nil
+		if !received && !inGap {
+			first, inGap = seq, true
+		} else if received && inGap {
+			payload = encodeLossRange(payload, first, seqAdd(seq, -1))
+			inGap = false
+			ranges++
+		}
+	}
+	if inGap {
+		payload = encodeLossRange(payload, first, seqAdd(c.rcvLast, -1))
+	}
+	if len(payload) > 0 {
+		_ = c.sendControl(ctrlNak, 0, 0, payload)
+	}
+}
+
+// The loss list of a NAK has single sequence numbers and ranges, where the first number has the top bit set.
+
+func encodeLossRange(b []byte, first, last uint32) []byte {
+	if first == last {
+		return append(b, words(first)...)
+	}
+	return append(b, words(first|1<<31, last)...)
+}
+
+func decodeLossList(b []byte) [][2]uint32 {
+	var ranges [][2]uint32
+	for len(b) >= 4 {
+		first := binary.BigEndian.Uint32(b)
+		b = b[4:]
+		if first&(1<<31) == 0 {
+			ranges = append(ranges, [2]uint32{first, first})
+			continue
+		}
+		if len(b) < 4 {
+			break
+		}
+		last := binary.BigEndian.Uint32(b)
+		b = b[4:]
+		ranges = append(ranges, [2]uint32{first & seqMask, last & seqMask})
+	}
+	return ranges
+}
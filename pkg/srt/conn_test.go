@@ -0,0 +1,223 @@
+package srt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// listen starts a listener whose connections pass what they read to the returned channel.
+func listen(t *testing.T, passphrase string, latency time.Duration) (*Listener, <-chan []byte, <-chan *Conn) {
+	t.Helper()
+	received := make(chan []byte, 1024)
+	accepted := make(chan *Conn, 1)
+	l, err := Listen("127.0.0.1:0", ListenerConfig{
+		Latency:    latency,
+		Passphrase: passphrase,
+		Handler: func(c *Conn) (func(), RejectReason) {
+			if c.StreamID() == "reject" {
+				return nil, RejectForbidden
+			}
+			return func() {
+				accepted <- c
+				buf := make([]byte, PayloadSize)
+				for {
+					n, err := c.Read(buf)
+					if err != nil {
+						return
+					}
+					received <- append([]byte(nil), buf[:n]...)
+				}
+			}, 0
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = l.Serve() }()
+	t.Cleanup(func() { _ = l.Close() })
+	return l, received, accepted
+}
+
+func TestLoopback(t *testing.T) {
+	const latency = 200 * time.Millisecond
+	for _, passphrase := range []string{"", "0123456789secret"} {
+		t.Run(fmt.Sprintf("encrypted=%v", passphrase != ""), func(t *testing.T) {
+			l, received, accepted := listen(t, passphrase, latency)
+			c, err := Dial("udp", l.Addr().String(), DialConfig{StreamID: "#!::r=live/s1", Passphrase: passphrase, Latency: latency})
+			if err != nil {
+				t.Fatalf("Dial: %v", err)
+			}
+			defer c.Close()
+			if c.Encrypted() != (passphrase != "") {
+				t.Fatalf("Encrypted() = %v", c.Encrypted())
+			}
+
+			var server *Conn
+			select {
+			case server = <-accepted:
+			case <-time.After(5 * time.Second):
+				t.Fatal("the listener accepted no connection")
+			}
+			if server.StreamID() != "#!::r=live/s1" || server.Encrypted() != (passphrase != "") {
+				t.Fatalf("server side: stream ID %q, encrypted %v", server.StreamID(), server.Encrypted())
+			}
+
+			const count = 100
+			sent := time.Now()
+			for i := 0; i < count; i++ {
+				if _, err := c.Write(bytes.Repeat([]byte{byte(i)}, PayloadSize)); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+			}
+			for i := 0; i < count; i++ {
+				select {
+				case data := <-received:
+					if i == 0 && time.Since(sent) < latency {
+						t.Fatalf("the first packet was delivered after %v, before the latency", time.Since(sent))
+					}
+					if len(data) != PayloadSize || data[0] != byte(i) || data[PayloadSize-1] != byte(i) {
+						t.Fatalf("packet %d: got %d bytes starting with %d", i, len(data), data[0])
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("received %d packets of %d", i, count)
+				}
+			}
+		})
+	}
+}
+
+func TestDialRejected(t *testing.T) {
+	tests := []struct {
+		name       string
+		listener   string
+		caller     string
+		streamID   string
+		wantReason RejectReason
+	}{
+		{"wrong passphrase", "0123456789secret", "0123456789wrong", "s1", RejectBadSecret},
+		{"missing passphrase", "0123456789secret", "", "s1", RejectUnsecure},
+		{"unexpected passphrase", "", "0123456789secret", "s1", RejectUnsecure},
+		{"handler", "", "", "reject", RejectForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _, _ := listen(t, tt.listener, 0)
+			c, err := Dial("udp", l.Addr().String(), DialConfig{StreamID: tt.streamID, Passphrase: tt.caller, Timeout: 2 * time.Second})
+			if err == nil {
+				_ = c.Close()
+				t.Fatal("Dial succeeded")
+			}
+			var reason RejectReason
+			if !errors.As(err, &reason) || reason != tt.wantReason {
+				t.Fatalf("Dial: %v, want %v", err, tt.wantReason)
+			}
+		})
+	}
+}
+
+// testConn is a connection without a socket, its output is collected.
+func testConn(isn uint32, latency time.Duration) (*Conn, *[]*packet) {
+	var sent []*packet
+	c := newConn(1, 2, isn, latency, nil)
+	c.output = func(b []byte) error {
+		p, err := parsePacket(b)
+		if err == nil {
+			sent = append(sent, p)
+		}
+		return err
+	}
+	return c, &sent
+}
+
+func dataPacket(seq uint32, ts uint32, payload byte) *packet {
+	return &packet{seq: seq, flags: flagSolo, timestamp: ts, dest: 1, payload: []byte{payload}}
+}
+
+func TestReceiveWindow(t *testing.T) {
+	const isn = seqMask - 10 // the window wraps around
+	c, sent := testConn(isn, 100*time.Millisecond)
+
+	c.handlePacket(dataPacket(isn, 0, 0))
+	// Far ahead of the window: neither buffered nor reported as a loss
+	c.handlePacket(dataPacket(seqAdd(isn, 1<<30), 1000, 1))
+	if c.rcvCount != 1 || c.rcvLast != isn || len(*sent) != 0 {
+		t.Fatalf("a packet beyond the window was accepted: %d buffered, last %d, %d sent", c.rcvCount, c.rcvLast, len(*sent))
+	}
+
+	// The last packet of the window opens a gap reported in a single range
+	last := seqAdd(isn, flowWindow-1)
+	c.handlePacket(dataPacket(last, 1000, 2))
+	if len(*sent) != 1 || (*sent)[0].ctrlType != ctrlNak {
+		t.Fatalf("sent %d packets, want a NAK", len(*sent))
+	}
+	if got := decodeLossList((*sent)[0].payload); len(got) != 1 || got[0] != [2]uint32{seqAdd(isn, 1), seqAdd(last, -1)} {
+		t.Fatalf("loss list %v", got)
+	}
+
+	// Every other packet of the gap arrives, the periodic report is limited
+	for seq := seqAdd(isn, 2); seqLess(seq, last); seq = seqAdd(seq, 2) {
+		c.handlePacket(dataPacket(seq, 1000, 3))
+	}
+	*sent = nil
+	c.sendLossReport()
+	if got := decodeLossList((*sent)[0].payload); len(got) != maxLossRanges {
+		t.Fatalf("the loss report has %d ranges, want %d", len(got), maxLossRanges)
+	}
+	if c.rcvContig != seqAdd(isn, 1) {
+		t.Fatalf("acknowledged %d, want %d", c.rcvContig, seqAdd(isn, 1))
+	}
+
+	// Once due, the buffered packets are delivered and the missing ones skipped
+	c.deliverReady(time.Now().Add(time.Second))
+	if c.rcvCount != 0 || c.rcvNext != seqAdd(last, 1) || c.rcvContig != c.rcvNext {
+		t.Fatalf("%d packets left, next %d, want %d", c.rcvCount, c.rcvNext, seqAdd(last, 1))
+	}
+	// The packets that didn't fit the queue of the reader count as lost too
+	delivered := flowWindow/2 + 1
+	if want := uint64(flowWindow/2 - 1 + delivered - receiveQueueSize); c.lost.Load() != want {
+		t.Fatalf("lost %d packets, want %d", c.lost.Load(), want)
+	}
+}
+
+func TestPlayTime(t *testing.T) {
+	const latency = 100 * time.Millisecond
+	c, _ := testConn(0, latency)
+	start := time.Now()
+	steps := []struct {
+		name    string
+		ts      uint32 // µs
+		arrival time.Duration
+		want    time.Duration
+	}{
+		{"first packet", 0, 0, 0},
+		{"jitter is absorbed", 50000, 60 * time.Millisecond, 50 * time.Millisecond},
+		{"a faster packet moves the mapping", 100000, 90 * time.Millisecond, 90 * time.Millisecond},
+		{"retransmission keeps its time", 50000, 150 * time.Millisecond, 40 * time.Millisecond},
+	}
+	for _, step := range steps {
+		if got := c.playTime(step.ts, start.Add(step.arrival)).Sub(start); got != step.want+latency {
+			t.Fatalf("%s: plays at %v, want %v", step.name, got, step.want+latency)
+		}
+	}
+
+	c, _ = testConn(0, latency)
+	c.playTime(1<<32-1000, start)
+	if got := c.playTime(1000, start.Add(2*time.Millisecond)).Sub(start); got != 2*time.Millisecond+latency {
+		t.Fatalf("across the wraparound: plays at %v, want %v", got, 2*time.Millisecond+latency)
+	}
+
+	// The sender clock is 0.1% slower, the packets come later and later
+	c, _ = testConn(0, latency)
+	var late time.Duration
+	for ts := time.Duration(0); ts <= 5*driftWindow; ts += 100 * time.Millisecond {
+		arrival := ts * 1001 / 1000
+		late = arrival - (c.playTime(uint32(ts.Microseconds()), start.Add(arrival)).Sub(start) - latency)
+	}
+	// Uncorrected they'd be 5 windows of drift late, the mapping lags by up to 2
+	if late > 2*driftWindow/1000+time.Millisecond {
+		t.Fatalf("the packets arrive %v after their time, the mapping didn't follow the drift", late)
+	}
+}
@@ -0,0 +1,222 @@
+package srt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	saltSize         = 16
+	kmHeaderSize     = 16
+	pbkdf2SaltSize   = 8 // the last bytes of the salt
+	pbkdf2Iterations = 2048
+	kmCipherAESCTR   = 2
+	kmSEMpegTS       = 2
+
+	// KMRSP of a listener which can't decrypt the key material
+	kmStateNoSecret  = 3
+	kmStateBadSecret = 4
+
+	MinPassphraseLen = 10
+	MaxPassphraseLen = 79
+)
+
+var errBadSecret = errors.New("srt: wrong passphrase")
+
+// ValidateKeyLength checks the AES key length in bytes, zero selects the default 16.
+func ValidateKeyLength(keyLen int) error {
+	switch keyLen {
+	case 0, 16, 24, 32:
+		return nil
+	}
+	return fmt.Errorf("key length must be 16, 24 or 32, got %d", keyLen)
+}
+
+func ValidatePassphrase(passphrase string) error {
+	if len(passphrase) < MinPassphraseLen || len(passphrase) > MaxPassphraseLen {
+		return fmt.Errorf("passphrase must be %d to %d characters long", MinPassphraseLen, MaxPassphraseLen)
+	}
+	return nil
+}
+
+// keyMaterial holds the stream encryption keys, which are exchanged wrapped with a key derived from the passphrase.
+type keyMaterial struct {
+	salt   []byte
+	keys   [3][]byte // indexed by the KK bits, even and odd
+	blocks [3]cipher.Block
+}
+
+func newKeyMaterial(keyLen int) (*keyMaterial, error) {
+	if keyLen == 0 {
+		keyLen = 16
+	}
+	km := &keyMaterial{salt: make([]byte, saltSize)}
+	km.keys[keyEven] = make([]byte, keyLen)
+	if _, err := rand.Read(km.salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(km.keys[keyEven]); err != nil {
+		return nil, err
+	}
+	return km, nil
+}
+
+func (km *keyMaterial) keyLen() int {
+	for _, key := range km.keys {
+		if key != nil {
+			return len(key)
+		}
+	}
+	return 0
+}
+
+func deriveKEK(passphrase string, salt []byte, keyLen int) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt[len(salt)-pbkdf2SaltSize:], pbkdf2Iterations, keyLen, sha1.New)
+}
+
+// marshal encodes the KMREQ message.
+func (km *keyMaterial) marshal(passphrase string) ([]byte, error) {
+	keyLen := km.keyLen()
+	var kk byte
+	var plain []byte
+	for i := keyEven; i <= keyOdd; i++ {
+		if km.keys[i] != nil {
+			kk |= byte(i)
+			plain = append(plain, km.keys[i]...)
+		}
+	}
+	wrapped, err := wrapKey(deriveKEK(passphrase, km.salt, keyLen), plain)
+	if err != nil {
+		return nil, err
+	}
+	msg := make([]byte, kmHeaderSize, kmHeaderSize+saltSize+len(wrapped))
+	msg[0] = 0x12 // version 1, packet type KM message
+	binary.BigEndian.PutUint16(msg[1:], 0x2029)
+	msg[3] = kk
+	msg[8] = kmCipherAESCTR
+	msg[10] = kmSEMpegTS
+	msg[14] = saltSize / 4
+	msg[15] = byte(keyLen / 4)
+	msg = append(msg, km.salt...)
+	return append(msg, wrapped...), nil
+}
+
+func parseKeyMaterial(msg []byte, passphrase string) (*keyMaterial, error) {
+	if len(msg) < kmHeaderSize || msg[0] != 0x12 || binary.BigEndian.Uint16(msg[1:]) != 0x2029 {
+		return nil, errors.New("srt: invalid key material")
+	}
+	if msg[8] != kmCipherAESCTR {
+		return nil, fmt.Errorf("srt: unsupported cipher %d", msg[8])
+	}
+	kk := msg[3] & 3
+	saltLen, keyLen := 4*int(msg[14]), 4*int(msg[15])
+	if kk == 0 || saltLen != saltSize || ValidateKeyLength(keyLen) != nil || keyLen == 0 {
+		return nil, errors.New("srt: invalid key material")
+	}
+	count := 1
+	if kk == keyEven|keyOdd {
+		count = 2
+	}
+	wrapped := msg[kmHeaderSize+saltLen:]
+	if len(wrapped) != 8+count*keyLen {
+		return nil, errors.New("srt: invalid key material")
+	}
+	km := &keyMaterial{salt: append([]byte(nil), msg[kmHeaderSize:kmHeaderSize+saltLen]...)}
+	plain, err := unwrapKey(deriveKEK(passphrase, km.salt, keyLen), wrapped)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(keyEven); i <= keyOdd; i++ {
+		if uint32(kk)&i != 0 {
+			km.keys[i] = plain[:keyLen]
+			plain = plain[keyLen:]
+		}
+	}
+	return km, nil
+}
+
+// crypt encrypts or decrypts the payload of the packet in place with AES-CTR.
+func (km *keyMaterial) crypt(key uint32, seq uint32, payload []byte) error {
+	if key > keyOdd || km.keys[key] == nil {
+		return errors.New("srt: no such key")
+	}
+	if km.blocks[key] == nil {
+		block, err := aes.NewCipher(km.keys[key])
+		if err != nil {
+			return err
+		}
+		km.blocks[key] = block
+	}
+	// The counter is the packet index in bytes 10-13 XORed with the first 112 bits of the salt
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint32(iv[10:], seq)
+	for i := 0; i < 14; i++ {
+		iv[i] ^= km.salt[i]
+	}
+	cipher.NewCTR(km.blocks[key], iv).XORKeyStream(payload, payload)
+	return nil
+}
+
+var keyWrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// wrapKey implements the AES key wrap of RFC 3394.
+func wrapKey(kek, plain []byte) ([]byte, error) {
+	if len(plain)%8 != 0 || len(plain) < 16 {
+		return nil, errors.New("srt: invalid key size")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(plain) / 8
+	out := make([]byte, 8+len(plain))
+	copy(out, keyWrapIV)
+	copy(out[8:], plain)
+	buf := make([]byte, aes.BlockSize)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf, out[:8])
+			copy(buf[8:], out[8*i:8*i+8])
+			block.Encrypt(buf, buf)
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(out, binary.BigEndian.Uint64(buf)^t)
+			copy(out[8*i:], buf[8:])
+		}
+	}
+	return out, nil
+}
+
+func unwrapKey(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 24 {
+		return nil, errors.New("srt: invalid wrapped key size")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(wrapped)/8 - 1
+	out := append([]byte(nil), wrapped...)
+	buf := make([]byte, aes.BlockSize)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf, binary.BigEndian.Uint64(out)^t)
+			copy(buf[8:], out[8*i:8*i+8])
+			block.Decrypt(buf, buf)
+			copy(out, buf[:8])
+			copy(out[8*i:], buf[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(out[:8], keyWrapIV) != 1 {
+		return nil, errBadSecret
+	}
+	return out[8:], nil
+}
@@ -0,0 +1,220 @@
+package srt
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+const (
+	handshakeRetry = 250 * time.Millisecond
+	DefaultTimeout = 3 * time.Second
+)
+
+type DialConfig struct {
+	StreamID   string
+	Passphrase string // encrypts the connection if set
+	KeyLength  int    // AES key length in bytes, 16 (default), 24 or 32
+	Latency    time.Duration
+	Timeout    time.Duration
+}
+
+// Dial connects to an SRT listener as a caller.
+func Dial(network, addr string, config DialConfig) (*Conn, error) {
+	if config.Latency == 0 {
+		config.Latency = DefaultLatency
+	}
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if len(config.StreamID) > MaxStreamIDLen {
+		return nil, errors.New("srt: stream ID too long")
+	}
+	var km *keyMaterial
+	if config.Passphrase != "" {
+		err := ValidatePassphrase(config.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		if km, err = newKeyMaterial(config.KeyLength); err != nil {
+			return nil, err
+		}
+	}
+
+	raddr, err := net.ResolveUDPAddr(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	udp, err := net.DialUDP(network, nil, raddr)
+	if err != nil {
+		return nil, err
+	}
+	_ = udp.SetReadBuffer(udpBufferSize)
+	_ = udp.SetWriteBuffer(udpBufferSize)
+
+	c, err := handshakeCaller(udp, raddr, config, km)
+	if err != nil {
+		_ = udp.Close()
+		return nil, err
+	}
+	c.local = udp.LocalAddr()
+	c.remote = raddr
+	c.output = func(b []byte) error {
+		_, err := udp.Write(b)
+		return err
+	}
+	c.onClose = func() {
+		_ = udp.Close()
+	}
+	go c.run()
+	go func() {
+		buf := make([]byte, 65536)
+		for {
+			n, err := udp.Read(buf)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					go c.closeWith(err, false)
+				}
+				return
+			}
+			if p, err := parsePacket(buf[:n]); err == nil && p.dest == c.localID {
+				c.handlePacket(p)
+			}
+		}
+	}()
+	return c, nil
+}
+
+func handshakeCaller(udp *net.UDPConn, raddr *net.UDPAddr, config DialConfig, km *keyMaterial) (*Conn, error) {
+	socketID := randomUint32() & seqMask
+	isn := randomUint32() & seqMask
+	deadline := time.Now().Add(config.Timeout)
+
+	request := &handshake{
+		version:    4,
+		extension:  udtDgram,
+		isn:        isn,
+		mtu:        defaultMTU,
+		flowWindow: flowWindow,
+		hsType:     hsInduction,
+		socketID:   socketID,
+		peerIP:     raddr.IP,
+	}
+	induction, err := exchangeHandshake(udp, request, socketID, deadline)
+	if err != nil {
+		return nil, err
+	}
+	if induction.version < 5 || induction.extension != srtMagic {
+		return nil, RejectVersion
+	}
+
+	latencyMs := uint16(config.Latency.Milliseconds())
+	request = &handshake{
+		version:    5,
+		extension:  hsExtHSReq,
+		isn:        isn,
+		mtu:        defaultMTU,
+		flowWindow: flowWindow,
+		hsType:     hsConclusion,
+		socketID:   socketID,
+		cookie:     induction.cookie,
+		peerIP:     raddr.IP,
+	}
+	request.addExtension(extHSReq, srtOptions{
+		version:    srtVersion,
+		flags:      defaultOptions,
+		rcvLatency: latencyMs,
+		sndLatency: latencyMs,
+	}.marshal())
+	if km != nil {
+		msg, err := km.marshal(config.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		request.encryption = uint16(km.keyLen() / 8)
+		request.extension |= hsExtKMReq
+		request.addExtension(extKMReq, msg)
+	}
+	if config.StreamID != "" {
+		request.extension |= hsExtConfig
+		request.addExtension(extSID, encodeStreamID(config.StreamID))
+	}
+	conclusion, err := exchangeHandshake(udp, request, socketID, deadline)
+	if err != nil {
+		return nil, err
+	}
+	if conclusion.hsType != hsConclusion {
+		return nil, fmt.Errorf("srt: unexpected handshake type %#x", conclusion.hsType)
+	}
+
+	data, ok := conclusion.extensionData(extHSRsp)
+	if !ok {
+		return nil, RejectVersion
+	}
+	options, err := parseSrtOptions(data)
+	if err != nil {
+		return nil, err
+	}
+	if km != nil {
+		rsp, ok := conclusion.extensionData(extKMRsp)
+		if !ok {
+			return nil, RejectUnsecure
+		}
+		if len(rsp) == 4 {
+			// The listener reports its key material state instead
+			if rsp[3] == kmStateBadSecret {
+				return nil, RejectBadSecret
+			}
+			return nil, RejectUnsecure
+		}
+	}
+	latency := max(config.Latency, time.Duration(options.rcvLatency)*time.Millisecond)
+	c := newConn(socketID, conclusion.socketID, isn, latency, km)
+	c.kmPassphrase = config.Passphrase
+	c.streamID = config.StreamID
+	return c, nil
+}
+
+// exchangeHandshake sends the request until the listener answers, a rejection is returned as a RejectReason.
+func exchangeHandshake(udp *net.UDPConn, request *handshake, socketID uint32, deadline time.Time) (*handshake, error) {
+	p := packet{control: true, ctrlType: ctrlHandshake, payload: request.marshal()}
+	b := p.marshal()
+	buf := make([]byte, 65536)
+	for time.Now().Before(deadline) {
+		if _, err := udp.Write(b); err != nil {
+			return nil, err
+		}
+		retryAt := time.Now().Add(handshakeRetry)
+		if retryAt.After(deadline) {
+			retryAt = deadline
+		}
+		_ = udp.SetReadDeadline(retryAt)
+		for {
+			n, err := udp.Read(buf)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			response, err := parsePacket(buf[:n])
+			if err != nil || !response.control || response.ctrlType != ctrlHandshake || response.dest != socketID {
+				continue
+			}
+			hs, err := parseHandshake(response.payload)
+			if err != nil {
+				continue
+			}
+			if hs.hsType >= hsRejectBase && hs.hsType != hsConclusion {
+				return nil, RejectReason(hs.hsType - hsRejectBase)
+			}
+			if hs.hsType != request.hsType {
+				continue
+			}
+			_ = udp.SetReadDeadline(time.Time{})
+			return hs, nil
+		}
+	}
+	return nil, errors.New("srt: connection timeout")
+}
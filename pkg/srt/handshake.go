@@ -0,0 +1,256 @@
+package srt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	handshakeSize = 48
+
+	hsInduction  = 1
+	hsConclusion = 0xffffffff
+	hsRejectBase = 1000 // the handshake type of a rejection is hsRejectBase + reason
+
+	udtDgram = 2      // socket type sent by callers in the induction request
+	srtMagic = 0x4a17 // extension field of the induction response, marks an HSv5 listener
+
+	// Flags of the extension field of conclusion requests
+	hsExtHSReq  = 1
+	hsExtKMReq  = 2
+	hsExtConfig = 4
+
+	// Extension types
+	extHSReq = 1
+	extHSRsp = 2
+	extKMReq = 3
+	extKMRsp = 4
+	extSID   = 5
+
+	srtVersion = 0x010503
+
+	// Flags of HSREQ and HSRSP
+	optTSBPDSnd    = 0x01
+	optTSBPDRcv    = 0x02
+	optCrypt       = 0x04
+	optTLPktDrop   = 0x08
+	optPeriodicNak = 0x10
+	optRexmitFlag  = 0x20
+	optStream      = 0x40
+
+	defaultOptions = optTSBPDSnd | optTSBPDRcv | optCrypt | optTLPktDrop | optPeriodicNak | optRexmitFlag
+
+	MaxStreamIDLen = 512
+	defaultMTU     = 1500
+	flowWindow     = 8192
+)
+
+// RejectReason is sent to the caller when the listener refuses the connection.
+type RejectReason uint32
+
+const (
+	RejectUnknown   RejectReason = 0
+	RejectSystem    RejectReason = 1
+	RejectPeer      RejectReason = 2
+	RejectResource  RejectReason = 3
+	RejectRogue     RejectReason = 4
+	RejectVersion   RejectReason = 8
+	RejectBadSecret RejectReason = 10
+	RejectUnsecure  RejectReason = 11
+
+	// Reasons defined by the access control guidelines for the stream ID
+	RejectBadRequest   RejectReason = 1400
+	RejectUnauthorized RejectReason = 1401
+	RejectForbidden    RejectReason = 1403
+	RejectNotFound     RejectReason = 1404
+	RejectBadMode      RejectReason = 1405
+	RejectConflict     RejectReason = 1409
+	RejectInternal     RejectReason = 1500
+)
+
+func (r RejectReason) Error() string {
+	switch r {
+	case RejectPeer:
+		return "srt: rejected by peer"
+	case RejectVersion:
+		return "srt: unsupported version"
+	case RejectBadSecret:
+		return "srt: wrong passphrase"
+	case RejectUnsecure:
+		return "srt: encryption required on one side only"
+	case RejectUnauthorized:
+		return "srt: unauthorized"
+	case RejectForbidden:
+		return "srt: forbidden"
+	case RejectNotFound:
+		return "srt: stream not found"
+	case RejectBadMode:
+		return "srt: unsupported mode"
+	case RejectConflict:
+		return "srt: stream is already being published"
+	}
+	return fmt.Sprintf("srt: connection rejected with reason %d", uint32(r))
+}
+
+type handshake struct {
+	version    uint32
+	encryption uint16 // key length / 8 in conclusion requests of encrypting callers
+	extension  uint16
+	isn        uint32
+	mtu        uint32
+	flowWindow uint32
+	hsType     uint32
+	socketID   uint32
+	cookie     uint32
+	peerIP     net.IP
+
+	extensions []hsExtension
+}
+
+type hsExtension struct {
+	kind uint16
+	data []byte
+}
+
+func parseHandshake(b []byte) (*handshake, error) {
+	if len(b) < handshakeSize {
+		return nil, errors.New("srt: handshake too short")
+	}
+	h := &handshake{
+		version:    binary.BigEndian.Uint32(b),
+		encryption: binary.BigEndian.Uint16(b[4:]),
+		extension:  binary.BigEndian.Uint16(b[6:]),
+		isn:        binary.BigEndian.Uint32(b[8:]) & seqMask,
+		mtu:        binary.BigEndian.Uint32(b[12:]),
+		flowWindow: binary.BigEndian.Uint32(b[16:]),
+		hsType:     binary.BigEndian.Uint32(b[20:]),
+		socketID:   binary.BigEndian.Uint32(b[24:]),
+		cookie:     binary.BigEndian.Uint32(b[28:]),
+		peerIP:     parsePeerIP(b[32:48]),
+	}
+	for rest := b[handshakeSize:]; len(rest) >= 4; {
+		kind := binary.BigEndian.Uint16(rest)
+		size := 4 * int(binary.BigEndian.Uint16(rest[2:]))
+		if len(rest) < 4+size {
+			return nil, errors.New("srt: truncated handshake extension")
+		}
+		h.extensions = append(h.extensions, hsExtension{kind: kind, data: rest[4 : 4+size]})
+		rest = rest[4+size:]
+	}
+	return h, nil
+}
+
+func (h *handshake) marshal() []byte {
+	b := make([]byte, handshakeSize)
+	binary.BigEndian.PutUint32(b, h.version)
+	binary.BigEndian.PutUint16(b[4:], h.encryption)
+	binary.BigEndian.PutUint16(b[6:], h.extension)
+	binary.BigEndian.PutUint32(b[8:], h.isn)
+	binary.BigEndian.PutUint32(b[12:], h.mtu)
+	binary.BigEndian.PutUint32(b[16:], h.flowWindow)
+	binary.BigEndian.PutUint32(b[20:], h.hsType)
+	binary.BigEndian.PutUint32(b[24:], h.socketID)
+	binary.BigEndian.PutUint32(b[28:], h.cookie)
+	putPeerIP(b[32:48], h.peerIP)
+	for _, ext := range h.extensions {
+		b = binary.BigEndian.AppendUint16(b, ext.kind)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(ext.data)/4))
+		b = append(b, ext.data...)
+	}
+	return b
+}
+
+func (h *handshake) extensionData(kind uint16) ([]byte, bool) {
+	for _, ext := range h.extensions {
+		if ext.kind == kind {
+			return ext.data, true
+		}
+	}
+	return nil, false
+}
+
+func (h *handshake) addExtension(kind uint16, data []byte) {
+	h.extensions = append(h.extensions, hsExtension{kind: kind, data: data})
+}
+
+// The peer IP is stored as 32 bit words in little endian.
+
+func parsePeerIP(b []byte) net.IP {
+	ip := make(net.IP, 16)
+	for i := 0; i < 16; i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+	}
+	if isZero(ip[4:]) {
+		return net.IPv4(ip[0], ip[1], ip[2], ip[3])
+	}
+	return ip
+}
+
+func putPeerIP(b []byte, ip net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for i := 0; i+4 <= len(ip); i += 4 {
+		b[i], b[i+1], b[i+2], b[i+3] = ip[i+3], ip[i+2], ip[i+1], ip[i]
+	}
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// srtOptions is the content of the HSREQ and HSRSP extensions.
+type srtOptions struct {
+	version    uint32
+	flags      uint32
+	rcvLatency uint16 // ms
+	sndLatency uint16
+}
+
+func parseSrtOptions(b []byte) (srtOptions, error) {
+	if len(b) < 12 {
+		return srtOptions{}, errors.New("srt: invalid handshake options")
+	}
+	latency := binary.BigEndian.Uint32(b[8:])
+	return srtOptions{
+		version:    binary.BigEndian.Uint32(b),
+		flags:      binary.BigEndian.Uint32(b[4:]),
+		rcvLatency: uint16(latency >> 16),
+		sndLatency: uint16(latency),
+	}, nil
+}
+
+func (o srtOptions) marshal() []byte {
+	return words(o.version, o.flags, uint32(o.rcvLatency)<<16|uint32(o.sndLatency))
+}
+
+// The stream ID is padded to 32 bit words, which are sent in little endian.
+
+func encodeStreamID(streamID string) []byte {
+	b := []byte(streamID)
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	swapWords(b)
+	return b
+}
+
+func decodeStreamID(b []byte) string {
+	b = append([]byte(nil), b...)
+	swapWords(b)
+	return strings.TrimRight(string(b), "\x00")
+}
+
+func swapWords(b []byte) {
+	for i := 0; i+4 <= len(b); i += 4 {
+		b[i], b[i+1], b[i+2], b[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+	}
+}
@@ -0,0 +1,340 @@
+package srt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+const udpBufferSize = 4 << 20
+
+// Handler authorizes a caller during the handshake. If it accepts, serve is run
+// in its own goroutine once the connection is established.
+type Handler func(c *Conn) (serve func(), reject RejectReason)
+
+type ListenerConfig struct {
+	Latency    time.Duration // receiver latency, defaults to DefaultLatency
+	Passphrase string        // callers must encrypt with it if set
+	Handler    Handler
+}
+
+// Listener accepts SRT callers on a UDP socket shared by all their connections.
+type Listener struct {
+	conn   *net.UDPConn
+	config ListenerConfig
+	secret []byte // of the SYN cookies
+
+	mu      sync.Mutex
+	conns   map[uint32]*Conn
+	callers map[string]*pendingCaller // by address and socket ID of the caller
+	closed  bool
+}
+
+// pendingCaller is a caller whose conclusion is being handled or has been answered.
+type pendingCaller struct {
+	response []byte
+}
+
+func Listen(addr string, config ListenerConfig) (*Listener, error) {
+	if config.Passphrase != "" {
+		if err := ValidatePassphrase(config.Passphrase); err != nil {
+			return nil, err
+		}
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetReadBuffer(udpBufferSize)
+	_ = conn.SetWriteBuffer(udpBufferSize)
+	if config.Latency == 0 {
+		config.Latency = DefaultLatency
+	}
+	l := &Listener{
+		conn:    conn,
+		config:  config,
+		secret:  make([]byte, 32),
+		conns:   make(map[uint32]*Conn),
+		callers: make(map[string]*pendingCaller),
+	}
+	if _, err := rand.Read(l.secret); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Listener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// Serve reads the socket until the listener is closed.
+func (l *Listener) Serve() error {
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := l.conn.ReadFromUDP(buf)
+		if err != nil {
+			l.mu.Lock()
+			closed := l.closed
+			l.mu.Unlock()
+			if closed {
+				return nil
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			continue
+		}
+		p, err := parsePacket(buf[:n])
+		if err != nil {
+			continue
+		}
+		if p.dest == 0 {
+			if p.control && p.ctrlType == ctrlHandshake {
+				l.handleHandshake(p, addr)
+			}
+			continue
+		}
+		l.mu.Lock()
+		c := l.conns[p.dest]
+		l.mu.Unlock()
+		if c != nil && c.remote.String() == addr.String() {
+			c.handlePacket(p)
+		}
+	}
+}
+
+func (l *Listener) Close() error {
+	l.mu.Lock()
+	l.closed = true
+	conns := make([]*Conn, 0, len(l.conns))
+	for _, c := range l.conns {
+		conns = append(conns, c)
+	}
+	l.mu.Unlock()
+	for _, c := range conns {
+		_ = c.Close()
+	}
+	return l.conn.Close()
+}
+
+func (l *Listener) output(addr *net.UDPAddr) func(b []byte) error {
+	return func(b []byte) error {
+		_, err := l.conn.WriteToUDP(b, addr)
+		return err
+	}
+}
+
+// cookie binds the induction to the address of the caller, it changes every minute.
+func (l *Listener) cookie(addr *net.UDPAddr, at time.Time) uint32 {
+	h := sha256.New()
+	h.Write(l.secret)
+	h.Write([]byte(addr.String()))
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(at.Unix()/60)))
+	return binary.BigEndian.Uint32(h.Sum(nil))
+}
+
+func (l *Listener) handleHandshake(p *packet, addr *net.UDPAddr) {
+	// The conclusion is handled after the read buffer is reused
+	hs, err := parseHandshake(append([]byte(nil), p.payload...))
+	if err != nil {
+		return
+	}
+	switch hs.hsType {
+	case hsInduction:
+		response := &handshake{
+			version:    5,
+			extension:  srtMagic,
+			isn:        hs.isn,
+			mtu:        hs.mtu,
+			flowWindow: hs.flowWindow,
+			hsType:     hsInduction,
+			socketID:   hs.socketID,
+			cookie:     l.cookie(addr, time.Now()),
+			peerIP:     addr.IP,
+		}
+		l.sendHandshake(addr, hs.socketID, response)
+	case hsConclusion:
+		now := time.Now()
+		if hs.cookie != l.cookie(addr, now) && hs.cookie != l.cookie(addr, now.Add(-time.Minute)) {
+			return
+		}
+		key := fmt.Sprintf("%s/%d", addr, hs.socketID)
+		l.mu.Lock()
+		var response []byte
+		pending, known := l.callers[key]
+		if known {
+			response = pending.response
+		} else {
+			l.callers[key] = &pendingCaller{}
+		}
+		l.mu.Unlock()
+		if known {
+			// The caller retransmits until it gets the response
+			if response != nil {
+				_, _ = l.conn.WriteToUDP(response, addr)
+			}
+			return
+		}
+		// The handler may take a while, e.g. for an HTTP callback
+		go l.conclude(hs, addr, key)
+	}
+}
+
+func (l *Listener) sendHandshake(addr *net.UDPAddr, dest uint32, hs *handshake) []byte {
+	p := packet{control: true, ctrlType: ctrlHandshake, dest: dest, payload: hs.marshal()}
+	b := p.marshal()
+	_, _ = l.conn.WriteToUDP(b, addr)
+	return b
+}
+
+func (l *Listener) reject(addr *net.UDPAddr, hs *handshake, key string, reason RejectReason) {
+	response := &handshake{
+		version:    5,
+		isn:        hs.isn,
+		mtu:        hs.mtu,
+		flowWindow: hs.flowWindow,
+		hsType:     hsRejectBase + uint32(reason),
+		socketID:   0,
+		cookie:     hs.cookie,
+		peerIP:     addr.IP,
+	}
+	b := l.sendHandshake(addr, hs.socketID, response)
+	l.mu.Lock()
+	l.callers[key].response = b
+	l.mu.Unlock()
+	l.forgetCaller(key)
+}
+
+// forgetCaller drops the caller after the time it retransmits the conclusion.
+func (l *Listener) forgetCaller(key string) {
+	time.AfterFunc(10*time.Second, func() {
+		l.mu.Lock()
+		delete(l.callers, key)
+		l.mu.Unlock()
+	})
+}
+
+func (l *Listener) conclude(hs *handshake, addr *net.UDPAddr, key string) {
+	if hs.version < 5 {
+		l.reject(addr, hs, key, RejectVersion)
+		return
+	}
+	data, ok := hs.extensionData(extHSReq)
+	if !ok {
+		l.reject(addr, hs, key, RejectVersion)
+		return
+	}
+	options, err := parseSrtOptions(data)
+	if err != nil {
+		l.reject(addr, hs, key, RejectRogue)
+		return
+	}
+
+	var km *keyMaterial
+	kmMsg, hasKM := hs.extensionData(extKMReq)
+	switch {
+	case hasKM && l.config.Passphrase != "":
+		if km, err = parseKeyMaterial(kmMsg, l.config.Passphrase); err != nil {
+			l.reject(addr, hs, key, RejectBadSecret)
+			return
+		}
+	case hasKM || l.config.Passphrase != "":
+		l.reject(addr, hs, key, RejectUnsecure)
+		return
+	}
+
+	latency := max(l.config.Latency, time.Duration(options.sndLatency)*time.Millisecond)
+	c := newConn(l.newSocketID(), hs.socketID, hs.isn, latency, km)
+	c.kmPassphrase = l.config.Passphrase
+	c.local = l.conn.LocalAddr()
+	c.remote = addr
+	c.output = l.output(addr)
+	if sid, ok := hs.extensionData(extSID); ok {
+		c.streamID = decodeStreamID(sid)
+	}
+
+	serve, reason := l.config.Handler(c)
+	if serve == nil {
+		if reason == 0 {
+			reason = RejectPeer
+		}
+		l.reject(addr, hs, key, reason)
+		return
+	}
+
+	response := &handshake{
+		version:    5,
+		extension:  hsExtHSReq,
+		isn:        hs.isn,
+		mtu:        min(hs.mtu, defaultMTU),
+		flowWindow: flowWindow,
+		hsType:     hsConclusion,
+		socketID:   c.localID,
+		cookie:     hs.cookie,
+		peerIP:     addr.IP,
+	}
+	latencyMs := uint16(latency.Milliseconds())
+	response.addExtension(extHSRsp, srtOptions{
+		version:    srtVersion,
+		flags:      defaultOptions,
+		rcvLatency: latencyMs,
+		sndLatency: max(latencyMs, options.rcvLatency),
+	}.marshal())
+	if km != nil {
+		response.extension |= hsExtKMReq
+		response.addExtension(extKMRsp, kmMsg)
+	}
+
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		// serve releases what the handler acquired
+		c.closeWith(net.ErrClosed, false)
+		go serve()
+		return
+	}
+	l.conns[c.localID] = c
+	l.mu.Unlock()
+	c.onClose = func() {
+		l.mu.Lock()
+		delete(l.conns, c.localID)
+		l.mu.Unlock()
+		l.forgetCaller(key)
+	}
+	b := l.sendHandshake(addr, hs.socketID, response)
+	l.mu.Lock()
+	l.callers[key].response = b
+	l.mu.Unlock()
+	go c.run()
+	go serve()
+}
+
+func (l *Listener) newSocketID() uint32 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for {
+		id := randomUint32() & seqMask
+		if _, used := l.conns[id]; id != 0 && !used {
+			return id
+		}
+	}
+}
+
+func randomUint32() uint32 {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		log.Printf("srt: random source failed: %v", err)
+	}
+	return binary.BigEndian.Uint32(b[:])
+}
@@ -0,0 +1,119 @@
+package srt
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	headerSize = 16
+	seqMask    = 1<<31 - 1
+	msgNoMask  = 1<<26 - 1
+
+	// MPEG-TS payload of live mode, 7 TS packets
+	PayloadSize = 1316
+)
+
+type controlType uint16
+
+const (
+	ctrlHandshake controlType = 0x0000
+	ctrlKeepalive controlType = 0x0001
+	ctrlAck       controlType = 0x0002
+	ctrlNak       controlType = 0x0003
+	ctrlShutdown  controlType = 0x0005
+	ctrlAckAck    controlType = 0x0006
+	ctrlDropReq   controlType = 0x0007
+	ctrlUser      controlType = 0x7fff
+)
+
+// Flags of the second word of data packets
+const (
+	flagSolo       = 3 << 30 // the message is a single packet
+	flagKeyShift   = 27
+	flagKeyMask    = 3 << flagKeyShift
+	flagRetransmit = 1 << 26
+	keyEven        = 1
+	keyOdd         = 2
+)
+
+type packet struct {
+	control bool
+
+	seq   uint32 // data packets
+	flags uint32 // data packets, position, encryption key, retransmission and message number
+
+	ctrlType controlType
+	subtype  uint16
+	info     uint32 // type specific word of control packets
+
+	timestamp uint32 // µs since the start of the connection
+	dest      uint32 // socket ID of the receiver
+	payload   []byte
+}
+
+var errShortPacket = errors.New("srt: packet too short")
+
+func parsePacket(b []byte) (*packet, error) {
+	if len(b) < headerSize {
+		return nil, errShortPacket
+	}
+	p := &packet{
+		timestamp: binary.BigEndian.Uint32(b[8:]),
+		dest:      binary.BigEndian.Uint32(b[12:]),
+		payload:   b[headerSize:],
+	}
+	first := binary.BigEndian.Uint32(b)
+	if first&(1<<31) != 0 {
+		p.control = true
+		p.ctrlType = controlType(first >> 16 & 0x7fff)
+		p.subtype = uint16(first)
+		p.info = binary.BigEndian.Uint32(b[4:])
+	} else {
+		p.seq = first
+		p.flags = binary.BigEndian.Uint32(b[4:])
+	}
+	return p, nil
+}
+
+func (p *packet) marshal() []byte {
+	b := make([]byte, headerSize, headerSize+len(p.payload))
+	if p.control {
+		binary.BigEndian.PutUint32(b, 1<<31|uint32(p.ctrlType)<<16|uint32(p.subtype))
+		binary.BigEndian.PutUint32(b[4:], p.info)
+	} else {
+		binary.BigEndian.PutUint32(b, p.seq&seqMask)
+		binary.BigEndian.PutUint32(b[4:], p.flags)
+	}
+	binary.BigEndian.PutUint32(b[8:], p.timestamp)
+	binary.BigEndian.PutUint32(b[12:], p.dest)
+	return append(b, p.payload...)
+}
+
+func (p *packet) keyIndex() uint32 {
+	return p.flags & flagKeyMask >> flagKeyShift
+}
+
+// Sequence numbers are 31 bit and wrap around.
+
+func seqAdd(seq uint32, n int32) uint32 {
+	return (seq + uint32(n)) & seqMask
+}
+
+// seqDiff returns a - b.
+func seqDiff(a, b uint32) int32 {
+	return int32((a-b)<<1) >> 1
+}
+
+func seqLess(a, b uint32) bool {
+	return seqDiff(a, b) < 0
+}
+
+// words encodes 32 bit values of a control packet payload.
+func words(values ...uint32) []byte {
+	b := make([]byte, 0, 4*len(values))
+	for _, v := range values {
+		b = binary.BigEndian.AppendUint32(b, v)
+	}
+	return b
+}
@@ -0,0 +1,76 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const reloadCheckInterval = 5 * time.Second
+
+// CertReloader serves a certificate loaded from files and reloads it when the files change.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+	mu        sync.Mutex
+}
+
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checkedAt) >= reloadCheckInterval {
+		r.checkedAt = time.Now()
+		if modTime := r.latestModTime(); modTime.After(r.modTime) {
+			if err := r.reload(); err != nil {
+				log.Printf("Failed to reload TLS certificate %s: %v", r.certFile, err)
+			} else {
+				log.Printf("Reloaded TLS certificate %s", r.certFile)
+			}
+		}
+	}
+	return r.cert, nil
+}
+
+// TLSConfig returns a server config using the reloader.
+func (r *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+}
+
+func (r *CertReloader) latestModTime() time.Time {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+func (r *CertReloader) reload() error {
+	modTime := r.latestModTime()
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	r.checkedAt = time.Now()
+	return nil
+}
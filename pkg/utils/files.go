@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+var ErrNotInDir = errors.New("file is not in the allowed directory")
+
+// OpenInDir opens the file name relative to dir. Names which are absolute, climb out of the directory
+// or resolve outside of it through a symlink are rejected, as is everything if dir is empty.
+func OpenInDir(dir, name string) (*os.File, error) {
+	if dir == "" || !filepath.IsLocal(name) {
+		return nil, ErrNotInDir
+	}
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(root, name))
+	if err != nil {
+		return nil, err
+	}
+	if rel, err := filepath.Rel(root, path); err != nil || !filepath.IsLocal(rel) {
+		return nil, ErrNotInDir
+	}
+	return os.Open(path)
+}
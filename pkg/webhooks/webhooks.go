@@ -0,0 +1,194 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/events"
+)
+
+const (
+	SignatureHeader = "X-Restreamer-Signature"
+	TimestampHeader = "X-Restreamer-Timestamp"
+	EventHeader     = "X-Restreamer-Event"
+
+	DEAD_LETTER_FILE = "simple-rtmp-restreamer.webhooks-dead.jsonl"
+
+	queueSize      = 1000
+	initialBackoff = time.Second
+	maxBackoff     = time.Minute
+)
+
+type Config struct {
+	URLs           []string
+	Secret         string
+	MaxAttempts    int // defaults to 5
+	DeadLetterFile string
+	Timeout        time.Duration
+}
+
+// Dispatcher delivers events to the configured webhook URLs.
+// Each URL has its own queue, so a slow endpoint doesn't delay the others.
+type Dispatcher struct {
+	config    Config
+	client    *http.Client
+	endpoints []*endpoint
+
+	// Dead letters are appended to the file by a single writer, so neither Handle nor the delivery loops wait for the disk
+	deadLetters chan deadLetter
+	dropped     atomic.Int64 // dead letters lost because the writer fell behind
+}
+
+type endpoint struct {
+	url   string
+	queue chan events.Event
+}
+
+type deadLetter struct {
+	URL      string       `json:"url"`
+	Event    events.Event `json:"event"`
+	Error    string       `json:"error"`
+	Attempts int          `json:"attempts"`
+	FailedAt time.Time    `json:"failed_at"`
+}
+
+func NewDispatcher(config Config) *Dispatcher {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.DeadLetterFile == "" {
+		config.DeadLetterFile = DEAD_LETTER_FILE
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	d := &Dispatcher{
+		config:      config,
+		client:      &http.Client{Timeout: config.Timeout},
+		deadLetters: make(chan deadLetter, queueSize),
+	}
+	go d.deadLetterLoop()
+	for _, url := range config.URLs {
+		e := &endpoint{url: url, queue: make(chan events.Event, queueSize)}
+		d.endpoints = append(d.endpoints, e)
+		go d.deliverLoop(e)
+	}
+	return d
+}
+
+// Handle enqueues the event for all endpoints. It never blocks.
+func (d *Dispatcher) Handle(event events.Event) {
+	for _, e := range d.endpoints {
+		select {
+		case e.queue <- event:
+		default:
+			d.addDeadLetter(e.url, event, fmt.Errorf("queue is full"), 0)
+		}
+	}
+}
+
+func (d *Dispatcher) deliverLoop(e *endpoint) {
+	for event := range e.queue {
+		body, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("Webhook %s: failed to encode event %s: %v", e.url, event.Type, err)
+			continue
+		}
+
+		backoff := initialBackoff
+		for attempt := 1; ; attempt++ {
+			err = d.post(e.url, event, body)
+			if err == nil {
+				break
+			}
+			if attempt >= d.config.MaxAttempts {
+				log.Printf("Webhook %s: giving up on event %s after %d attempts: %v", e.url, event.Type, attempt, err)
+				d.addDeadLetter(e.url, event, err, attempt)
+				break
+			}
+			log.Printf("Webhook %s: delivery of %s failed, retry in %v: %v", e.url, event.Type, backoff, err)
+			time.Sleep(backoff)
+			backoff = min(backoff*2, maxBackoff)
+		}
+	}
+}
+
+func (d *Dispatcher) post(url string, event events.Event, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(event.Type))
+	req.Header.Set(TimestampHeader, timestamp)
+	if d.config.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(d.config.Secret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "timestamp.body", as sent in the signature header.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// addDeadLetter hands the failed event to the dead-letter writer, or counts it as dropped if the writer is behind.
+func (d *Dispatcher) addDeadLetter(url string, event events.Event, err error, attempts int) {
+	letter := deadLetter{
+		URL:      url,
+		Event:    event,
+		Error:    err.Error(),
+		Attempts: attempts,
+		FailedAt: time.Now(),
+	}
+	select {
+	case d.deadLetters <- letter:
+	default:
+		d.dropped.Add(1)
+	}
+}
+
+func (d *Dispatcher) deadLetterLoop() {
+	for letter := range d.deadLetters {
+		if dropped := d.dropped.Swap(0); dropped > 0 {
+			log.Printf("Webhooks: dropped %d events, the dead-letter file can't keep up", dropped)
+		}
+		d.writeDeadLetter(letter)
+	}
+}
+
+func (d *Dispatcher) writeDeadLetter(letter deadLetter) {
+	file, err := os.OpenFile(d.config.DeadLetterFile, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		log.Printf("Failed to open webhooks dead-letter file: %v", err)
+		return
+	}
+	defer file.Close()
+	if err := json.NewEncoder(file).Encode(letter); err != nil {
+		log.Printf("Failed to write webhooks dead-letter file: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package webhooks
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kbats183/simple-rtmp-restreamer/pkg/events"
+)
+
+func TestDeliverSigned(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(Config{URLs: []string{server.URL}, Secret: "secret", DeadLetterFile: filepath.Join(t.TempDir(), "dead.jsonl")})
+	d.Handle(events.Event{Type: events.PublishStarted, Stream: "s1"})
+
+	select {
+	case r := <-received:
+		body := <-bodies
+		if got := r.Header.Get(EventHeader); got != string(events.PublishStarted) {
+			t.Fatalf("%s = %q", EventHeader, got)
+		}
+		want := "sha256=" + Sign("secret", r.Header.Get(TimestampHeader), body)
+		if got := r.Header.Get(SignatureHeader); got != want {
+			t.Fatalf("%s = %q, want %q", SignatureHeader, got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+}
+
+func TestHandleDoesNotBlockOnOverflow(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	deadFile := filepath.Join(t.TempDir(), "dead.jsonl")
+	d := NewDispatcher(Config{URLs: []string{server.URL}, DeadLetterFile: deadFile})
+
+	// The first event occupies the delivery loop, the rest fill the queue and then overflow
+	overflow := 10
+	start := time.Now()
+	for i := 0; i < queueSize+1+overflow; i++ {
+		d.Handle(events.Event{Type: events.PublishStarted, Stream: "s1"})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Handle took %v", elapsed)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if lines := countLines(t, deadFile); lines+int(d.dropped.Load()) >= overflow {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("overflowed events were not written to the dead-letter file")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	n := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		n++
+	}
+	return n
+}
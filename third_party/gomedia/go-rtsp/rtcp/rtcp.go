@@ -0,0 +1,9 @@
+package rtcp
+
+const (
+    RTCP_SR   = 200
+    RTCP_RR   = 201
+    RTCP_SDES = 202
+    RTCP_BYE  = 203
+    RTCP_APP  = 204
+)
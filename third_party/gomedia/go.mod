@@ -0,0 +1,3 @@
+module github.com/yapingcat/gomedia
+
+go 1.16
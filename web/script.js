@@ -1,10 +1,24 @@
 class RTMPRestreamer {
     constructor() {
         this.apiBase = 'api/streams';
+        this.previews = new Map(); // stream name -> {video, player}, kept across renders
         this.init();
     }
 
-    init() {
+    async init() {
+        this.scopes = ['streams:write', 'targets:write'];
+        try {
+            const response = await fetch('api/me');
+            if (response.ok) {
+                this.scopes = (await response.json()).scopes || [];
+            }
+        } catch (error) {
+            console.error('Failed to load user info:', error);
+        }
+        if (!this.canEditStreams()) {
+            document.getElementById('addStreamBtn').style.display = 'none';
+        }
+
         this.setupEventListeners();
         this.loadStreams();
         setInterval(() => this.loadStreams(), 5000); // Refresh every 5 seconds
@@ -35,9 +49,22 @@ class RTMPRestreamer {
         document.getElementById('addTargetForm').addEventListener('submit', (e) => this.handleAddTarget(e));
     }
 
+    canEditStreams() {
+        return this.scopes.includes('streams:write');
+    }
+
+    canEditTargets() {
+        return this.scopes.includes('targets:write');
+    }
+
     async loadStreams() {
         try {
             const response = await fetch(`${this.apiBase}/-/status`);
+            if (response.status === 401) {
+                // Session expired, reload to go through the login flow again
+                window.location.reload();
+                return;
+            }
             if (!response.ok) throw new Error(`HTTP ${response.status}`);
             
             const streams = await response.json();
@@ -64,6 +91,7 @@ class RTMPRestreamer {
         
         const html = streams.map(stream => this.renderStreamCard(stream, stream.status)).join('');
         container.innerHTML = html;
+        this.attachPreviews(streams);
 
         // Add event listeners for stream actions
         container.querySelectorAll('.delete-stream').forEach(btn => {
@@ -80,6 +108,13 @@ class RTMPRestreamer {
             });
         });
 
+        container.querySelectorAll('.toggle-preview').forEach(btn => {
+            btn.addEventListener('click', (e) => {
+                const streamName = e.target.dataset.streamName;
+                this.togglePreview(streamName);
+            });
+        });
+
         container.querySelectorAll('.delete-target').forEach(btn => {
             btn.addEventListener('click', (e) => {
                 const streamName = e.target.dataset.streamName;
@@ -94,16 +129,24 @@ class RTMPRestreamer {
         const bitrate = status?.bitrate || 0;
         const lastFrameTime = status?.last_frame_time ? new Date(status.last_frame_time * 1000).toLocaleString() : 'Never';
         
+        const targetStatuses = {};
+        (stream.targets_status || []).forEach(s => targetStatuses[s.url] = s);
+
         const targetsHtml = stream.targets && stream.targets.length > 0 
             ? stream.targets.map(target => {
                 // Handle both old format (string) and new format (object with name/url)
                 const targetName = typeof target === 'string' ? target : (target?.name || '');
                 const targetUrl = typeof target === 'string' ? target : (target?.url || '');
+                const targetStatus = targetStatuses[targetUrl];
+                const statusHtml = targetStatus
+                    ? `<span class="target-state ${this.escapeHtml(targetStatus.state)}" title="${this.escapeHtml(targetStatus.last_error || '')}">${this.escapeHtml(targetStatus.state)}${targetStatus.tls_error ? ' (TLS)' : ''}</span>`
+                    : '';
                 return `
                     <div class="target-item">
                         <span class="target-name">${this.escapeHtml(targetName)}</span>
+                        ${statusHtml}
                         <span class="target-url">${this.escapeHtml(targetUrl)}</span>
-                        <button class="btn btn-danger btn-tiny delete-target" data-stream-name="${this.escapeHtml(stream.name || '')}" data-target="${this.escapeHtml(targetUrl)}">×</button>
+                        ${this.canEditTargets() ? `<button class="btn btn-danger btn-tiny delete-target" data-stream-name="${this.escapeHtml(stream.name || '')}" data-target="${this.escapeHtml(targetUrl)}">×</button>` : ''}
                     </div>
                 `;
             }).join('')
@@ -125,23 +168,74 @@ class RTMPRestreamer {
                     <div><strong>RTMP URL:</strong> rtmp://${location.hostname}/live/${this.escapeHtml(stream.name || '')}</div>
                 </div>
 
+                <div class="stream-preview" data-stream-name="${this.escapeHtml(stream.name || '')}"></div>
+
                 <div class="stream-targets">
                     <h4>Push Targets (${stream.targets ? stream.targets.length : 0}):</h4>
                     ${targetsHtml}
                 </div>
 
                 <div class="stream-actions">
-                    <button class="btn btn-primary btn-small add-target" data-stream-name="${this.escapeHtml(stream.name || '')}">
+                    ${isLive && window.mpegts && mpegts.isSupported() ? `<button class="btn btn-secondary btn-small toggle-preview" data-stream-name="${this.escapeHtml(stream.name || '')}">
+                        ${this.previews.has(stream.name) ? 'Hide Preview' : 'Preview'}
+                    </button>` : ''}
+                    ${this.canEditTargets() ? `<button class="btn btn-primary btn-small add-target" data-stream-name="${this.escapeHtml(stream.name || '')}">
                         Add Target
-                    </button>
-                    <button class="btn btn-danger btn-small delete-stream" data-stream-name="${this.escapeHtml(stream.name || '')}">
+                    </button>` : ''}
+                    ${this.canEditStreams() ? `<button class="btn btn-danger btn-small delete-stream" data-stream-name="${this.escapeHtml(stream.name || '')}">
                         Delete Stream
-                    </button>
+                    </button>` : ''}
                 </div>
             </div>
         `;
     }
 
+    togglePreview(streamName) {
+        if (this.previews.has(streamName)) {
+            this.stopPreview(streamName);
+            this.loadStreams();
+            return;
+        }
+        const video = document.createElement('video');
+        video.muted = true;
+        video.controls = true;
+        const url = new URL(`live/${encodeURIComponent(streamName)}.flv`, location.href);
+        const player = mpegts.createPlayer({type: 'flv', isLive: true, url: url.href}, {
+            enableStashBuffer: false,
+            liveBufferLatencyChasing: true,
+        });
+        player.attachMediaElement(video);
+        player.load();
+        player.play();
+        this.previews.set(streamName, {video, player});
+        this.loadStreams();
+    }
+
+    stopPreview(streamName) {
+        const preview = this.previews.get(streamName);
+        if (!preview) return;
+        preview.player.destroy();
+        preview.video.remove();
+        this.previews.delete(streamName);
+    }
+
+    // attachPreviews moves the playing videos into the re-rendered cards and stops the previews
+    // of streams which went offline.
+    attachPreviews(streams) {
+        const live = new Set(streams.filter(s => s.status?.is_live).map(s => s.name));
+        for (const streamName of Array.from(this.previews.keys())) {
+            if (!live.has(streamName)) {
+                this.stopPreview(streamName);
+            }
+        }
+        document.querySelectorAll('.stream-preview').forEach(el => {
+            const preview = this.previews.get(el.dataset.streamName);
+            if (preview) {
+                el.appendChild(preview.video);
+            }
+        });
+    }
+
     showAddStreamModal() {
         document.getElementById('addStreamModal').style.display = 'block';
         document.getElementById('streamName').focus();